@@ -0,0 +1,249 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/ghodss/yaml"
+	"github.com/lovethedrake/brigdrake/pkg/brigade"
+	"github.com/lovethedrake/brigdrake/pkg/brigade/executor"
+	"github.com/pkg/errors"
+	v1 "k8s.io/api/core/v1"
+)
+
+// plan implements the plan subcommand, which prints what the worker would
+// execute in response to a given event without executing anything and without
+// requiring a Kubernetes cluster.
+func plan(args []string) error {
+	flags := flag.NewFlagSet("plan", flag.ExitOnError)
+	drakefilePath := flags.String(
+		"drakefile",
+		"Drakefile.yaml",
+		"path to the Drakefile",
+	)
+	provider := flags.String(
+		"provider",
+		"",
+		"event provider, e.g. github or brigade-cli (required)",
+	)
+	eventType := flags.String(
+		"type",
+		"",
+		"event type, e.g. push or pull_request:opened (required)",
+	)
+	payloadPath := flags.String(
+		"payload",
+		"",
+		"path to a file containing the event's JSON payload",
+	)
+	commit := flags.String("commit", "", "commit the event pertains to")
+	ref := flags.String("ref", "", "ref the event pertains to")
+	sourceDir := flags.String(
+		"source-dir",
+		"",
+		"directory cache key files are relative to; defaults to the directory "+
+			"containing the Drakefile",
+	)
+	projectPath := flags.String(
+		"project",
+		"",
+		"path to a YAML or JSON file containing the project's Kubernetes secret; "+
+			"if unspecified, a project with default settings is assumed",
+	)
+	allowPrivilegedJobs := flags.Bool(
+		"allow-privileged-jobs",
+		false,
+		"plan for a project that permits privileged jobs; overrides -project",
+	)
+	allowHostMounts := flags.Bool(
+		"allow-host-mounts",
+		false,
+		"plan for a project that permits jobs to mount the host's Docker "+
+			"socket; overrides -project",
+	)
+	manifests := flags.Bool(
+		"manifests",
+		false,
+		"also print the pod and PVC manifests that would be created",
+	)
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if *provider == "" || *eventType == "" {
+		flags.Usage()
+		return errors.New("both -provider and -type must be specified")
+	}
+	if *sourceDir == "" {
+		*sourceDir = filepath.Dir(*drakefilePath)
+	}
+
+	event := brigade.NewEventWithDefaults()
+	event.Provider = *provider
+	event.Type = *eventType
+	event.Revision.Commit = *commit
+	event.Revision.Ref = *ref
+	if *payloadPath != "" {
+		var err error
+		if event.Payload, err = ioutil.ReadFile(*payloadPath); err != nil {
+			return errors.Wrapf(err, "error reading payload from %s", *payloadPath)
+		}
+	}
+
+	project, err := loadPlanProject(*projectPath)
+	if err != nil {
+		return err
+	}
+	flags.Visit(func(f *flag.Flag) {
+		switch f.Name {
+		case "allow-privileged-jobs":
+			project.AllowPrivilegedJobs = *allowPrivilegedJobs
+		case "allow-host-mounts":
+			project.AllowHostMounts = *allowHostMounts
+		}
+	})
+
+	plans, err := executor.PlanBuild(
+		project,
+		event,
+		brigade.NewWorkerConfigWithDefaults(),
+		*drakefilePath,
+		*sourceDir,
+	)
+	if err != nil {
+		return err
+	}
+	return printPlans(os.Stdout, plans, *manifests)
+}
+
+// loadPlanProject returns the project to plan a build for. The project is
+// loaded from the project's Kubernetes secret, stored in the file at the given
+// path, in the same way the worker loads it. If no path is given, a project
+// with default settings is returned.
+func loadPlanProject(path string) (brigade.Project, error) {
+	if path == "" {
+		return brigade.Project{
+			ID:   "brigade-plan",
+			Name: "plan",
+			Kubernetes: brigade.KubernetesConfig{
+				Namespace:        "default",
+				BuildStorageSize: "50Mi",
+			},
+			Secrets: map[string]string{},
+		}, nil
+	}
+	secretBytes, err := ioutil.ReadFile(path)
+	if err != nil {
+		return brigade.Project{},
+			errors.Wrapf(err, "error reading project secret from %s", path)
+	}
+	projectSecret := &v1.Secret{}
+	if err = yaml.Unmarshal(secretBytes, projectSecret); err != nil {
+		return brigade.Project{},
+			errors.Wrapf(err, "error parsing project secret from %s", path)
+	}
+	// Kubernetes merges stringData into data when a secret is written, so the
+	// same is done here.
+	if projectSecret.Data == nil {
+		projectSecret.Data = map[string][]byte{}
+	}
+	for key, value := range projectSecret.StringData {
+		projectSecret.Data[key] = []byte(value)
+	}
+	if projectSecret.Namespace == "" {
+		projectSecret.Namespace = "default"
+	}
+	project, err := brigade.NewProjectFromSecret(projectSecret, "")
+	if err != nil {
+		return project,
+			errors.Wrapf(err, "error loading project from %s", path)
+	}
+	return project, nil
+}
+
+// printPlans prints a human-readable description of the given pipeline plans
+// and, optionally, the manifests of the Kubernetes resources they would
+// create as a multi-document YAML stream.
+func printPlans(
+	w io.Writer,
+	plans []executor.PipelinePlan,
+	manifests bool,
+) error {
+	for _, plan := range plans {
+		outcome := "would not execute"
+		if plan.Matched {
+			outcome = "would execute"
+		}
+		fmt.Fprintf(w, "pipeline %q %s\n", plan.Name, outcome)
+		if len(plan.TriggerEvaluations) == 0 {
+			fmt.Fprintln(w, "  no triggers are defined")
+		}
+		for i, evaluation := range plan.TriggerEvaluations {
+			if !evaluation.Supported {
+				fmt.Fprintf(
+					w,
+					"  trigger %d (%s) is not supported\n",
+					i,
+					evaluation.SpecURI,
+				)
+				continue
+			}
+			fmt.Fprintf(
+				w,
+				"  trigger %d (%s) %s\n",
+				i,
+				evaluation.SpecURI,
+				evaluation.Result,
+			)
+		}
+		if plan.Matched && len(plan.Jobs) == 0 {
+			fmt.Fprintln(w, "  none of the selected jobs are in the pipeline")
+		}
+		if plan.SecretsWithheld {
+			fmt.Fprintln(w, "  secrets would be withheld")
+		}
+		if len(plan.Jobs) > 0 {
+			fmt.Fprintln(w, "  jobs:")
+		}
+		for _, job := range plan.Jobs {
+			if len(job.Dependencies) == 0 {
+				fmt.Fprintf(w, "    %s\n", job.Name)
+				continue
+			}
+			fmt.Fprintf(
+				w,
+				"    %s (after %s)\n",
+				job.Name,
+				strings.Join(job.Dependencies, ", "),
+			)
+		}
+	}
+	if !manifests {
+		return nil
+	}
+	for _, plan := range plans {
+		objs := []interface{}{}
+		for _, pvc := range plan.PersistentVolumeClaims {
+			pvc.APIVersion = "v1"
+			pvc.Kind = "PersistentVolumeClaim"
+			objs = append(objs, pvc)
+		}
+		for _, pod := range plan.Pods {
+			pod.APIVersion = "v1"
+			pod.Kind = "Pod"
+			objs = append(objs, pod)
+		}
+		for _, obj := range objs {
+			manifest, err := yaml.Marshal(obj)
+			if err != nil {
+				return errors.Wrap(err, "error marshaling manifest")
+			}
+			fmt.Fprintf(w, "---\n%s", manifest)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,69 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+
+	"github.com/lovethedrake/brigdrake/pkg/brigade"
+	"github.com/lovethedrake/brigdrake/pkg/brigade/executor"
+	"github.com/pkg/errors"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/rest"
+)
+
+// resetCache implements the reset-cache subcommand, which deletes all of a
+// project's PVCs for the named cache. Like the worker itself, it must be run
+// within the cluster, using a service account that may delete PVCs in the
+// project's namespace.
+func resetCache(args []string) error {
+	flags := flag.NewFlagSet("reset-cache", flag.ExitOnError)
+	projectID := flags.String(
+		"project",
+		"",
+		"ID of the Brigade project (required)",
+	)
+	namespace := flags.String(
+		"namespace",
+		"default",
+		"namespace of the Brigade project",
+	)
+	cacheName := flags.String(
+		"cache",
+		"",
+		"name of the cache to reset (required)",
+	)
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if *projectID == "" || *cacheName == "" {
+		flags.Usage()
+		return errors.New("both -project and -cache must be specified")
+	}
+
+	clientConfig, err := rest.InClusterConfig()
+	if err != nil {
+		return err
+	}
+	kubeClient, err := kubernetes.NewForConfig(clientConfig)
+	if err != nil {
+		return err
+	}
+
+	project := brigade.Project{
+		ID: *projectID,
+		Kubernetes: brigade.KubernetesConfig{
+			Namespace: *namespace,
+		},
+	}
+	deleted, err := executor.ResetCache(project, *cacheName, kubeClient)
+	for _, pvcName := range deleted {
+		fmt.Printf("deleted PVC %q\n", pvcName)
+	}
+	if err != nil {
+		return err
+	}
+	if len(deleted) == 0 {
+		fmt.Printf("cache %q of project %q has no PVCs\n", *cacheName, *projectID)
+	}
+	return nil
+}
@@ -0,0 +1,13 @@
+package main
+
+// Trigger implementations register themselves with the drake package when
+// they are linked into the worker. To make additional trigger specs available,
+// including ones maintained outside this repository, import their packages
+// here. Packages may also register a notifier that reports invalid Drakefiles
+// back to the provider of the events they handle.
+import (
+	_ "github.com/lovethedrake/brigdrake/pkg/drake/brig"
+	_ "github.com/lovethedrake/brigdrake/pkg/drake/cron"
+	_ "github.com/lovethedrake/brigdrake/pkg/drake/generic"
+	_ "github.com/lovethedrake/brigdrake/pkg/drake/github"
+)
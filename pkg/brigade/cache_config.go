@@ -0,0 +1,89 @@
+package brigade
+
+import (
+	"encoding/json"
+	"path"
+	"regexp"
+
+	"github.com/pkg/errors"
+	"k8s.io/apimachinery/pkg/api/resource"
+)
+
+// defaultCacheSize is the amount of storage requested for a cache that
+// doesn't specify its size.
+const defaultCacheSize = "1Gi"
+
+// cacheNameRegex matches valid cache names. Cache names are used in
+// Kubernetes labels, so they are constrained to lowercase DNS labels, less a
+// few characters to leave room for prefixes and suffixes.
+var cacheNameRegex = regexp.MustCompile(`^[a-z0-9]([-a-z0-9]{0,38}[a-z0-9])?$`)
+
+// CacheConfig represents a cache that persists between builds of the same
+// project and is mounted into each of a job's containers.
+type CacheConfig struct {
+	// Name identifies the cache within the project.
+	Name string
+	// Path is the absolute path at which the cache is mounted.
+	Path string
+	// Key distinguishes between different caches having the same name. e.g. A
+	// key of "${DRAKE_REF}" maintains a separate cache for every branch. Job
+	// environment variables are expanded. An empty key means a single cache
+	// having the given name is shared by all of the project's builds.
+	Key string
+	// KeyFiles enumerates files, relative to the root of the project's source,
+	// whose contents are hashed and incorporated into the key. e.g. Listing
+	// go.sum starts a new cache every time dependencies change.
+	KeyFiles []string
+	// Size is the amount of storage requested for the cache.
+	Size resource.Quantity
+}
+
+// UnmarshalJSON unmarshals JSON into a CacheConfig. It is needed because
+// sizes are expressed as strings such as "5Gi" in JSON and because cache
+// configuration requires validation.
+func (c *CacheConfig) UnmarshalJSON(data []byte) error {
+	type flatCacheConfig struct {
+		Name     string   `json:"name"`
+		Path     string   `json:"path"`
+		Key      string   `json:"key"`
+		KeyFiles []string `json:"keyFiles"`
+		Size     string   `json:"size"`
+	}
+	flatCfg := flatCacheConfig{}
+	if err := json.Unmarshal(data, &flatCfg); err != nil {
+		return err
+	}
+	if !cacheNameRegex.MatchString(flatCfg.Name) {
+		return errors.Errorf(
+			"invalid cache name %q; cache names must consist of no more than 40 "+
+				"lowercase alphanumeric characters or '-', and must start and end "+
+				"with an alphanumeric character",
+			flatCfg.Name,
+		)
+	}
+	if !path.IsAbs(flatCfg.Path) {
+		return errors.Errorf(
+			"path %q for cache %q is not an absolute path",
+			flatCfg.Path,
+			flatCfg.Name,
+		)
+	}
+	if flatCfg.Size == "" {
+		flatCfg.Size = defaultCacheSize
+	}
+	size, err := resource.ParseQuantity(flatCfg.Size)
+	if err != nil {
+		return errors.Wrapf(
+			err,
+			"error parsing size %q for cache %q",
+			flatCfg.Size,
+			flatCfg.Name,
+		)
+	}
+	c.Name = flatCfg.Name
+	c.Path = path.Clean(flatCfg.Path)
+	c.Key = flatCfg.Key
+	c.KeyFiles = flatCfg.KeyFiles
+	c.Size = size
+	return nil
+}
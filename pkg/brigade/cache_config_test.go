@@ -0,0 +1,69 @@
+package brigade
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"k8s.io/apimachinery/pkg/api/resource"
+)
+
+func TestCacheConfigUnmarshalJSON(t *testing.T) {
+	testCases := []struct {
+		name       string
+		json       string
+		assertions func(*testing.T, CacheConfig, error)
+	}{
+		{
+			name: "valid cache",
+			json: `{"name":"npm","path":"/root/.npm","key":"${DRAKE_REF}","keyFiles":["package-lock.json"],"size":"5Gi"}`, // nolint: lll
+			assertions: func(t *testing.T, cacheCfg CacheConfig, err error) {
+				require.NoError(t, err)
+				require.Equal(t, "npm", cacheCfg.Name)
+				require.Equal(t, "/root/.npm", cacheCfg.Path)
+				require.Equal(t, "${DRAKE_REF}", cacheCfg.Key)
+				require.Equal(t, []string{"package-lock.json"}, cacheCfg.KeyFiles)
+				require.Equal(t, resource.MustParse("5Gi"), cacheCfg.Size)
+			},
+		},
+		{
+			name: "default size",
+			json: `{"name":"npm","path":"/root/.npm"}`,
+			assertions: func(t *testing.T, cacheCfg CacheConfig, err error) {
+				require.NoError(t, err)
+				require.Equal(t, resource.MustParse("1Gi"), cacheCfg.Size)
+			},
+		},
+		{
+			name: "invalid name",
+			json: `{"name":"Go_Modules","path":"/go/pkg/mod"}`,
+			assertions: func(t *testing.T, _ CacheConfig, err error) {
+				require.Error(t, err)
+				require.Contains(t, err.Error(), "invalid cache name")
+			},
+		},
+		{
+			name: "relative path",
+			json: `{"name":"npm","path":".npm"}`,
+			assertions: func(t *testing.T, _ CacheConfig, err error) {
+				require.Error(t, err)
+				require.Contains(t, err.Error(), "not an absolute path")
+			},
+		},
+		{
+			name: "invalid size",
+			json: `{"name":"npm","path":"/root/.npm","size":"lots"}`,
+			assertions: func(t *testing.T, _ CacheConfig, err error) {
+				require.Error(t, err)
+				require.Contains(t, err.Error(), "error parsing size")
+			},
+		},
+	}
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			cacheCfg := CacheConfig{}
+			err := json.Unmarshal([]byte(testCase.json), &cacheCfg)
+			testCase.assertions(t, cacheCfg, err)
+		})
+	}
+}
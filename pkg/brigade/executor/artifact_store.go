@@ -0,0 +1,247 @@
+package executor
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/lovethedrake/brigdrake/pkg/brigade"
+	"github.com/pkg/errors"
+	v1 "k8s.io/api/core/v1"
+)
+
+const (
+	artifactStorePVC = "pvc"
+	artifactStoreS3  = "s3"
+
+	defaultPVCArtifactCollectorImage = "busybox:1.31"
+	// defaultS3ArtifactCollectorImage is pinned because the syntax of the mc
+	// commands used by s3CollectorScript varies between mc versions.
+	defaultS3ArtifactCollectorImage = "minio/mc:RELEASE.2020-11-25T23-04-07Z"
+
+	artifactsVolumeName = "artifacts"
+	artifactsMountPath  = "/artifacts"
+)
+
+// ArtifactStore is the interface for components that artifacts collected from
+// jobs can be copied to so that they outlive the pipeline's shared storage.
+// Artifacts are copied by a collector container that runs in its own pod with
+// the pipeline's shared storage mounted.
+type ArtifactStore interface {
+	// CollectorContainer returns a container that copies the artifacts at the
+	// given paths, relative to the given source directory, into the store
+	// beneath the given prefix.
+	CollectorContainer(
+		sourceDir string,
+		prefix string,
+		paths []string,
+	) v1.Container
+	// Volumes returns any volumes, in addition to the pipeline's shared storage,
+	// that the collector container mounts.
+	Volumes() []v1.Volume
+	// URL returns the URL at which the artifacts stored beneath the given prefix
+	// can be found. An empty string is returned if there is no such URL.
+	URL(prefix string) string
+}
+
+// newArtifactStore returns the ArtifactStore enabled by the worker
+// configuration, if any.
+func newArtifactStore(
+	workerConfig brigade.WorkerConfig,
+) (ArtifactStore, error) {
+	switch workerConfig.ArtifactStore {
+	case "":
+		return nil, nil
+	case artifactStorePVC:
+		if workerConfig.ArtifactsPVC == "" {
+			return nil, errors.Errorf(
+				"the %q artifact store requires a PVC to be specified",
+				artifactStorePVC,
+			)
+		}
+		store := &pvcArtifactStore{
+			image:   workerConfig.ArtifactCollectorImage,
+			pvcName: workerConfig.ArtifactsPVC,
+			baseURL: strings.TrimSuffix(workerConfig.ArtifactsURL, "/"),
+		}
+		if store.image == "" {
+			store.image = defaultPVCArtifactCollectorImage
+		}
+		return store, nil
+	case artifactStoreS3:
+		if workerConfig.ArtifactsS3Endpoint == "" ||
+			workerConfig.ArtifactsS3Bucket == "" ||
+			workerConfig.ArtifactsS3CredentialsSecret == "" {
+			return nil, errors.Errorf(
+				"the %q artifact store requires an endpoint, a bucket, and a "+
+					"credentials secret to be specified",
+				artifactStoreS3,
+			)
+		}
+		store := &s3ArtifactStore{
+			image: workerConfig.ArtifactCollectorImage,
+			endpoint: strings.TrimSuffix(
+				workerConfig.ArtifactsS3Endpoint,
+				"/",
+			),
+			bucket:            workerConfig.ArtifactsS3Bucket,
+			credentialsSecret: workerConfig.ArtifactsS3CredentialsSecret,
+			baseURL:           strings.TrimSuffix(workerConfig.ArtifactsURL, "/"),
+		}
+		if store.image == "" {
+			store.image = defaultS3ArtifactCollectorImage
+		}
+		if store.baseURL == "" {
+			store.baseURL = fmt.Sprintf("%s/%s", store.endpoint, store.bucket)
+		}
+		return store, nil
+	default:
+		return nil, errors.Errorf(
+			"unrecognized artifact store %q",
+			workerConfig.ArtifactStore,
+		)
+	}
+}
+
+// pvcArtifactStore is an ArtifactStore that copies artifacts to a persistent
+// volume. Making the artifacts available via a URL, e.g. by serving the
+// volume's contents over HTTP, is left to the operator.
+type pvcArtifactStore struct {
+	image   string
+	pvcName string
+	baseURL string
+}
+
+// pvcCollectorScript copies each artifact, given as an argument, from the
+// source directory to the destination directory, preserving its path.
+const pvcCollectorScript = `set -e
+for artifact in "$@"; do
+  mkdir -p "$(dirname "$DEST_DIR/$artifact")"
+  cp -a "$SOURCE_DIR/$artifact" "$DEST_DIR/$artifact"
+done`
+
+func (p *pvcArtifactStore) CollectorContainer(
+	sourceDir string,
+	prefix string,
+	paths []string,
+) v1.Container {
+	return v1.Container{
+		Name:    "collector",
+		Image:   p.image,
+		Command: append([]string{"sh", "-c", pvcCollectorScript, "--"}, paths...),
+		Env: []v1.EnvVar{
+			{
+				Name:  "SOURCE_DIR",
+				Value: sourceDir,
+			},
+			{
+				Name:  "DEST_DIR",
+				Value: fmt.Sprintf("%s/%s", artifactsMountPath, prefix),
+			},
+		},
+		VolumeMounts: []v1.VolumeMount{
+			{
+				Name:      artifactsVolumeName,
+				MountPath: artifactsMountPath,
+			},
+		},
+	}
+}
+
+func (p *pvcArtifactStore) Volumes() []v1.Volume {
+	return []v1.Volume{
+		{
+			Name: artifactsVolumeName,
+			VolumeSource: v1.VolumeSource{
+				PersistentVolumeClaim: &v1.PersistentVolumeClaimVolumeSource{
+					ClaimName: p.pvcName,
+				},
+			},
+		},
+	}
+}
+
+func (p *pvcArtifactStore) URL(prefix string) string {
+	if p.baseURL == "" {
+		return ""
+	}
+	return fmt.Sprintf("%s/%s/", p.baseURL, prefix)
+}
+
+// s3ArtifactStore is an ArtifactStore that uploads artifacts to a bucket of
+// any S3-compatible endpoint, e.g. MinIO.
+type s3ArtifactStore struct {
+	image             string
+	endpoint          string
+	bucket            string
+	credentialsSecret string
+	baseURL           string
+}
+
+// s3CollectorScript uploads each artifact, given as an argument, from the
+// source directory to the destination within the bucket, preserving its path.
+// Directories are mirrored recursively.
+const s3CollectorScript = `set -e
+mc alias set store "$S3_ENDPOINT" "$S3_ACCESS_KEY_ID" "$S3_SECRET_ACCESS_KEY"
+for artifact in "$@"; do
+  if [ -d "$SOURCE_DIR/$artifact" ]; then
+    mc mirror "$SOURCE_DIR/$artifact" "store/$DEST/$artifact"
+  else
+    mc cp "$SOURCE_DIR/$artifact" "store/$DEST/$artifact"
+  fi
+done`
+
+func (s *s3ArtifactStore) CollectorContainer(
+	sourceDir string,
+	prefix string,
+	paths []string,
+) v1.Container {
+	return v1.Container{
+		Name:    "collector",
+		Image:   s.image,
+		Command: append([]string{"sh", "-c", s3CollectorScript, "--"}, paths...),
+		Env: []v1.EnvVar{
+			{
+				Name:  "SOURCE_DIR",
+				Value: sourceDir,
+			},
+			{
+				Name:  "DEST",
+				Value: fmt.Sprintf("%s/%s", s.bucket, prefix),
+			},
+			{
+				Name:  "S3_ENDPOINT",
+				Value: s.endpoint,
+			},
+			{
+				Name: "S3_ACCESS_KEY_ID",
+				ValueFrom: &v1.EnvVarSource{
+					SecretKeyRef: &v1.SecretKeySelector{
+						LocalObjectReference: v1.LocalObjectReference{
+							Name: s.credentialsSecret,
+						},
+						Key: "accessKeyID",
+					},
+				},
+			},
+			{
+				Name: "S3_SECRET_ACCESS_KEY",
+				ValueFrom: &v1.EnvVarSource{
+					SecretKeyRef: &v1.SecretKeySelector{
+						LocalObjectReference: v1.LocalObjectReference{
+							Name: s.credentialsSecret,
+						},
+						Key: "secretAccessKey",
+					},
+				},
+			},
+		},
+	}
+}
+
+func (s *s3ArtifactStore) Volumes() []v1.Volume {
+	return nil
+}
+
+func (s *s3ArtifactStore) URL(prefix string) string {
+	return fmt.Sprintf("%s/%s/", s.baseURL, prefix)
+}
@@ -0,0 +1,144 @@
+package executor
+
+import (
+	"testing"
+
+	"github.com/lovethedrake/brigdrake/pkg/brigade"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewArtifactStore(t *testing.T) {
+	testCases := []struct {
+		name         string
+		workerConfig brigade.WorkerConfig
+		assertions   func(*testing.T, ArtifactStore, error)
+	}{
+		{
+			name:         "no artifact store",
+			workerConfig: brigade.WorkerConfig{},
+			assertions: func(t *testing.T, store ArtifactStore, err error) {
+				require.NoError(t, err)
+				require.Nil(t, store)
+			},
+		},
+		{
+			name: "pvc artifact store without pvc",
+			workerConfig: brigade.WorkerConfig{
+				ArtifactStore: artifactStorePVC,
+			},
+			assertions: func(t *testing.T, _ ArtifactStore, err error) {
+				require.Error(t, err)
+				require.Contains(t, err.Error(), "requires a PVC")
+			},
+		},
+		{
+			name: "pvc artifact store without url",
+			workerConfig: brigade.WorkerConfig{
+				ArtifactStore: artifactStorePVC,
+				ArtifactsPVC:  "artifacts",
+			},
+			assertions: func(t *testing.T, store ArtifactStore, err error) {
+				require.NoError(t, err)
+				require.IsType(t, &pvcArtifactStore{}, store)
+				require.Equal(t, "", store.URL("foo/bar"))
+				require.Len(t, store.Volumes(), 1)
+				require.Equal(
+					t,
+					"artifacts",
+					store.Volumes()[0].PersistentVolumeClaim.ClaimName,
+				)
+				container := store.CollectorContainer("/shared", "foo/bar", nil)
+				require.Equal(t, defaultPVCArtifactCollectorImage, container.Image)
+			},
+		},
+		{
+			name: "pvc artifact store with url",
+			workerConfig: brigade.WorkerConfig{
+				ArtifactStore: artifactStorePVC,
+				ArtifactsPVC:  "artifacts",
+				ArtifactsURL:  "https://artifacts.example.com/",
+			},
+			assertions: func(t *testing.T, store ArtifactStore, err error) {
+				require.NoError(t, err)
+				require.Equal(
+					t,
+					"https://artifacts.example.com/foo/bar/",
+					store.URL("foo/bar"),
+				)
+			},
+		},
+		{
+			name: "s3 artifact store without bucket",
+			workerConfig: brigade.WorkerConfig{
+				ArtifactStore:                artifactStoreS3,
+				ArtifactsS3Endpoint:          "http://minio:9000",
+				ArtifactsS3CredentialsSecret: "minio",
+			},
+			assertions: func(t *testing.T, _ ArtifactStore, err error) {
+				require.Error(t, err)
+				require.Contains(t, err.Error(), "requires an endpoint, a bucket")
+			},
+		},
+		{
+			name: "s3 artifact store",
+			workerConfig: brigade.WorkerConfig{
+				ArtifactStore:                artifactStoreS3,
+				ArtifactCollectorImage:       "minio/mc:edge",
+				ArtifactsS3Endpoint:          "http://minio:9000/",
+				ArtifactsS3Bucket:            "artifacts",
+				ArtifactsS3CredentialsSecret: "minio",
+			},
+			assertions: func(t *testing.T, store ArtifactStore, err error) {
+				require.NoError(t, err)
+				require.IsType(t, &s3ArtifactStore{}, store)
+				require.Equal(
+					t,
+					"http://minio:9000/artifacts/foo/bar/",
+					store.URL("foo/bar"),
+				)
+				require.Empty(t, store.Volumes())
+				container := store.CollectorContainer(
+					"/shared",
+					"foo/bar",
+					[]string{"bin", "coverage.txt"},
+				)
+				require.Equal(t, "minio/mc:edge", container.Image)
+				require.Equal(
+					t,
+					[]string{"bin", "coverage.txt"},
+					container.Command[len(container.Command)-2:],
+				)
+				env := map[string]string{}
+				for _, envVar := range container.Env {
+					env[envVar.Name] = envVar.Value
+					if envVar.ValueFrom != nil {
+						require.Equal(
+							t,
+							"minio",
+							envVar.ValueFrom.SecretKeyRef.Name,
+						)
+					}
+				}
+				require.Equal(t, "/shared", env["SOURCE_DIR"])
+				require.Equal(t, "artifacts/foo/bar", env["DEST"])
+				require.Equal(t, "http://minio:9000", env["S3_ENDPOINT"])
+			},
+		},
+		{
+			name: "unrecognized artifact store",
+			workerConfig: brigade.WorkerConfig{
+				ArtifactStore: "ftp",
+			},
+			assertions: func(t *testing.T, _ ArtifactStore, err error) {
+				require.Error(t, err)
+				require.Contains(t, err.Error(), "unrecognized artifact store")
+			},
+		},
+	}
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			store, err := newArtifactStore(testCase.workerConfig)
+			testCase.assertions(t, store, err)
+		})
+	}
+}
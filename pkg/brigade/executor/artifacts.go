@@ -0,0 +1,154 @@
+package executor
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strings"
+
+	"github.com/lovethedrake/brigdrake/pkg/brigade"
+	"github.com/lovethedrake/drakecore/config"
+	"github.com/pkg/errors"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+)
+
+const artifactsSourceDir = "/shared"
+
+// jobUsesSharedStorage returns true if any of the job's containers mounts the
+// pipeline's shared storage.
+func jobUsesSharedStorage(job config.Job) bool {
+	if job.PrimaryContainer().SharedStorageMountPath() != "" {
+		return true
+	}
+	for _, sidecarContainer := range job.SidecarContainers() {
+		if sidecarContainer.SharedStorageMountPath() != "" {
+			return true
+		}
+	}
+	return false
+}
+
+// artifactsPrefix returns the prefix beneath which the artifacts of the named
+// job are stored.
+func artifactsPrefix(
+	project brigade.Project,
+	event brigade.Event,
+	pipelineName string,
+	jobName string,
+) string {
+	return fmt.Sprintf(
+		"%s/%s/%s/%s",
+		project.ID,
+		strings.ToLower(event.BuildID),
+		pipelineName,
+		jobName,
+	)
+}
+
+// collectJobArtifacts copies the artifacts the job's configuration enumerates
+// from the pipeline's shared storage to the artifact store using a collector
+// pod. It returns the URL at which the artifacts can be found, if any.
+func collectJobArtifacts(
+	ctx context.Context,
+	project brigade.Project,
+	event brigade.Event,
+	workerConfig brigade.WorkerConfig,
+	pipelineName string,
+	job config.Job,
+	jobPodName string,
+	artifactStore ArtifactStore,
+	kubeClient kubernetes.Interface,
+) (string, error) {
+	prefix := artifactsPrefix(project, event, pipelineName, job.Name())
+	pod := buildArtifactCollectorPod(
+		project,
+		event,
+		pipelineName,
+		job.Name(),
+		jobPodName,
+		prefix,
+		project.Jobs[job.Name()].Artifacts,
+		artifactStore,
+	)
+	if _, err := kubeClient.CoreV1().Pods(
+		project.Kubernetes.Namespace,
+	).Create(pod); err != nil {
+		return "", errors.Wrapf(err, "error creating pod %q", pod.Name)
+	}
+	// The collector pod is of no further use once it's done, however that
+	// turns out.
+	defer func() {
+		if err := kubeClient.CoreV1().Pods(
+			project.Kubernetes.Namespace,
+		).Delete(pod.Name, &metav1.DeleteOptions{}); err != nil {
+			log.Printf("error deleting artifact collector pod %q: %s", pod.Name, err)
+		}
+	}()
+	if err := waitForJobPodCompletion(
+		ctx,
+		project.Kubernetes.Namespace,
+		fmt.Sprintf("%s-artifacts", qualifiedJobName(pipelineName, job.Name())),
+		pod.Name,
+		jobTimeout(project, workerConfig, job.Name()),
+		kubeClient,
+	); err != nil {
+		return "", err
+	}
+	return artifactStore.URL(prefix), nil
+}
+
+func buildArtifactCollectorPod(
+	project brigade.Project,
+	event brigade.Event,
+	pipelineName string,
+	jobName string,
+	jobPodName string,
+	prefix string,
+	paths []string,
+	artifactStore ArtifactStore,
+) *v1.Pod {
+	collectorContainer :=
+		artifactStore.CollectorContainer(artifactsSourceDir, prefix, paths)
+	collectorContainer.VolumeMounts = append(
+		collectorContainer.VolumeMounts,
+		v1.VolumeMount{
+			Name:      sharedStorageVolumeName,
+			MountPath: artifactsSourceDir,
+			ReadOnly:  true,
+		},
+	)
+	return &v1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: resourceName(jobPodName, "artifacts"),
+			Labels: map[string]string{
+				"heritage":             "brigade",
+				"component":            "artifactCollector",
+				"project":              labelValue(project.ID),
+				"worker":               labelValue(event.WorkerID),
+				"build":                labelValue(event.BuildID),
+				"thedrake.io/pipeline": labelValue(pipelineName),
+				"thedrake.io/job":      labelValue(jobName),
+			},
+		},
+		Spec: v1.PodSpec{
+			RestartPolicy: v1.RestartPolicyNever,
+			Volumes: append(
+				[]v1.Volume{
+					{
+						Name: sharedStorageVolumeName,
+						VolumeSource: v1.VolumeSource{
+							PersistentVolumeClaim: &v1.PersistentVolumeClaimVolumeSource{
+								ClaimName: sharedStoragePVCName(event.WorkerID, pipelineName),
+								ReadOnly:  true,
+							},
+						},
+					},
+				},
+				artifactStore.Volumes()...,
+			),
+			Containers: []v1.Container{collectorContainer},
+		},
+	}
+}
@@ -0,0 +1,180 @@
+package executor
+
+import (
+	"context"
+	"testing"
+
+	"github.com/lovethedrake/brigdrake/pkg/brigade"
+	"github.com/lovethedrake/drakecore/config"
+	"github.com/stretchr/testify/require"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/watch"
+	"k8s.io/client-go/kubernetes/fake"
+	k8stesting "k8s.io/client-go/testing"
+)
+
+func TestJobUsesSharedStorage(t *testing.T) {
+	require.False(
+		t,
+		jobUsesSharedStorage(
+			&fakeJob{
+				name:             "foo",
+				primaryContainer: &fakeContainer{name: "bar"},
+			},
+		),
+	)
+	require.True(
+		t,
+		jobUsesSharedStorage(
+			&fakeJob{
+				name:             "foo",
+				primaryContainer: &fakeContainer{name: "bar"},
+				sidecarContainers: []config.Container{
+					&fakeContainer{
+						name:                   "bat",
+						sharedStorageMountPath: "/shared",
+					},
+				},
+			},
+		),
+	)
+}
+
+func TestBuildArtifactCollectorPod(t *testing.T) {
+	project := brigade.Project{ID: "brigade-123"}
+	event := brigade.Event{
+		WorkerID: "WORKER",
+		BuildID:  "BUILD",
+	}
+	prefix := artifactsPrefix(project, event, "ci", "build")
+	require.Equal(t, "brigade-123/build/ci/build", prefix)
+	pod := buildArtifactCollectorPod(
+		project,
+		event,
+		"ci",
+		"build",
+		"ci-build-build",
+		prefix,
+		[]string{"bin"},
+		&pvcArtifactStore{
+			image:   defaultPVCArtifactCollectorImage,
+			pvcName: "artifacts",
+		},
+	)
+	require.Equal(t, "ci-build-build-artifacts", pod.Name)
+	require.Equal(t, "artifactCollector", pod.Labels["component"])
+	require.Equal(t, "ci", pod.Labels["thedrake.io/pipeline"])
+	require.Len(t, pod.Spec.Volumes, 2)
+	require.Equal(
+		t,
+		sharedStoragePVCName("WORKER", "ci"),
+		pod.Spec.Volumes[0].PersistentVolumeClaim.ClaimName,
+	)
+	require.Equal(
+		t,
+		"artifacts",
+		pod.Spec.Volumes[1].PersistentVolumeClaim.ClaimName,
+	)
+	require.Len(t, pod.Spec.Containers, 1)
+	require.Contains(
+		t,
+		pod.Spec.Containers[0].VolumeMounts,
+		v1.VolumeMount{
+			Name:      sharedStorageVolumeName,
+			MountPath: artifactsSourceDir,
+			ReadOnly:  true,
+		},
+	)
+	require.Contains(
+		t,
+		pod.Spec.Containers[0].Env,
+		v1.EnvVar{
+			Name:  "DEST_DIR",
+			Value: "/artifacts/brigade-123/build/ci/build",
+		},
+	)
+}
+
+func TestCollectJobArtifactsDeletesCollectorPod(t *testing.T) {
+	testCases := []struct {
+		name       string
+		reason     string
+		assertions func(*testing.T, string, error)
+	}{
+		{
+			name:   "collection succeeds",
+			reason: "Completed",
+			assertions: func(t *testing.T, url string, err error) {
+				require.NoError(t, err)
+				require.Equal(t, "https://example.com/brigade-123/abc/ci/build/", url)
+			},
+		},
+		{
+			name:   "collection fails",
+			reason: "Error",
+			assertions: func(t *testing.T, _ string, err error) {
+				require.Error(t, err)
+			},
+		},
+	}
+	project := brigade.Project{
+		ID: "brigade-123",
+		Kubernetes: brigade.KubernetesConfig{
+			Namespace: testNamespace,
+		},
+	}
+	event := brigade.Event{
+		WorkerID: "worker",
+		BuildID:  "abc",
+	}
+	job := &fakeJob{
+		name:             "build",
+		primaryContainer: &fakeContainer{name: "go"},
+	}
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			kubeClient := fake.NewSimpleClientset()
+			// The collector pod terminates as soon as it's watched
+			kubeClient.PrependWatchReactor(
+				"pods",
+				func(k8stesting.Action) (bool, watch.Interface, error) {
+					watcher := watch.NewFakeWithChanSize(1, false)
+					pod := newRunningTestPod("ci-build-abc-artifacts")
+					pod.Status.ContainerStatuses = []v1.ContainerStatus{
+						{
+							Name: pod.Spec.Containers[0].Name,
+							State: v1.ContainerState{
+								Terminated: &v1.ContainerStateTerminated{
+									Reason: testCase.reason,
+								},
+							},
+						},
+					}
+					watcher.Modify(pod)
+					return true, watcher, nil
+				},
+			)
+			url, err := collectJobArtifacts(
+				context.Background(),
+				project,
+				event,
+				brigade.NewWorkerConfigWithDefaults(),
+				"ci",
+				job,
+				"ci-build-abc",
+				&pvcArtifactStore{
+					image:   defaultPVCArtifactCollectorImage,
+					pvcName: "artifacts",
+					baseURL: "https://example.com",
+				},
+				kubeClient,
+			)
+			testCase.assertions(t, url, err)
+			pods, err :=
+				kubeClient.CoreV1().Pods(testNamespace).List(metav1.ListOptions{})
+			require.NoError(t, err)
+			require.Empty(t, pods.Items)
+		})
+	}
+}
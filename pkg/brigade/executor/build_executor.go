@@ -1,25 +1,20 @@
 package executor
 
 import (
+	"bytes"
 	"context"
 	"log"
 	"os"
 	"sync"
+	"time"
 
 	"github.com/lovethedrake/brigdrake/pkg/brigade"
 	"github.com/lovethedrake/brigdrake/pkg/drake"
-	"github.com/lovethedrake/brigdrake/pkg/drake/brig"
-	"github.com/lovethedrake/brigdrake/pkg/drake/github"
 	"github.com/lovethedrake/drakecore/config"
 	"github.com/pkg/errors"
 	"k8s.io/client-go/kubernetes"
 )
 
-var triggerBuilderFns = map[string]func([]byte) (drake.Trigger, error){
-	"github.com/lovethedrake/drakespec-github": github.NewTriggerFromJSON,
-	"github.com/lovethedrake/drakespec-brig":   brig.NewTriggerFromJSON,
-}
-
 // ExecuteBuild can execute a Brigade build driven via Drakefile.yaml when
 // supplied with a Brigade project, event, and worker configuration, as well
 // as a Kubernetes client.
@@ -66,64 +61,64 @@ func ExecuteBuild(
 		return errors.Wrapf(err, "error reading %s", drakefileLocation)
 	}
 
+	// Validate the entire Drakefile before doing anything else so that every
+	// problem with it is reported at once.
+	if errs := validateDrakefile(project, cfg); len(errs) > 0 {
+		notifyValidationFailure(project, event, errs)
+		return &drakefileValidationError{errs: errs}
+	}
+
 	// Find all pipelines that are eligible for execution-- and associate them
 	// with a JobStatusNotifier obtained from the trigger that identified the
-	// pipeline as eligible.
-	pipelinesToExecute := map[config.Pipeline]drake.JobStatusNotifier{}
+	// pipeline as eligible and with the subset of the pipeline's jobs that are
+	// to be executed. Every trigger evaluation is tabulated so that the log
+	// explains why each pipeline was or wasn't executed.
+	pipelinesToExecute := map[config.Pipeline]pipelineExecution{}
+	evaluationsTable := &bytes.Buffer{}
+	evaluationsWriter := newTriggerEvaluationsWriter(evaluationsTable)
 	for _, pipeline := range cfg.AllPipelines() {
-		log.Printf("evaluating triggers for pipeline %q", pipeline.Name())
-		for i, pipelineTrigger := range pipeline.Triggers() {
-			triggerBuilderFn, ok := triggerBuilderFns[pipelineTrigger.SpecURI()]
-			if !ok {
-				// Don't know what to do with this trigger...
-				continue // Next trigger
-			}
-			trigger, err := triggerBuilderFn(pipelineTrigger.Config())
-			if err != nil {
-				return errors.Wrapf(
-					err,
-					"error parsing trigger %d (%q) configuration for pipeline %q",
-					i,
-					pipelineTrigger.SpecURI(),
-					pipeline.Name(),
-				)
-			}
-			meetsCriteria, err := trigger.Matches(event)
-			if err != nil {
-				return errors.Wrapf(
-					err,
-					"error evaluating execution criteria for trigger %d (%q) "+
-						"configuration for pipeline %q",
-					i,
-					pipelineTrigger.SpecURI(),
-					pipeline.Name(),
-				)
-			}
-			if meetsCriteria {
-				jsn, err := trigger.JobStatusNotifier(project, event)
-				if err != nil {
-					return errors.Wrapf(
-						err,
-						"error obtaining job status notifier for trigger %d (%q) "+
-							"configuration for pipeline %q",
-						i,
-						pipelineTrigger.SpecURI(),
-						pipeline.Name(),
-					)
-				}
-				pipelinesToExecute[pipeline] = jsn
-				break // Stop iterating over triggers; move on to the next pipeline
-			}
+		execution, evaluations, err :=
+			evaluatePipeline(project, event, pipeline, true)
+		if err != nil {
+			return err
 		}
+		writeTriggerEvaluations(evaluationsWriter, pipeline.Name(), evaluations)
+		if execution != nil {
+			pipelinesToExecute[pipeline] = *execution
+		}
+	}
+	if err = evaluationsWriter.Flush(); err != nil {
+		return errors.Wrap(err, "error formatting trigger evaluations")
 	}
+	log.Printf("trigger evaluations:\n%s", evaluationsTable)
 
 	// Bail if we found no pipelines to execute
 	if len(pipelinesToExecute) == 0 {
 		return nil
 	}
 
-	// Create build secret
-	if err := createBuildSecret(project, event, kubeClient); err != nil {
+	logSinks, err := newLogSinks(event, workerConfig)
+	if err != nil {
+		return errors.Wrap(err, "error initializing job log sinks")
+	}
+
+	artifactStore, err := newArtifactStore(workerConfig)
+	if err != nil {
+		return errors.Wrap(err, "error initializing artifact store")
+	}
+
+	// Create build secret containing only the project secrets that will be
+	// exposed to job containers
+	secretNames, err := requiredSecrets(project, pipelinesToExecute)
+	if err != nil {
+		return err
+	}
+	if err = createBuildSecret(
+		project,
+		event,
+		secretNames,
+		kubeClient,
+	); err != nil {
 		return err
 	}
 	defer func() {
@@ -132,19 +127,43 @@ func ExecuteBuild(
 		}
 	}()
 
+	// Create a secret containing the event payload, which is mounted into job
+	// containers
+	if err = createEventPayloadSecret(project, event, kubeClient); err != nil {
+		return err
+	}
+	defer func() {
+		if err := destroyEventPayloadSecret(
+			project,
+			event,
+			kubeClient,
+		); err != nil {
+			log.Printf("error destroying event payload secret: %s", err)
+		}
+	}()
+
 	// Execute all pipelines we have identified-- each in their own goroutine
 	wg := &sync.WaitGroup{}
 	errCh := make(chan error)
-	for pipeline, jsn := range pipelinesToExecute {
+	for pipeline, execution := range pipelinesToExecute {
 		p := pipeline // Avoid closing over a variable we're using for iteration
+		pipelineProject := project
+		if execution.withholdSecrets {
+			pipelineProject = projectWithoutSecrets(project)
+		}
 		wg.Add(1)
 		go executePipeline(
 			ctx,
-			project,
+			pipelineProject,
 			event,
 			workerConfig,
 			p,
-			jsn,
+			execution.matchResult,
+			execution.jobs,
+			execution.environment,
+			execution.jobStatusNotifier,
+			logSinks,
+			artifactStore,
 			kubeClient,
 			wg,
 			errCh,
@@ -176,6 +195,13 @@ errLoop:
 		}
 	}
 
+	// Now that the build's jobs have used their caches, evict any of the
+	// project's caches that have gone unused for too long or that don't fit
+	// within the project's limits.
+	if err := evictCaches(project, time.Now(), kubeClient); err != nil {
+		log.Printf("error evicting caches: %s", err)
+	}
+
 	if len(errs) > 1 {
 		return &multiError{errs: errs}
 	}
@@ -184,3 +210,148 @@ errLoop:
 	}
 	return nil
 }
+
+// evaluatePipeline evaluates the pipeline's triggers against the event in
+// order. If one matches, the pipeline is eligible for execution and the
+// returned pipelineExecution associates it with a JobStatusNotifier obtained
+// from the trigger that identified the pipeline as eligible and with the subset
+// of the pipeline's jobs that are to be executed. The evaluation of each
+// trigger, up to and including the first that matched, is returned either way.
+// Obtaining a JobStatusNotifier may involve calls to the event provider's API,
+// so it is only done if withNotifier is set; otherwise the pipelineExecution
+// has none.
+func evaluatePipeline(
+	project brigade.Project,
+	event brigade.Event,
+	pipeline config.Pipeline,
+	withNotifier bool,
+) (*pipelineExecution, []TriggerEvaluation, error) {
+	log.Printf("evaluating triggers for pipeline %q", pipeline.Name())
+	evaluations := []TriggerEvaluation{}
+	for i, pipelineTrigger := range pipeline.Triggers() {
+		triggerBuilderFn, ok := drake.LookupTrigger(
+			pipelineTrigger.SpecURI(),
+			pipelineTrigger.SpecVersion(),
+		)
+		if !ok {
+			// Don't know what to do with this trigger...
+			evaluations = append(
+				evaluations,
+				TriggerEvaluation{SpecURI: pipelineTrigger.SpecURI()},
+			)
+			continue // Next trigger
+		}
+		trigger, err := triggerBuilderFn(pipelineTrigger.Config())
+		if err != nil {
+			return nil, evaluations, errors.Wrapf(
+				err,
+				"error parsing trigger %d (%q) configuration for pipeline %q",
+				i,
+				pipelineTrigger.SpecURI(),
+				pipeline.Name(),
+			)
+		}
+		result, err := trigger.Matches(project, event)
+		if err != nil {
+			return nil, evaluations, errors.Wrapf(
+				err,
+				"error evaluating execution criteria for trigger %d (%q) "+
+					"configuration for pipeline %q",
+				i,
+				pipelineTrigger.SpecURI(),
+				pipeline.Name(),
+			)
+		}
+		evaluations = append(
+			evaluations,
+			TriggerEvaluation{
+				SpecURI:   pipelineTrigger.SpecURI(),
+				Supported: true,
+				Result:    result,
+			},
+		)
+		if result.Matched {
+			var jsn drake.JobStatusNotifier
+			if withNotifier {
+				if jsn, err = trigger.JobStatusNotifier(project, event); err != nil {
+					return nil, evaluations, errors.Wrapf(
+						err,
+						"error obtaining job status notifier for trigger %d (%q) "+
+							"configuration for pipeline %q",
+						i,
+						pipelineTrigger.SpecURI(),
+						pipeline.Name(),
+					)
+				}
+			}
+			var selectedJobNames []string
+			if jobSelector, ok := trigger.(drake.JobSelector); ok {
+				if selectedJobNames, err =
+					jobSelector.SelectedJobs(event); err != nil {
+					return nil, evaluations, errors.Wrapf(
+						err,
+						"error selecting jobs for trigger %d (%q) configuration for "+
+							"pipeline %q",
+						i,
+						pipelineTrigger.SpecURI(),
+						pipeline.Name(),
+					)
+				}
+			}
+			jobs := selectPipelineJobs(pipeline, selectedJobNames)
+			if len(jobs) == 0 {
+				log.Printf(
+					"pipeline %q contains none of the selected jobs %q",
+					pipeline.Name(),
+					selectedJobNames,
+				)
+				return nil, evaluations, nil
+			}
+			var environment map[string]string
+			if environmentProvider, ok :=
+				trigger.(drake.JobEnvironmentProvider); ok {
+				if environment, err =
+					environmentProvider.JobEnvironment(event); err != nil {
+					return nil, evaluations, errors.Wrapf(
+						err,
+						"error obtaining job environment for trigger %d (%q) "+
+							"configuration for pipeline %q",
+						i,
+						pipelineTrigger.SpecURI(),
+						pipeline.Name(),
+					)
+				}
+			}
+			var withholdSecrets bool
+			if originEvaluator, ok := trigger.(drake.EventOriginEvaluator); ok {
+				origin, err := originEvaluator.EventOrigin(event)
+				if err != nil {
+					return nil, evaluations, errors.Wrapf(
+						err,
+						"error evaluating event origin for trigger %d (%q) "+
+							"configuration for pipeline %q",
+						i,
+						pipelineTrigger.SpecURI(),
+						pipeline.Name(),
+					)
+				}
+				withholdSecrets = secretsWithheld(project, origin)
+				if withholdSecrets {
+					log.Printf(
+						"withholding secrets from pipeline %q because of the event's "+
+							"origin",
+						pipeline.Name(),
+					)
+				}
+			}
+			return &pipelineExecution{
+				matchResult:       result,
+				jobs:              jobs,
+				environment:       environment,
+				jobStatusNotifier: jsn,
+				withholdSecrets:   withholdSecrets,
+			}, evaluations, nil
+		}
+	}
+	return nil, evaluations, nil
+}
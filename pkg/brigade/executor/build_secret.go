@@ -13,9 +13,10 @@ import (
 func createBuildSecret(
 	project brigade.Project,
 	event brigade.Event,
+	secretNames []string,
 	kubeClient kubernetes.Interface,
 ) error {
-	secret := buildBuildSecret(project, event)
+	secret := buildBuildSecret(project, event, secretNames)
 	if _, err := kubeClient.CoreV1().Secrets(
 		project.Kubernetes.Namespace,
 	).Create(secret); err != nil {
@@ -28,19 +29,31 @@ func createBuildSecret(
 	return nil
 }
 
-func buildBuildSecret(project brigade.Project, event brigade.Event) *v1.Secret {
+// buildBuildSecret returns a secret containing the named project secrets. Only
+// secrets that are to be exposed to at least one job container should be
+// named, so that no other project secrets are needlessly written to the
+// cluster.
+func buildBuildSecret(
+	project brigade.Project,
+	event brigade.Event,
+	secretNames []string,
+) *v1.Secret {
+	stringData := make(map[string]string, len(secretNames))
+	for _, secretName := range secretNames {
+		stringData[secretName] = project.Secrets[secretName]
+	}
 	return &v1.Secret{
 		ObjectMeta: metav1.ObjectMeta{
-			Name: strings.ToLower(event.BuildID),
+			Name: buildSecretName(event),
 			Labels: map[string]string{
 				"heritage":  "brigade",
 				"component": "buildSecret",
-				"project":   project.ID,
-				"worker":    strings.ToLower(event.WorkerID),
-				"build":     strings.ToLower(event.BuildID),
+				"project":   labelValue(project.ID),
+				"worker":    labelValue(strings.ToLower(event.WorkerID)),
+				"build":     labelValue(strings.ToLower(event.BuildID)),
 			},
 		},
-		StringData: project.Secrets,
+		StringData: stringData,
 	}
 }
 
@@ -52,7 +65,7 @@ func destroyBuildSecret(
 	if err := kubeClient.CoreV1().Secrets(
 		project.Kubernetes.Namespace,
 	).Delete(
-		strings.ToLower(event.BuildID),
+		buildSecretName(event),
 		&metav1.DeleteOptions{},
 	); err != nil {
 		return errors.Wrapf(
@@ -63,3 +76,9 @@ func destroyBuildSecret(
 	}
 	return nil
 }
+
+// buildSecretName returns the name of the secret that holds the build's
+// secrets.
+func buildSecretName(event brigade.Event) string {
+	return resourceName(strings.ToLower(event.BuildID))
+}
@@ -11,11 +11,51 @@ import (
 )
 
 func TestBuildBuildSecret(t *testing.T) {
-	buildBuildSecret(
-		brigade.Project{},
-		brigade.Event{},
-	)
-	// TODO: What's worth asserting here?
+	testCases := []struct {
+		name       string
+		event      brigade.Event
+		assertions func(*testing.T, *v1.Secret)
+	}{
+		{
+			name:  "event without payload",
+			event: brigade.Event{BuildID: "FOO"},
+			assertions: func(t *testing.T, secret *v1.Secret) {
+				require.Equal(t, "foo", secret.Name)
+				require.Equal(
+					t,
+					map[string]string{"BAR": "bat"},
+					secret.StringData,
+				)
+				require.Empty(t, secret.Data)
+			},
+		},
+		{
+			name: "event with payload",
+			event: brigade.Event{
+				BuildID: "FOO",
+				Payload: []byte(`{"foo":"bar"}`),
+			},
+			assertions: func(t *testing.T, secret *v1.Secret) {
+				// The payload is stored in a secret of its own
+				require.Empty(t, secret.Data)
+			},
+		},
+	}
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			secret := buildBuildSecret(
+				brigade.Project{
+					Secrets: map[string]string{
+						"BAR": "bat",
+						"BAZ": "qux",
+					},
+				},
+				testCase.event,
+				[]string{"BAR"},
+			)
+			testCase.assertions(t, secret)
+		})
+	}
 }
 
 func TestDestroyBuildSecret(t *testing.T) {
@@ -0,0 +1,319 @@
+package executor
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+
+	"github.com/lovethedrake/brigdrake/pkg/brigade"
+	"github.com/lovethedrake/drakecore/config"
+	"github.com/pkg/errors"
+	v1 "k8s.io/api/core/v1"
+	kerrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+)
+
+const (
+	// workerSourceDir is where the worker's own copy of the project's source,
+	// if any, is checked out.
+	workerSourceDir         = "/vcs"
+	cacheComponent          = "buildCache"
+	cacheNameLabel          = "thedrake.io/cache"
+	cacheLastUsedAnnotation = "thedrake.io/cache-last-used"
+)
+
+// jobCache represents a cache, with its key resolved, that is to be mounted
+// into each of a job's containers.
+type jobCache struct {
+	name    string
+	path    string
+	size    resource.Quantity
+	pvcName string
+}
+
+// resolveJobCaches returns the caches, indexed by job name, for each of the
+// given jobs of the named pipeline. Cache keys are expanded using the job's
+// environment and incorporate a hash of the contents of any key files, which
+// are read from the given source directory.
+func resolveJobCaches(
+	project brigade.Project,
+	event brigade.Event,
+	pipelineName string,
+	jobs []config.PipelineJob,
+	environment map[string]string,
+	sourceDir string,
+) (map[string][]jobCache, error) {
+	caches := map[string][]jobCache{}
+	for _, pipelineJob := range jobs {
+		jobName := pipelineJob.Job().Name()
+		cacheCfgs := project.Jobs[jobName].Caches
+		if len(cacheCfgs) == 0 {
+			continue
+		}
+		jobEnv :=
+			jobEnvironment(project, event, pipelineName, jobName, environment)
+		jobCaches := make([]jobCache, len(cacheCfgs))
+		for i, cacheCfg := range cacheCfgs {
+			hash := sha256.New()
+			fmt.Fprintf(hash, "%s\x00%s\x00", project.ID, cacheCfg.Name)
+			fmt.Fprintf(
+				hash,
+				"%s\x00",
+				os.Expand(cacheCfg.Key, func(name string) string {
+					return jobEnv[name]
+				}),
+			)
+			for _, keyFile := range cacheCfg.KeyFiles {
+				if err := hashFile(
+					hash,
+					filepath.Join(sourceDir, filepath.FromSlash(keyFile)),
+				); err != nil {
+					return nil, errors.Wrapf(
+						err,
+						"error hashing key file %q for cache %q of job %q",
+						keyFile,
+						cacheCfg.Name,
+						jobName,
+					)
+				}
+			}
+			jobCaches[i] = jobCache{
+				name: cacheCfg.Name,
+				path: cacheCfg.Path,
+				size: cacheCfg.Size,
+				pvcName: fmt.Sprintf(
+					"cache-%s-%s",
+					cacheCfg.Name,
+					hex.EncodeToString(hash.Sum(nil))[:16],
+				),
+			}
+		}
+		caches[jobName] = jobCaches
+	}
+	return caches, nil
+}
+
+// hashFile writes the name and contents of the given file to the given hash.
+func hashFile(hash io.Writer, path string) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	fmt.Fprintf(hash, "%s\x00", filepath.Base(path))
+	_, err = io.Copy(hash, file)
+	return err
+}
+
+// ensureCachePVC creates the PVC for the given cache if it doesn't already
+// exist. If it does exist, it's marked as having been used at the given time
+// so that it isn't evicted.
+func ensureCachePVC(
+	project brigade.Project,
+	workerConfig brigade.WorkerConfig,
+	cache jobCache,
+	now time.Time,
+	kubeClient kubernetes.Interface,
+) error {
+	pvcsClient :=
+		kubeClient.CoreV1().PersistentVolumeClaims(project.Kubernetes.Namespace)
+	pvc, err := pvcsClient.Get(cache.pvcName, metav1.GetOptions{})
+	if err == nil {
+		if pvc.Annotations == nil {
+			pvc.Annotations = map[string]string{}
+		}
+		pvc.Annotations[cacheLastUsedAnnotation] = now.UTC().Format(time.RFC3339)
+		if _, err = pvcsClient.Update(pvc); err != nil {
+			// This isn't fatal. The worst consequence is that the cache might be
+			// evicted sooner than it otherwise would have been.
+			log.Printf(
+				"error recording use of cache %q in PVC %q: %s",
+				cache.name,
+				cache.pvcName,
+				err,
+			)
+		}
+		return nil
+	}
+	if !kerrors.IsNotFound(err) {
+		return errors.Wrapf(
+			err,
+			"error getting PVC %q for cache %q",
+			cache.pvcName,
+			cache.name,
+		)
+	}
+	if _, err = pvcsClient.Create(
+		buildCachePVC(project, workerConfig, cache, now),
+	); err != nil && !kerrors.IsAlreadyExists(err) {
+		return errors.Wrapf(
+			err,
+			"error creating PVC %q for cache %q",
+			cache.pvcName,
+			cache.name,
+		)
+	}
+	return nil
+}
+
+func buildCachePVC(
+	project brigade.Project,
+	workerConfig brigade.WorkerConfig,
+	cache jobCache,
+	now time.Time,
+) *v1.PersistentVolumeClaim {
+	pvc := &v1.PersistentVolumeClaim{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: cache.pvcName,
+			Labels: map[string]string{
+				"heritage":     "brigade",
+				"component":    cacheComponent,
+				"project":      labelValue(project.ID),
+				cacheNameLabel: cache.name,
+			},
+			Annotations: map[string]string{
+				cacheLastUsedAnnotation: now.UTC().Format(time.RFC3339),
+			},
+		},
+		Spec: v1.PersistentVolumeClaimSpec{
+			AccessModes: []v1.PersistentVolumeAccessMode{v1.ReadWriteMany},
+			Resources: v1.ResourceRequirements{
+				Requests: v1.ResourceList{
+					"storage": cache.size,
+				},
+			},
+		},
+	}
+	if project.Kubernetes.CacheStorageClass != "" {
+		pvc.Spec.StorageClassName = &project.Kubernetes.CacheStorageClass
+	} else if workerConfig.DefaultCacheStorageClass != "" {
+		pvc.Spec.StorageClassName = &workerConfig.DefaultCacheStorageClass
+	}
+	return pvc
+}
+
+// evictCaches deletes the PVCs of any of the project's caches that have gone
+// unused for longer than the project permits. Then, if the storage requested
+// by all the project's remaining caches exceeds what the project permits, the
+// PVCs of the least recently used caches are deleted until it no longer does.
+// PVCs that are in use by other builds' jobs aren't actually removed by
+// Kubernetes until those jobs complete.
+func evictCaches(
+	project brigade.Project,
+	now time.Time,
+	kubeClient kubernetes.Interface,
+) error {
+	if project.CacheMaxAge == 0 && project.CacheMaxSize == nil {
+		return nil
+	}
+	pvcsClient :=
+		kubeClient.CoreV1().PersistentVolumeClaims(project.Kubernetes.Namespace)
+	pvcList, err := pvcsClient.List(
+		metav1.ListOptions{
+			LabelSelector: fmt.Sprintf(
+				"component=%s,project=%s",
+				cacheComponent,
+				labelValue(project.ID),
+			),
+		},
+	)
+	if err != nil {
+		return errors.Wrap(err, "error listing cache PVCs")
+	}
+	pvcs := pvcList.Items
+	lastUsed := make(map[string]time.Time, len(pvcs))
+	for _, pvc := range pvcs {
+		lastUsed[pvc.Name] = pvc.CreationTimestamp.Time
+		if lastUsedTime, err := time.Parse(
+			time.RFC3339,
+			pvc.Annotations[cacheLastUsedAnnotation],
+		); err == nil {
+			lastUsed[pvc.Name] = lastUsedTime
+		}
+	}
+	// Most recently used first
+	sort.SliceStable(pvcs, func(i, j int) bool {
+		return lastUsed[pvcs[i].Name].After(lastUsed[pvcs[j].Name])
+	})
+	totalSize := resource.Quantity{}
+	for _, pvc := range pvcs {
+		var reason string
+		if project.CacheMaxAge > 0 &&
+			now.Sub(lastUsed[pvc.Name]) > project.CacheMaxAge {
+			reason = "it has gone unused for too long"
+		} else if project.CacheMaxSize != nil {
+			totalSize.Add(pvc.Spec.Resources.Requests[v1.ResourceStorage])
+			if totalSize.Cmp(*project.CacheMaxSize) > 0 {
+				reason = "the project's caches are too large"
+			}
+		}
+		if reason == "" {
+			continue
+		}
+		log.Printf(
+			"evicting cache %q in PVC %q because %s",
+			pvc.Labels[cacheNameLabel],
+			pvc.Name,
+			reason,
+		)
+		if err := pvcsClient.Delete(
+			pvc.Name,
+			&metav1.DeleteOptions{},
+		); err != nil && !kerrors.IsNotFound(err) {
+			return errors.Wrapf(err, "error deleting cache PVC %q", pvc.Name)
+		}
+	}
+	return nil
+}
+
+// ResetCache deletes the PVCs of the named cache of the given project,
+// regardless of their keys, so that the next build to use the cache starts
+// with an empty one. It returns the names of the deleted PVCs. As with
+// eviction, PVCs that are in use by builds' jobs aren't actually removed by
+// Kubernetes until those jobs complete.
+func ResetCache(
+	project brigade.Project,
+	cacheName string,
+	kubeClient kubernetes.Interface,
+) ([]string, error) {
+	pvcsClient :=
+		kubeClient.CoreV1().PersistentVolumeClaims(project.Kubernetes.Namespace)
+	pvcList, err := pvcsClient.List(
+		metav1.ListOptions{
+			LabelSelector: fmt.Sprintf(
+				"component=%s,project=%s,%s=%s",
+				cacheComponent,
+				labelValue(project.ID),
+				cacheNameLabel,
+				cacheName,
+			),
+		},
+	)
+	if err != nil {
+		return nil, errors.Wrapf(err, "error listing PVCs for cache %q", cacheName)
+	}
+	deleted := []string{}
+	for _, pvc := range pvcList.Items {
+		if err := pvcsClient.Delete(
+			pvc.Name,
+			&metav1.DeleteOptions{},
+		); err != nil && !kerrors.IsNotFound(err) {
+			return deleted, errors.Wrapf(
+				err,
+				"error deleting PVC %q for cache %q",
+				pvc.Name,
+				cacheName,
+			)
+		}
+		deleted = append(deleted, pvc.Name)
+	}
+	return deleted, nil
+}
@@ -0,0 +1,298 @@
+package executor
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/lovethedrake/brigdrake/pkg/brigade"
+	"github.com/lovethedrake/drakecore/config"
+	"github.com/stretchr/testify/require"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func TestResolveJobCaches(t *testing.T) {
+	cfg, err := config.NewConfigFromYAML([]byte(testDrakefile))
+	require.NoError(t, err)
+	pipelines, err := cfg.Pipelines("ci")
+	require.NoError(t, err)
+	pipeline := pipelines[0]
+
+	sourceDir, err := ioutil.TempDir("", "brigdrake-cache-test")
+	require.NoError(t, err)
+	defer os.RemoveAll(sourceDir)
+	goSumPath := filepath.Join(sourceDir, "go.sum")
+
+	resolve := func(
+		projectID string,
+		cacheCfg brigade.CacheConfig,
+		ref string,
+	) (jobCache, error) {
+		caches, err := resolveJobCaches(
+			brigade.Project{
+				ID: projectID,
+				Jobs: map[string]brigade.JobConfig{
+					"test": {
+						Caches: []brigade.CacheConfig{cacheCfg},
+					},
+				},
+			},
+			brigade.Event{
+				Revision: brigade.Revision{
+					Ref: ref,
+				},
+			},
+			pipeline.Name(),
+			pipeline.Jobs(),
+			nil,
+			sourceDir,
+		)
+		if err != nil {
+			return jobCache{}, err
+		}
+		require.Len(t, caches, 1)
+		require.Len(t, caches["test"], 1)
+		return caches["test"][0], nil
+	}
+
+	cacheCfg := brigade.CacheConfig{
+		Name: "go-modules",
+		Path: "/go/pkg/mod",
+		Key:  "${DRAKE_REF}",
+	}
+
+	cache, err := resolve("brigade-123", cacheCfg, "refs/heads/master")
+	require.NoError(t, err)
+	require.Equal(t, "go-modules", cache.name)
+	require.Equal(t, "/go/pkg/mod", cache.path)
+	require.Regexp(t, "^cache-go-modules-[0-9a-f]{16}$", cache.pvcName)
+
+	// The same key for the same project yields the same cache
+	sameCache, err := resolve("brigade-123", cacheCfg, "refs/heads/master")
+	require.NoError(t, err)
+	require.Equal(t, cache.pvcName, sameCache.pvcName)
+
+	// A different ref yields a different cache
+	otherRefCache, err := resolve("brigade-123", cacheCfg, "refs/heads/dev")
+	require.NoError(t, err)
+	require.NotEqual(t, cache.pvcName, otherRefCache.pvcName)
+
+	// A different project yields a different cache
+	otherProjectCache, err :=
+		resolve("brigade-456", cacheCfg, "refs/heads/master")
+	require.NoError(t, err)
+	require.NotEqual(t, cache.pvcName, otherProjectCache.pvcName)
+
+	// Changes to key files yield a different cache
+	cacheCfg.KeyFiles = []string{"go.sum"}
+	_, err = resolve("brigade-123", cacheCfg, "refs/heads/master")
+	require.Error(t, err)
+	require.Contains(t, err.Error(), `error hashing key file "go.sum"`)
+	err = ioutil.WriteFile(goSumPath, []byte("foo"), 0644)
+	require.NoError(t, err)
+	keyFileCache, err := resolve("brigade-123", cacheCfg, "refs/heads/master")
+	require.NoError(t, err)
+	err = ioutil.WriteFile(goSumPath, []byte("bar"), 0644)
+	require.NoError(t, err)
+	changedKeyFileCache, err :=
+		resolve("brigade-123", cacheCfg, "refs/heads/master")
+	require.NoError(t, err)
+	require.NotEqual(t, keyFileCache.pvcName, changedKeyFileCache.pvcName)
+}
+
+func TestEnsureCachePVC(t *testing.T) {
+	project := brigade.Project{
+		ID: "brigade-123",
+		Kubernetes: brigade.KubernetesConfig{
+			Namespace: testNamespace,
+		},
+	}
+	cache := jobCache{
+		name:    "go-modules",
+		path:    "/go/pkg/mod",
+		size:    resource.MustParse("1Gi"),
+		pvcName: "cache-go-modules-0123456789abcdef",
+	}
+	kubeClient := fake.NewSimpleClientset()
+	pvcsClient := kubeClient.CoreV1().PersistentVolumeClaims(testNamespace)
+
+	// The PVC is created if it doesn't exist
+	firstUse := time.Date(2019, time.June, 1, 0, 0, 0, 0, time.UTC)
+	err := ensureCachePVC(
+		project,
+		brigade.WorkerConfig{DefaultCacheStorageClass: "nfs"},
+		cache,
+		firstUse,
+		kubeClient,
+	)
+	require.NoError(t, err)
+	pvc, err := pvcsClient.Get(cache.pvcName, metav1.GetOptions{})
+	require.NoError(t, err)
+	require.Equal(t, "go-modules", pvc.Labels[cacheNameLabel])
+	require.Equal(t, "brigade-123", pvc.Labels["project"])
+	require.Equal(t, "nfs", *pvc.Spec.StorageClassName)
+	require.Equal(
+		t,
+		"2019-06-01T00:00:00Z",
+		pvc.Annotations[cacheLastUsedAnnotation],
+	)
+
+	// The PVC is marked as used if it already exists
+	secondUse := firstUse.Add(time.Hour)
+	err = ensureCachePVC(
+		project,
+		brigade.WorkerConfig{},
+		cache,
+		secondUse,
+		kubeClient,
+	)
+	require.NoError(t, err)
+	pvc, err = pvcsClient.Get(cache.pvcName, metav1.GetOptions{})
+	require.NoError(t, err)
+	require.Equal(
+		t,
+		"2019-06-01T01:00:00Z",
+		pvc.Annotations[cacheLastUsedAnnotation],
+	)
+}
+
+func TestEvictCaches(t *testing.T) {
+	now := time.Date(2019, time.June, 30, 0, 0, 0, 0, time.UTC)
+	cachePVC := func(
+		name string,
+		lastUsed time.Time,
+		size string,
+	) *v1.PersistentVolumeClaim {
+		return &v1.PersistentVolumeClaim{
+			ObjectMeta: metav1.ObjectMeta{
+				Namespace: testNamespace,
+				Name:      name,
+				Labels: map[string]string{
+					"component":    cacheComponent,
+					"project":      "brigade-123",
+					cacheNameLabel: name,
+				},
+				Annotations: map[string]string{
+					cacheLastUsedAnnotation: lastUsed.Format(time.RFC3339),
+				},
+			},
+			Spec: v1.PersistentVolumeClaimSpec{
+				Resources: v1.ResourceRequirements{
+					Requests: v1.ResourceList{
+						v1.ResourceStorage: resource.MustParse(size),
+					},
+				},
+			},
+		}
+	}
+	maxSize := resource.MustParse("2Gi")
+	testCases := []struct {
+		name      string
+		project   brigade.Project
+		remaining []string
+	}{
+		{
+			name:      "no limits",
+			project:   brigade.Project{},
+			remaining: []string{"newest", "newer", "older", "oldest"},
+		},
+		{
+			name: "age limit",
+			project: brigade.Project{
+				CacheMaxAge: 7 * 24 * time.Hour,
+			},
+			remaining: []string{"newest", "newer"},
+		},
+		{
+			name: "size limit",
+			project: brigade.Project{
+				CacheMaxSize: &maxSize,
+			},
+			remaining: []string{"newest", "newer", "older"},
+		},
+	}
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			kubeClient := fake.NewSimpleClientset(
+				cachePVC("newest", now.Add(-time.Hour), "1Gi"),
+				cachePVC("newer", now.Add(-24*time.Hour), "512Mi"),
+				cachePVC("older", now.Add(-14*24*time.Hour), "512Mi"),
+				cachePVC("oldest", now.Add(-30*24*time.Hour), "1Gi"),
+			)
+			project := testCase.project
+			project.ID = "brigade-123"
+			project.Kubernetes.Namespace = testNamespace
+			err := evictCaches(project, now, kubeClient)
+			require.NoError(t, err)
+			pvcList, err := kubeClient.CoreV1().PersistentVolumeClaims(
+				testNamespace,
+			).List(metav1.ListOptions{})
+			require.NoError(t, err)
+			remaining := []string{}
+			for _, pvc := range pvcList.Items {
+				remaining = append(remaining, pvc.Name)
+			}
+			require.ElementsMatch(t, testCase.remaining, remaining)
+		})
+	}
+}
+
+func TestResetCache(t *testing.T) {
+	cachePVC := func(
+		name string,
+		projectID string,
+		cacheName string,
+	) *v1.PersistentVolumeClaim {
+		return &v1.PersistentVolumeClaim{
+			ObjectMeta: metav1.ObjectMeta{
+				Namespace: testNamespace,
+				Name:      name,
+				Labels: map[string]string{
+					"component":    cacheComponent,
+					"project":      projectID,
+					cacheNameLabel: cacheName,
+				},
+			},
+		}
+	}
+	kubeClient := fake.NewSimpleClientset(
+		cachePVC("cache-go-modules-master", "brigade-123", "go-modules"),
+		cachePVC("cache-go-modules-feature", "brigade-123", "go-modules"),
+		cachePVC("cache-npm-master", "brigade-123", "npm"),
+		cachePVC("cache-go-modules-other", "brigade-456", "go-modules"),
+	)
+	deleted, err := ResetCache(
+		brigade.Project{
+			ID: "brigade-123",
+			Kubernetes: brigade.KubernetesConfig{
+				Namespace: testNamespace,
+			},
+		},
+		"go-modules",
+		kubeClient,
+	)
+	require.NoError(t, err)
+	require.ElementsMatch(
+		t,
+		[]string{"cache-go-modules-master", "cache-go-modules-feature"},
+		deleted,
+	)
+	pvcList, err := kubeClient.CoreV1().PersistentVolumeClaims(
+		testNamespace,
+	).List(metav1.ListOptions{})
+	require.NoError(t, err)
+	remaining := []string{}
+	for _, pvc := range pvcList.Items {
+		remaining = append(remaining, pvc.Name)
+	}
+	require.ElementsMatch(
+		t,
+		[]string{"cache-npm-master", "cache-go-modules-other"},
+		remaining,
+	)
+}
@@ -1,6 +1,11 @@
 package executor
 
-import "fmt"
+import (
+	"fmt"
+	"strings"
+
+	"github.com/lovethedrake/brigdrake/pkg/drake"
+)
 
 type multiError struct {
 	errs []error
@@ -14,6 +19,20 @@ func (m *multiError) Error() string {
 	return str
 }
 
+// drakefileValidationError represents all of the errors found while validating
+// a Drakefile.
+type drakefileValidationError struct {
+	errs []error
+}
+
+func (d *drakefileValidationError) Error() string {
+	msgs := make([]string, len(d.errs))
+	for i, err := range d.errs {
+		msgs[i] = err.Error()
+	}
+	return "Drakefile is invalid:\n" + strings.Join(msgs, "\n")
+}
+
 type timedOutError struct {
 	job string
 }
@@ -37,3 +56,78 @@ type inProgressJobAbortedError struct {
 func (i *inProgressJobAbortedError) Error() string {
 	return fmt.Sprintf("in-progress job %q aborted", i.job)
 }
+
+// jobPodFailedError represents the failure of a job's pod. It captures as much
+// detail as Kubernetes makes available about the cause of the failure.
+type jobPodFailedError struct {
+	job      string
+	pod      string
+	reason   string
+	exitCode int32
+	message  string
+	// terminated indicates whether the pod has stopped running. If it hasn't,
+	// it may go on consuming resources.
+	terminated bool
+}
+
+func (j *jobPodFailedError) Error() string {
+	str := fmt.Sprintf("pod %q failed", j.pod)
+	if j.reason != "" {
+		str = fmt.Sprintf("%s: %s", str, j.reason)
+	}
+	if j.exitCode != 0 {
+		str = fmt.Sprintf("%s (exit %d)", str, j.exitCode)
+	}
+	if j.message != "" {
+		str = fmt.Sprintf("%s: %s", str, j.message)
+	}
+	return str
+}
+
+// jobFailure converts the error that caused a job to fail into a
+// drake.JobFailure that can be reported via a drake.JobStatusNotifier.
+func jobFailure(err error) drake.JobFailure {
+	if failedErr, ok := err.(*jobPodFailedError); ok {
+		return drake.JobFailure{
+			Reason:   failedErr.reason,
+			ExitCode: failedErr.exitCode,
+			Message:  failedErr.message,
+		}
+	}
+	return drake.JobFailure{
+		Message: err.Error(),
+	}
+}
+
+// jobConclusion converts the error, if any, returned from running a job into
+// a drake.JobConclusion that can be reported via a drake.JobStatusNotifier.
+func jobConclusion(err error) drake.JobConclusion {
+	switch err.(type) {
+	case nil:
+		return drake.JobConclusionSuccess
+	case *timedOutError:
+		return drake.JobConclusionTimedOut
+	case *pendingJobCanceledError:
+		return drake.JobConclusionSkipped
+	case *inProgressJobAbortedError:
+		return drake.JobConclusionCancelled
+	default:
+		return drake.JobConclusionFailure
+	}
+}
+
+// artifactCollectionError represents a failure to collect a job's artifacts
+// after the job itself succeeded. It is deliberately distinct from
+// jobPodFailedError so that the job isn't retried on its account.
+type artifactCollectionError struct {
+	job string
+	err error
+}
+
+func (a *artifactCollectionError) Error() string {
+	return fmt.Sprintf(
+		"error collecting artifacts of job %q: %s",
+		a.job,
+		a.err,
+	)
+}
@@ -1,8 +1,10 @@
 package executor
 
 import (
+	"fmt"
 	"testing"
 
+	"github.com/lovethedrake/brigdrake/pkg/drake"
 	"github.com/stretchr/testify/require"
 
 	"github.com/pkg/errors"
@@ -21,6 +23,16 @@ func TestMultiError(t *testing.T) {
 	require.Contains(t, errStr, "bar")
 }
 
+func TestDrakefileValidationError(t *testing.T) {
+	err := &drakefileValidationError{
+		errs: []error{
+			errors.New("foo"),
+			errors.New("bar"),
+		},
+	}
+	require.Equal(t, "Drakefile is invalid:\nfoo\nbar", err.Error())
+}
+
 func TestTimedOutError(t *testing.T) {
 	const jobName = "foo"
 	err := timedOutError{
@@ -44,3 +56,54 @@ func TestInProgressJobAbortedError(t *testing.T) {
 	}
 	require.Contains(t, err.Error(), jobName)
 }
+
+func TestJobPodFailedError(t *testing.T) {
+	const podName = "foo"
+	err := jobPodFailedError{
+		job:      "bar",
+		pod:      podName,
+		reason:   "OOMKilled",
+		exitCode: 137,
+	}
+	require.Equal(
+		t,
+		fmt.Sprintf("pod %q failed: OOMKilled (exit 137)", podName),
+		err.Error(),
+	)
+}
+
+func TestJobFailure(t *testing.T) {
+	failure := jobFailure(
+		&jobPodFailedError{
+			job:      "foo",
+			pod:      "bar",
+			reason:   "OOMKilled",
+			exitCode: 137,
+		},
+	)
+	require.Equal(t, "OOMKilled", failure.Reason)
+	require.Equal(t, int32(137), failure.ExitCode)
+	failure = jobFailure(errors.New("foo"))
+	require.Empty(t, failure.Reason)
+	require.Equal(t, "foo", failure.Message)
+}
+
+func TestJobConclusion(t *testing.T) {
+	testCases := []struct {
+		err        error
+		conclusion drake.JobConclusion
+	}{
+		{nil, drake.JobConclusionSuccess},
+		{&timedOutError{job: "foo"}, drake.JobConclusionTimedOut},
+		{&pendingJobCanceledError{job: "foo"}, drake.JobConclusionSkipped},
+		{&inProgressJobAbortedError{job: "foo"}, drake.JobConclusionCancelled},
+		{&jobPodFailedError{job: "foo"}, drake.JobConclusionFailure},
+		{
+			&artifactCollectionError{job: "foo", err: errors.New("bar")},
+			drake.JobConclusionFailure,
+		},
+	}
+	for _, testCase := range testCases {
+		require.Equal(t, testCase.conclusion, jobConclusion(testCase.err))
+	}
+}
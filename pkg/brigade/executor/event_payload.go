@@ -0,0 +1,115 @@
+package executor
+
+import (
+	"log"
+	"strings"
+
+	"github.com/lovethedrake/brigdrake/pkg/brigade"
+	"github.com/pkg/errors"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+)
+
+const (
+	// eventPayloadSecretKey is the key under which the event payload is stored
+	// in the event payload secret.
+	eventPayloadSecretKey = "payload"
+	// maxEventPayloadSize is the size of the largest event payload that can be
+	// mounted into job containers. It's the maximum size of a secret's data.
+	maxEventPayloadSize = 1024 * 1024
+)
+
+// eventPayloadMounted returns true if the event's payload is to be mounted as a
+// file into job containers. That's the case if the event has a payload and the
+// payload fits in a secret.
+func eventPayloadMounted(event brigade.Event) bool {
+	return len(event.Payload) > 0 && len(event.Payload) <= maxEventPayloadSize
+}
+
+// createEventPayloadSecret creates the secret from which the event payload is
+// mounted into job containers. The payload is kept apart from the build secret
+// so that, however large it is, it cannot prevent the build secret from being
+// created. A payload that is too large to be stored in a secret at all is
+// omitted with a warning rather than failing the build.
+func createEventPayloadSecret(
+	project brigade.Project,
+	event brigade.Event,
+	kubeClient kubernetes.Interface,
+) error {
+	if !eventPayloadMounted(event) {
+		if len(event.Payload) > 0 {
+			log.Printf(
+				"WARNING: the %d byte payload of build %q exceeds the limit of %d "+
+					"bytes and will not be mounted into job containers",
+				len(event.Payload),
+				event.BuildID,
+				maxEventPayloadSize,
+			)
+		}
+		return nil
+	}
+	if _, err := kubeClient.CoreV1().Secrets(
+		project.Kubernetes.Namespace,
+	).Create(buildEventPayloadSecret(project, event)); err != nil {
+		return errors.Wrapf(
+			err,
+			"error creating event payload secret for build %q",
+			event.BuildID,
+		)
+	}
+	return nil
+}
+
+// buildEventPayloadSecret returns a secret containing the event payload. The
+// payload is stored in a secret, rather than in a config map, because payloads
+// may contain sensitive information.
+func buildEventPayloadSecret(
+	project brigade.Project,
+	event brigade.Event,
+) *v1.Secret {
+	return &v1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: eventPayloadSecretName(event),
+			Labels: map[string]string{
+				"heritage":  "brigade",
+				"component": "eventPayloadSecret",
+				"project":   labelValue(project.ID),
+				"worker":    labelValue(strings.ToLower(event.WorkerID)),
+				"build":     labelValue(strings.ToLower(event.BuildID)),
+			},
+		},
+		Data: map[string][]byte{
+			eventPayloadSecretKey: event.Payload,
+		},
+	}
+}
+
+func destroyEventPayloadSecret(
+	project brigade.Project,
+	event brigade.Event,
+	kubeClient kubernetes.Interface,
+) error {
+	if !eventPayloadMounted(event) {
+		return nil
+	}
+	if err := kubeClient.CoreV1().Secrets(
+		project.Kubernetes.Namespace,
+	).Delete(
+		eventPayloadSecretName(event),
+		&metav1.DeleteOptions{},
+	); err != nil {
+		return errors.Wrapf(
+			err,
+			"error deleting event payload secret for build %q",
+			event.BuildID,
+		)
+	}
+	return nil
+}
+
+// eventPayloadSecretName returns the name of the secret that holds the build's
+// event payload.
+func eventPayloadSecretName(event brigade.Event) string {
+	return resourceName(strings.ToLower(event.BuildID), "event-payload")
+}
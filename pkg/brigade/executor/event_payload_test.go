@@ -0,0 +1,63 @@
+package executor
+
+import (
+	"testing"
+
+	"github.com/lovethedrake/brigdrake/pkg/brigade"
+	"github.com/stretchr/testify/require"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func TestCreateEventPayloadSecret(t *testing.T) {
+	testCases := []struct {
+		name            string
+		payload         []byte
+		expectedPayload []byte
+	}{
+		{
+			name: "event without payload",
+		},
+		{
+			name:            "event with payload",
+			payload:         []byte(`{"foo":"bar"}`),
+			expectedPayload: []byte(`{"foo":"bar"}`),
+		},
+		{
+			name:    "event with payload too large to mount",
+			payload: make([]byte, maxEventPayloadSize+1),
+		},
+	}
+	project := brigade.Project{
+		Kubernetes: brigade.KubernetesConfig{
+			Namespace: testNamespace,
+		},
+	}
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			event := brigade.Event{
+				BuildID: "foo",
+				Payload: testCase.payload,
+			}
+			kubeClient := fake.NewSimpleClientset()
+			err := createEventPayloadSecret(project, event, kubeClient)
+			require.NoError(t, err)
+			secrets, err :=
+				kubeClient.CoreV1().Secrets(testNamespace).List(metav1.ListOptions{})
+			require.NoError(t, err)
+			if testCase.expectedPayload == nil {
+				require.Empty(t, secrets.Items)
+			} else {
+				require.Len(t, secrets.Items, 1)
+				require.Equal(t, "foo-event-payload", secrets.Items[0].Name)
+				require.Equal(
+					t,
+					testCase.expectedPayload,
+					secrets.Items[0].Data[eventPayloadSecretKey],
+				)
+			}
+			err = destroyEventPayloadSecret(project, event, kubeClient)
+			require.NoError(t, err)
+		})
+	}
+}
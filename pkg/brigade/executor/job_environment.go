@@ -0,0 +1,46 @@
+package executor
+
+import (
+	"path"
+
+	"github.com/lovethedrake/brigdrake/pkg/brigade"
+)
+
+const (
+	eventPayloadVolumeName  = "event-payload"
+	eventPayloadMountPath   = "/var/run/drake/event"
+	eventPayloadFileName    = "payload"
+	eventPayloadFileEnvName = "DRAKE_EVENT_PAYLOAD_FILE"
+)
+
+// jobEnvironment returns the environment variables that are exposed to every
+// container of a job. These are a stable set of variables describing the
+// build, pipeline, and job, merged with any variables derived from the event
+// by the trigger that matched it. The former take precedence over the latter
+// so that a trigger cannot misrepresent the build. The full set of variables
+// is documented in the README.
+func jobEnvironment(
+	project brigade.Project,
+	event brigade.Event,
+	pipelineName string,
+	jobName string,
+	triggerEnvironment map[string]string,
+) map[string]string {
+	environment := make(map[string]string, len(triggerEnvironment)+9)
+	for name, value := range triggerEnvironment {
+		environment[name] = value
+	}
+	environment["DRAKE_PIPELINE"] = pipelineName
+	environment["DRAKE_JOB"] = jobName
+	environment["DRAKE_BUILD_ID"] = event.BuildID
+	environment["DRAKE_PROJECT_ID"] = project.ID
+	environment["DRAKE_COMMIT"] = event.Revision.Commit
+	environment["DRAKE_REF"] = event.Revision.Ref
+	environment["DRAKE_EVENT_PROVIDER"] = event.Provider
+	environment["DRAKE_EVENT_TYPE"] = event.Type
+	if eventPayloadMounted(event) {
+		environment[eventPayloadFileEnvName] =
+			path.Join(eventPayloadMountPath, eventPayloadFileName)
+	}
+	return environment
+}
@@ -0,0 +1,86 @@
+package executor
+
+import (
+	"testing"
+
+	"github.com/lovethedrake/brigdrake/pkg/brigade"
+	"github.com/stretchr/testify/require"
+)
+
+func TestJobEnvironment(t *testing.T) {
+	testCases := []struct {
+		name               string
+		event              brigade.Event
+		triggerEnvironment map[string]string
+		assertions         func(*testing.T, map[string]string)
+	}{
+		{
+			name: "standard variables",
+			event: brigade.Event{
+				BuildID:  "abc",
+				Provider: "github",
+				Type:     "push",
+				Revision: brigade.Revision{
+					Commit: "1234567",
+					Ref:    "refs/heads/master",
+				},
+			},
+			assertions: func(t *testing.T, env map[string]string) {
+				require.Equal(
+					t,
+					map[string]string{
+						"DRAKE_PIPELINE":       "ci",
+						"DRAKE_JOB":            "test",
+						"DRAKE_BUILD_ID":       "abc",
+						"DRAKE_PROJECT_ID":     "brigade-123",
+						"DRAKE_COMMIT":         "1234567",
+						"DRAKE_REF":            "refs/heads/master",
+						"DRAKE_EVENT_PROVIDER": "github",
+						"DRAKE_EVENT_TYPE":     "push",
+					},
+					env,
+				)
+			},
+		},
+		{
+			name: "event with payload",
+			event: brigade.Event{
+				Payload: []byte("{}"),
+			},
+			assertions: func(t *testing.T, env map[string]string) {
+				require.Equal(
+					t,
+					"/var/run/drake/event/payload",
+					env["DRAKE_EVENT_PAYLOAD_FILE"],
+				)
+			},
+		},
+		{
+			name: "trigger environment",
+			event: brigade.Event{
+				Type: "push",
+			},
+			triggerEnvironment: map[string]string{
+				"DRAKE_TAG":        "v1.0.0",
+				"DRAKE_EVENT_TYPE": "tag",
+			},
+			assertions: func(t *testing.T, env map[string]string) {
+				require.Equal(t, "v1.0.0", env["DRAKE_TAG"])
+				// Standard variables cannot be overridden by triggers
+				require.Equal(t, "push", env["DRAKE_EVENT_TYPE"])
+			},
+		},
+	}
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			env := jobEnvironment(
+				brigade.Project{ID: "brigade-123"},
+				testCase.event,
+				"ci",
+				"test",
+				testCase.triggerEnvironment,
+			)
+			testCase.assertions(t, env)
+		})
+	}
+}
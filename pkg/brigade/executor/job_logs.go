@@ -0,0 +1,174 @@
+package executor
+
+import (
+	"context"
+	"io"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/lovethedrake/drakecore/config"
+	"github.com/pkg/errors"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/client-go/kubernetes"
+)
+
+// jobLogsTailLines is the number of lines from the end of a job's logs that
+// are captured for reporting via a drake.JobStatusNotifier.
+const jobLogsTailLines int64 = 500
+
+// getJobPodLogs returns the tail of the logs from the specified container of a
+// job's pod.
+func getJobPodLogs(
+	namespace string,
+	podName string,
+	containerName string,
+	kubeClient kubernetes.Interface,
+) (string, error) {
+	tailLines := jobLogsTailLines
+	logBytes, err := kubeClient.CoreV1().Pods(namespace).GetLogs(
+		podName,
+		&v1.PodLogOptions{
+			Container: containerName,
+			TailLines: &tailLines,
+		},
+	).DoRaw()
+	if err != nil {
+		return "", errors.Wrapf(
+			err,
+			"error retrieving logs for container %q of pod %q",
+			containerName,
+			podName,
+		)
+	}
+	return string(logBytes), nil
+}
+
+const (
+	// logStreamRetryInterval is how long to wait before re-attempting to open a
+	// log stream for a container that has not yet started.
+	logStreamRetryInterval = 2 * time.Second
+	// logStreamDrainTimeout is how long to wait, once a job has completed, for
+	// its primary container's log stream to reach its end before all of the
+	// job's log streams are forcibly closed.
+	logStreamDrainTimeout = 10 * time.Second
+)
+
+// streamJobPodLogs streams logs from every container of a job's pod to all of
+// the given LogSinks. Streaming continues until each container's log stream
+// ends or the context is canceled. The first channel returned is closed when
+// all streaming has stopped. The second is closed when the primary
+// container's log stream has ended. Sidecar containers' log streams don't end
+// for as long as the pod exists, so once the primary container's has, the
+// context should be canceled.
+func streamJobPodLogs(
+	ctx context.Context,
+	namespace string,
+	podName string,
+	pipelineName string,
+	job config.Job,
+	attempt int,
+	logSinks []LogSink,
+	kubeClient kubernetes.Interface,
+) (<-chan struct{}, <-chan struct{}) {
+	primaryDone := make(chan struct{})
+	containerNames := []string{job.PrimaryContainer().Name()}
+	for _, sidecarContainer := range job.SidecarContainers() {
+		containerNames = append(containerNames, sidecarContainer.Name())
+	}
+	wg := &sync.WaitGroup{}
+	for i, c := range containerNames {
+		// Avoid closing over variables we're using for iteration
+		containerName := c
+		primary := i == 0
+		writers := []io.WriteCloser{}
+		for _, logSink := range logSinks {
+			writer, err := logSink.NewWriter(
+				pipelineName,
+				job.Name(),
+				containerName,
+				attempt,
+			)
+			if err != nil {
+				log.Printf(
+					"error opening log sink for container %q of pod %q: %s",
+					containerName,
+					podName,
+					err,
+				)
+				continue
+			}
+			writers = append(writers, writer)
+		}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			ioWriters := make([]io.Writer, len(writers))
+			for i, writer := range writers {
+				ioWriters[i] = writer
+			}
+			streamContainerLogs(
+				ctx,
+				namespace,
+				podName,
+				containerName,
+				io.MultiWriter(ioWriters...),
+				kubeClient,
+			)
+			for _, writer := range writers {
+				if err := writer.Close(); err != nil {
+					log.Printf("error closing log sink: %s", err)
+				}
+			}
+			if primary {
+				close(primaryDone)
+			}
+		}()
+	}
+	done := make(chan struct{})
+	go func() {
+		wg.Wait()
+		close(done)
+	}()
+	return done, primaryDone
+}
+
+// streamContainerLogs follows the logs of the specified container, copying
+// them to the given io.Writer until the container's log stream ends or the
+// context is canceled. If the container has not yet started, opening the log
+// stream is periodically re-attempted.
+func streamContainerLogs(
+	ctx context.Context,
+	namespace string,
+	podName string,
+	containerName string,
+	writer io.Writer,
+	kubeClient kubernetes.Interface,
+) {
+	for {
+		stream, err := kubeClient.CoreV1().Pods(namespace).GetLogs(
+			podName,
+			&v1.PodLogOptions{
+				Container: containerName,
+				Follow:    true,
+			},
+		).Context(ctx).Stream()
+		if err == nil {
+			defer stream.Close()
+			if _, err = io.Copy(writer, stream); err != nil && ctx.Err() == nil {
+				log.Printf(
+					"error streaming logs for container %q of pod %q: %s",
+					containerName,
+					podName,
+					err,
+				)
+			}
+			return
+		}
+		select {
+		case <-time.After(logStreamRetryInterval):
+		case <-ctx.Done():
+			return
+		}
+	}
+}
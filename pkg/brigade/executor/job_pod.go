@@ -4,6 +4,8 @@ import (
 	"context"
 	"fmt"
 	"log"
+	"sort"
+	"strconv"
 	"strings"
 	"time"
 
@@ -25,62 +27,281 @@ const (
 	dockerSocketVolumeName  = "docker-socket"
 )
 
+// runJobPod runs the given job in a pod, re-attempting it in a new pod as
+// often as the job's retry policy permits. The job is reported as in progress
+// at the start of each attempt, but its outcome is only reported once there
+// will be no further attempts.
 func runJobPod(
 	ctx context.Context,
 	project brigade.Project,
 	event brigade.Event,
+	workerConfig brigade.WorkerConfig,
 	pipelineName string,
 	job config.Job,
+	environment map[string]string,
+	caches []jobCache,
 	jobStatusNotifier drake.JobStatusNotifier,
+	logSinks []LogSink,
+	artifactStore ArtifactStore,
 	kubeClient kubernetes.Interface,
-) error {
-	var err error
+) (err error) {
+	// This captures output from the job's final attempt for inclusion in the
+	// job's final status notification.
+	var output drake.JobOutput
 	if jobStatusNotifier != nil {
-		if err = jobStatusNotifier.SendInProgressNotification(job); err != nil {
-			return err
-		}
 		defer func() {
-			jsnFn := jobStatusNotifier.SendFailureNotification
-			select {
-			case <-ctx.Done():
-				jsnFn = jobStatusNotifier.SendCancelledNotification
-			default:
-				if err == nil {
-					jsnFn = jobStatusNotifier.SendSuccessNotification
-				} else if _, ok := err.(*timedOutError); ok {
-					jsnFn = jobStatusNotifier.SendTimedOutNotification
-				}
+			sendJobOutcomeNotification(ctx, job, err, output, jobStatusNotifier)
+		}()
+	}
+	retryPolicy := project.Jobs[job.Name()].Retry
+	for attempt := 1; ; attempt++ {
+		if jobStatusNotifier != nil {
+			if err = jobStatusNotifier.SendInProgressNotification(
+				job,
+				attempt,
+			); err != nil {
+				return err
 			}
-			if err = jsnFn(job); err != nil {
-				log.Printf("error sending job status notification: %s", err)
+		}
+		output, err = runJobPodAttempt(
+			ctx,
+			project,
+			event,
+			workerConfig,
+			pipelineName,
+			job,
+			environment,
+			caches,
+			attempt,
+			jobStatusNotifier != nil,
+			logSinks,
+			artifactStore,
+			kubeClient,
+		)
+		if err == nil ||
+			attempt >= retryPolicy.MaxAttempts ||
+			!shouldRetry(retryPolicy, err) {
+			return err
+		}
+		backoff := retryBackoff(retryPolicy, attempt)
+		log.Printf(
+			"attempt %d of job %q in pipeline %q failed; retrying in %s: %s",
+			attempt,
+			job.Name(),
+			pipelineName,
+			backoff,
+			err,
+		)
+		select {
+		case <-time.After(backoff):
+		case <-ctx.Done():
+			err = contextDoneError(ctx, qualifiedJobName(pipelineName, job.Name()))
+			return err
+		}
+	}
+}
+
+// sendJobOutcomeNotification reports how the job ended, given the error, if
+// any, returned from its final attempt. Failure to report is logged, but is
+// otherwise not an error.
+func sendJobOutcomeNotification(
+	ctx context.Context,
+	job config.Job,
+	err error,
+	output drake.JobOutput,
+	jobStatusNotifier drake.JobStatusNotifier,
+) {
+	var nerr error
+	if _, ok := err.(*timedOutError); ok {
+		// This is checked first because a pipeline that exceeds its deadline
+		// also has its context canceled.
+		nerr = jobStatusNotifier.SendTimedOutNotification(job, output)
+	} else {
+		select {
+		case <-ctx.Done():
+			nerr = jobStatusNotifier.SendCancelledNotification(job, output)
+		default:
+			if err == nil {
+				nerr = jobStatusNotifier.SendSuccessNotification(job, output)
+			} else {
+				nerr = jobStatusNotifier.SendFailureNotification(
+					job,
+					jobFailure(err),
+					output,
+				)
 			}
-		}()
+		}
+	}
+	if nerr != nil {
+		log.Printf("error sending job status notification: %s", nerr)
 	}
+}
+
+// runJobPodAttempt makes a single attempt at running the given job in a pod.
+// It returns output from the pod that is worth reporting, along with the
+// error, if any, that the attempt ended with. The tail of the primary
+// container's logs is only included in the output if captureLogs is set.
+func runJobPodAttempt(
+	ctx context.Context,
+	project brigade.Project,
+	event brigade.Event,
+	workerConfig brigade.WorkerConfig,
+	pipelineName string,
+	job config.Job,
+	environment map[string]string,
+	caches []jobCache,
+	attempt int,
+	captureLogs bool,
+	logSinks []LogSink,
+	artifactStore ArtifactStore,
+	kubeClient kubernetes.Interface,
+) (drake.JobOutput, error) {
+	var err error
+	// This captures output from the job's pod for inclusion in the job's final
+	// status notification.
+	var output drake.JobOutput
 
-	// TODO: Let's not define the values for these in two places.
-	jobName := fmt.Sprintf("%s-%s", pipelineName, job.Name())
-	podName := fmt.Sprintf("%s-%s", jobName, event.BuildID)
+	jobName := qualifiedJobName(pipelineName, job.Name())
 
 	var pod *v1.Pod
-	pod, err = buildJobPod(project, event, pipelineName, job)
+	if pod, err = buildJobPod(
+		project,
+		event,
+		pipelineName,
+		job,
+		environment,
+		caches,
+		attempt,
+	); err != nil {
+		return output, err
+	}
+	podName := pod.Name
 
 	if _, err = kubeClient.CoreV1().Pods(
 		project.Kubernetes.Namespace,
 	).Create(pod); err != nil {
 		err = errors.Wrapf(err, "error creating pod %q", podName)
-		return err
+		return output, err
+	}
+
+	// podNeverStarted is set if the pod is found to have failed without any of
+	// its containers ever having run, in which case there are no logs to wait
+	// for.
+	var podNeverStarted bool
+	if len(logSinks) > 0 {
+		// Log streams aren't bound to the job's context because we'd like them to
+		// capture everything they can, even if the job is canceled.
+		logStreamsCtx, stopLogStreams := context.WithCancel(context.Background())
+		logStreamsDone, primaryLogStreamDone := streamJobPodLogs(
+			logStreamsCtx,
+			project.Kubernetes.Namespace,
+			podName,
+			pipelineName,
+			job,
+			attempt,
+			logSinks,
+			kubeClient,
+		)
+		defer func() {
+			// Once the primary container's logs have been captured in full, there's
+			// nothing more worth waiting for. The sidecars' log streams would only
+			// end when the pod is deleted.
+			if !podNeverStarted {
+				select {
+				case <-primaryLogStreamDone:
+				case <-time.After(logStreamDrainTimeout):
+				}
+			}
+			stopLogStreams()
+			<-logStreamsDone
+		}()
 	}
 
-	return waitForJobPodCompletion(
+	err = waitForJobPodCompletion(
 		ctx,
 		project.Kubernetes.Namespace,
 		jobName,
 		podName,
-		10*time.Minute, // TODO: This probably shouldn't be hardcoded
+		jobTimeout(project, workerConfig, job.Name()),
 		kubeClient,
 	)
+	if failedErr, ok := err.(*jobPodFailedError); ok && !failedErr.terminated {
+		// The pod failed without terminating-- e.g. because an image couldn't be
+		// pulled. Left alone, it would go on consuming resources, so we get rid of
+		// it. There are no logs worth capturing from such a pod.
+		podNeverStarted = true
+		if derr := kubeClient.CoreV1().Pods(
+			project.Kubernetes.Namespace,
+		).Delete(podName, &metav1.DeleteOptions{}); derr != nil {
+			log.Printf("error deleting failed pod %q: %s", podName, derr)
+		}
+	} else if captureLogs {
+		logs, lerr := getJobPodLogs(
+			project.Kubernetes.Namespace,
+			podName,
+			job.PrimaryContainer().Name(),
+			kubeClient,
+		)
+		if lerr != nil {
+			log.Printf("error retrieving logs for pod %q: %s", podName, lerr)
+		} else {
+			output.Logs = logs
+		}
+	}
+
+	// If the job succeeded, collect its artifacts, if any
+	if artifacts := project.Jobs[job.Name()].Artifacts; err == nil &&
+		len(artifacts) > 0 {
+		if artifactStore == nil {
+			log.Printf(
+				"job %q in pipeline %q declares artifacts, but no artifact store is "+
+					"configured; artifacts will not be collected",
+				job.Name(),
+				pipelineName,
+			)
+			return output, err
+		}
+		var artifactsURL string
+		var cerr error
+		if artifactsURL, cerr = collectJobArtifacts(
+			ctx,
+			project,
+			event,
+			workerConfig,
+			pipelineName,
+			job,
+			podName,
+			artifactStore,
+			kubeClient,
+		); cerr != nil {
+			err = &artifactCollectionError{job: job.Name(), err: cerr}
+			return output, err
+		}
+		output.ArtifactsURL = artifactsURL
+	}
+	return output, err
+}
+
+// jobTimeout determines how long the named job may run. A timeout configured
+// for the job itself takes precedence over the project's default, which, in
+// turn, takes precedence over the worker's default. A zero value means the job
+// may run indefinitely.
+func jobTimeout(
+	project brigade.Project,
+	workerConfig brigade.WorkerConfig,
+	jobName string,
+) time.Duration {
+	if jobConfig, ok := project.Jobs[jobName]; ok && jobConfig.Timeout > 0 {
+		return jobConfig.Timeout
+	}
+	if project.JobTimeout > 0 {
+		return project.JobTimeout
+	}
+	return workerConfig.DefaultJobTimeout
 }
 
+// waitForJobPodCompletion waits for the named pod to complete, fail, or exceed
+// the given timeout. A timeout of zero or less means there is no timeout.
 func waitForJobPodCompletion(
 	ctx context.Context,
 	namespace string,
@@ -101,9 +322,14 @@ func waitForJobPodCompletion(
 		return err
 	}
 
-	// Timeout
-	timer := time.NewTimer(timeout)
-	defer timer.Stop()
+	// Timeout. Receiving from a nil channel blocks forever, so without a timeout
+	// the corresponding case of the select below is never chosen.
+	var timeoutCh <-chan time.Time
+	if timeout > 0 {
+		timer := time.NewTimer(timeout)
+		defer timer.Stop()
+		timeoutCh = timer.C
+	}
 
 	for {
 		select {
@@ -116,48 +342,166 @@ func waitForJobPodCompletion(
 				)
 				return err
 			}
-			for _, containerStatus := range pod.Status.ContainerStatuses {
-				if containerStatus.Name == pod.Spec.Containers[0].Name {
-					if containerStatus.State.Terminated != nil {
-						if containerStatus.State.Terminated.Reason == "Completed" {
-							return nil
-						}
-						err = errors.Errorf("pod %q failed", podName)
-						return err
-					}
-					break
-				}
+			var done bool
+			if done, err = checkJobPodCompletion(jobName, pod); done {
+				return err
 			}
-		case <-timer.C:
+		case <-timeoutCh:
 			err = &timedOutError{job: jobName}
 			return err
 		case <-ctx.Done():
-			err = &inProgressJobAbortedError{job: jobName}
+			err = contextDoneError(ctx, jobName)
 			return err
 		}
 	}
 }
 
+// imagePullErrorReasons enumerates the reasons Kubernetes gives for a container
+// that is waiting because its image could not be pulled.
+var imagePullErrorReasons = map[string]bool{
+	"ErrImagePull":      true,
+	"ImagePullBackOff":  true,
+	"InvalidImageName":  true,
+	"ErrImageNeverPull": true,
+}
+
+// fatalWaitingReasons enumerates the reasons Kubernetes gives for a container
+// that is waiting on something that is unlikely to resolve itself before the
+// job times out. A job's pod is considered failed as soon as any of its
+// containers is found waiting for one of these reasons.
+var fatalWaitingReasons = map[string]bool{
+	"CreateContainerConfigError": true,
+	"CreateContainerError":       true,
+}
+
+// checkJobPodCompletion examines the status of a job's pod and returns true if
+// the job is finished. In that case, the returned error indicates whether the
+// job failed.
+func checkJobPodCompletion(jobName string, pod *v1.Pod) (bool, error) {
+	if pod.Status.Phase == v1.PodFailed {
+		// This happens, for instance, when a pod is evicted
+		if pod.Status.Reason != "" {
+			return true, &jobPodFailedError{
+				job:        jobName,
+				pod:        pod.Name,
+				reason:     pod.Status.Reason,
+				message:    pod.Status.Message,
+				terminated: true,
+			}
+		}
+	}
+	for _, condition := range pod.Status.Conditions {
+		// Note that if the cluster autoscales, this condition may be transient, but
+		// we'd rather fail fast than wait out the job's timeout on a pod that
+		// may never be scheduled.
+		if condition.Type == v1.PodScheduled &&
+			condition.Status == v1.ConditionFalse &&
+			condition.Reason == v1.PodReasonUnschedulable {
+			return true, &jobPodFailedError{
+				job:     jobName,
+				pod:     pod.Name,
+				reason:  condition.Reason,
+				message: condition.Message,
+			}
+		}
+	}
+	for _, containerStatus := range pod.Status.InitContainerStatuses {
+		if waiting := containerStatus.State.Waiting; waiting != nil &&
+			(imagePullErrorReasons[waiting.Reason] ||
+				fatalWaitingReasons[waiting.Reason]) {
+			return true, &jobPodFailedError{
+				job:     jobName,
+				pod:     pod.Name,
+				reason:  waiting.Reason,
+				message: waiting.Message,
+			}
+		}
+		if terminated := containerStatus.State.Terminated; terminated != nil &&
+			terminated.ExitCode != 0 {
+			return true, &jobPodFailedError{
+				job:        jobName,
+				pod:        pod.Name,
+				reason:     fmt.Sprintf("Init:%s", terminated.Reason),
+				exitCode:   terminated.ExitCode,
+				message:    terminated.Message,
+				terminated: true,
+			}
+		}
+	}
+	for _, containerStatus := range pod.Status.ContainerStatuses {
+		if waiting := containerStatus.State.Waiting; waiting != nil &&
+			(imagePullErrorReasons[waiting.Reason] ||
+				fatalWaitingReasons[waiting.Reason]) {
+			return true, &jobPodFailedError{
+				job:     jobName,
+				pod:     pod.Name,
+				reason:  waiting.Reason,
+				message: waiting.Message,
+			}
+		}
+		if containerStatus.Name == pod.Spec.Containers[0].Name {
+			if terminated := containerStatus.State.Terminated; terminated != nil {
+				if terminated.Reason == "Completed" {
+					return true, nil
+				}
+				return true, &jobPodFailedError{
+					job:        jobName,
+					pod:        pod.Name,
+					reason:     terminated.Reason,
+					exitCode:   terminated.ExitCode,
+					message:    terminated.Message,
+					terminated: true,
+				}
+			}
+		}
+	}
+	if pod.Status.Phase == v1.PodFailed {
+		// The pod failed, but we couldn't find anything more specific to say about
+		// why.
+		return true, &jobPodFailedError{
+			job:        jobName,
+			pod:        pod.Name,
+			message:    pod.Status.Message,
+			terminated: true,
+		}
+	}
+	return false, nil
+}
+
+// contextDoneError returns an error appropriate to the way in which a job's
+// context concluded. A context that has exceeded its deadline means the
+// pipeline as a whole has timed out. Otherwise, the job was aborted.
+func contextDoneError(ctx context.Context, jobName string) error {
+	if ctx.Err() == context.DeadlineExceeded {
+		return &timedOutError{job: jobName}
+	}
+	return &inProgressJobAbortedError{job: jobName}
+}
+
 func buildJobPod(
 	project brigade.Project,
 	event brigade.Event,
 	pipelineName string,
 	job config.Job,
+	environment map[string]string,
+	caches []jobCache,
+	attempt int,
 ) (*v1.Pod, error) {
-	jobName := fmt.Sprintf("%s-%s", pipelineName, job.Name())
-	podName := fmt.Sprintf("%s-%s", jobName, event.BuildID)
 	pod := &v1.Pod{
 		ObjectMeta: metav1.ObjectMeta{
-			Name: podName,
+			Name: jobPodName(pipelineName, job.Name(), event.BuildID, attempt),
 			Labels: map[string]string{
-				"heritage":             "brigade",
-				"component":            "job",
-				"jobname":              jobName,
-				"project":              project.ID,
-				"worker":               event.WorkerID,
-				"build":                event.BuildID,
-				"thedrake.io/pipeline": pipelineName,
-				"thedrake.io/job":      job.Name(),
+				"heritage":  "brigade",
+				"component": "job",
+				"jobname": labelValue(
+					qualifiedJobName(pipelineName, job.Name()),
+				),
+				"project":              labelValue(project.ID),
+				"worker":               labelValue(event.WorkerID),
+				"build":                labelValue(event.BuildID),
+				"thedrake.io/pipeline": labelValue(pipelineName),
+				"thedrake.io/job":      labelValue(job.Name()),
+				"thedrake.io/attempt":  strconv.Itoa(attempt),
 			},
 		},
 		Spec: v1.PodSpec{
@@ -236,6 +580,25 @@ func buildJobPod(
 			},
 		)
 	}
+	if eventPayloadMounted(event) {
+		pod.Spec.Volumes = append(
+			pod.Spec.Volumes,
+			v1.Volume{
+				Name: eventPayloadVolumeName,
+				VolumeSource: v1.VolumeSource{
+					Secret: &v1.SecretVolumeSource{
+						SecretName: eventPayloadSecretName(event),
+						Items: []v1.KeyToPath{
+							{
+								Key:  eventPayloadSecretKey,
+								Path: eventPayloadFileName,
+							},
+						},
+					},
+				},
+			},
+		)
+	}
 	if jobUsessDockerSocket {
 		pod.Spec.Volumes = append(
 			pod.Spec.Volumes,
@@ -271,6 +634,11 @@ func buildJobPod(
 		}
 	}
 
+	environment =
+		jobEnvironment(project, event, pipelineName, job.Name(), environment)
+
+	jobCfg := project.Jobs[job.Name()]
+
 	// First the primary container
 	pod.Spec.Containers = make([]v1.Container, 1+len(sidecarContainers))
 	jobPodPrimaryContainer, err := buildJobPodContainer(
@@ -278,6 +646,8 @@ func buildJobPod(
 		event,
 		primaryContainer,
 		job.SourceMountMode(),
+		jobCfg.Container(primaryContainer.Name()),
+		environment,
 	)
 	if err != nil {
 		err = errors.Wrapf(
@@ -297,6 +667,8 @@ func buildJobPod(
 			event,
 			sidecarContainer,
 			job.SourceMountMode(),
+			jobCfg.Container(sidecarContainer.Name()),
+			environment,
 		)
 		if err != nil {
 			err = errors.Wrapf(
@@ -310,6 +682,45 @@ func buildJobPod(
 		// +1 because the 0 element has the primary container.
 		pod.Spec.Containers[i+1] = jobPodSidecarContainer
 	}
+
+	// Mount any secret files into the containers that requested them
+	containers :=
+		append([]config.Container{primaryContainer}, sidecarContainers...)
+	for i, container := range containers {
+		volumes, volumeMounts := buildSecretFileVolumes(
+			event,
+			i,
+			jobCfg.Container(container.Name()).SecretFiles,
+		)
+		pod.Spec.Volumes = append(pod.Spec.Volumes, volumes...)
+		pod.Spec.Containers[i].VolumeMounts =
+			append(pod.Spec.Containers[i].VolumeMounts, volumeMounts...)
+	}
+
+	// Finally, mount any caches into all the containers
+	for _, cache := range caches {
+		volumeName := fmt.Sprintf("cache-%s", cache.name)
+		pod.Spec.Volumes = append(
+			pod.Spec.Volumes,
+			v1.Volume{
+				Name: volumeName,
+				VolumeSource: v1.VolumeSource{
+					PersistentVolumeClaim: &v1.PersistentVolumeClaimVolumeSource{
+						ClaimName: cache.pvcName,
+					},
+				},
+			},
+		)
+		for i := range pod.Spec.Containers {
+			pod.Spec.Containers[i].VolumeMounts = append(
+				pod.Spec.Containers[i].VolumeMounts,
+				v1.VolumeMount{
+					Name:      volumeName,
+					MountPath: cache.path,
+				},
+			)
+		}
+	}
 	return pod, nil
 }
 
@@ -318,22 +729,13 @@ func buildJobPodContainer(
 	event brigade.Event,
 	container config.Container,
 	sourceMountMode config.SourceMountMode,
+	containerCfg brigade.ContainerConfig,
+	environment map[string]string,
 ) (v1.Container, error) {
-	privileged := container.Privileged()
-	if privileged && !project.AllowPrivilegedJobs {
-		return v1.Container{}, errors.Errorf(
-			"container %q requested to be privileged, but privileged jobs are "+
-				"not permitted by this project",
-			container.Name(),
-		)
-	}
-	if container.MountDockerSocket() && !project.AllowHostMounts {
-		return v1.Container{}, errors.Errorf(
-			"container %q requested to mount the docker socket, but host "+
-				"mounts are not permitted by this project",
-			container.Name(),
-		)
+	if errs := containerPermissionErrors(project, container); len(errs) > 0 {
+		return v1.Container{}, errs[0]
 	}
+	privileged := container.Privileged()
 	requestedCPUMillicores := container.Resources().CPU().RequestedMillicores()
 	requestedCPUQuantity, err := resource.ParseQuantity(
 		fmt.Sprintf("%dm", requestedCPUMillicores),
@@ -408,22 +810,68 @@ func buildJobPodContainer(
 	if len(args) > 0 {
 		c.Args = args
 	}
-	for k := range project.Secrets {
+	// Only the project secrets requested for the container are exposed to it.
+	for _, secretName := range containerCfg.Secrets {
+		if _, ok := project.Secrets[secretName]; !ok {
+			continue
+		}
 		c.Env = append(
 			c.Env,
 			v1.EnvVar{
-				Name: k,
+				Name: secretName,
 				ValueFrom: &v1.EnvVarSource{
 					SecretKeyRef: &v1.SecretKeySelector{
 						LocalObjectReference: v1.LocalObjectReference{
-							Name: strings.ToLower(event.BuildID),
+							Name: buildSecretName(event),
 						},
-						Key: k,
+						Key: secretName,
 					},
 				},
 			},
 		)
 	}
+	// Keys of existing secrets are exposed to the container. These are sorted by
+	// name so that pod specs are deterministic.
+	externalSecretEnvVarNames :=
+		make([]string, 0, len(containerCfg.ExternalSecrets))
+	for name := range containerCfg.ExternalSecrets {
+		externalSecretEnvVarNames = append(externalSecretEnvVarNames, name)
+	}
+	sort.Strings(externalSecretEnvVarNames)
+	for _, name := range externalSecretEnvVarNames {
+		ref := containerCfg.ExternalSecrets[name]
+		c.Env = append(
+			c.Env,
+			v1.EnvVar{
+				Name: name,
+				ValueFrom: &v1.EnvVarSource{
+					SecretKeyRef: &v1.SecretKeySelector{
+						LocalObjectReference: v1.LocalObjectReference{
+							Name: ref.Name,
+						},
+						Key: ref.Key,
+					},
+				},
+			},
+		)
+	}
+	// Environment variables derived from the event come before those specified
+	// for the container so that the latter can override the former. They're
+	// sorted by name so that pod specs are deterministic.
+	envVarNames := make([]string, 0, len(environment))
+	for name := range environment {
+		envVarNames = append(envVarNames, name)
+	}
+	sort.Strings(envVarNames)
+	for _, name := range envVarNames {
+		c.Env = append(
+			c.Env,
+			v1.EnvVar{
+				Name:  name,
+				Value: environment[name],
+			},
+		)
+	}
 	for _, kv := range container.Environment() {
 		kvTokens := strings.SplitN(kv, "=", 2)
 		if len(kvTokens) == 2 {
@@ -467,6 +915,16 @@ func buildJobPodContainer(
 			},
 		)
 	}
+	if eventPayloadMounted(event) {
+		c.VolumeMounts = append(
+			c.VolumeMounts,
+			v1.VolumeMount{
+				Name:      eventPayloadVolumeName,
+				MountPath: eventPayloadMountPath,
+				ReadOnly:  true,
+			},
+		)
+	}
 	if container.MountDockerSocket() {
 		c.VolumeMounts = append(
 			c.VolumeMounts,
@@ -7,10 +7,14 @@ import (
 	"time"
 
 	"github.com/lovethedrake/brigdrake/pkg/brigade"
+	"github.com/lovethedrake/brigdrake/pkg/drake"
 	"github.com/lovethedrake/drakecore/config"
 	"github.com/stretchr/testify/require"
 	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/watch"
 	"k8s.io/client-go/kubernetes/fake"
+	k8stesting "k8s.io/client-go/testing"
 )
 
 func TestWaitForJobPodCompletionWithPodCompleted(t *testing.T) {
@@ -33,7 +37,7 @@ func TestWaitForJobPodCompletionWithPodCompleted(t *testing.T) {
 				require.Error(t, err)
 				require.Equal(
 					t,
-					fmt.Sprintf("pod %q failed", podName),
+					fmt.Sprintf("pod %q failed: Failed", podName),
 					err.Error(),
 				)
 			},
@@ -120,6 +124,58 @@ func TestWaitForJobPodCompletionWithPodThatTimesOut(t *testing.T) {
 	}
 }
 
+func TestWaitForJobPodCompletionWithoutTimeout(t *testing.T) {
+	const jobName = "foo"
+	const podName = "bar"
+	kubeClient := fake.NewSimpleClientset()
+	watcher := watch.NewFake()
+	kubeClient.PrependWatchReactor(
+		"pods",
+		func(k8stesting.Action) (bool, watch.Interface, error) {
+			return true, watcher, nil
+		},
+	)
+	errCh := make(chan error)
+	go func() {
+		errCh <- waitForJobPodCompletion(
+			context.Background(),
+			testNamespace,
+			jobName,
+			podName,
+			0, // No timeout
+			kubeClient,
+		)
+	}()
+	// The pod completes only after a zero timeout would already have elapsed
+	<-time.After(time.Second)
+	pod := newRunningTestPod(podName)
+	pod.Status.ContainerStatuses = []v1.ContainerStatus{
+		{
+			Name: pod.Spec.Containers[0].Name,
+			State: v1.ContainerState{
+				Terminated: &v1.ContainerStateTerminated{
+					Reason: "Completed",
+				},
+			},
+		},
+	}
+	select {
+	case err := <-errCh:
+		require.Fail(t, "watcher returned before the pod completed", "%v", err)
+	default:
+	}
+	watcher.Modify(pod)
+	select {
+	case err := <-errCh:
+		require.NoError(t, err)
+	case <-time.After(3 * time.Second):
+		require.Fail(
+			t,
+			"timed out waiting for pod completion to be acknowledged",
+		)
+	}
+}
+
 func TestWaitForJobPodCompletionWithContextCanceled(t *testing.T) {
 	const jobName = "foo"
 	const podName = "bar"
@@ -150,10 +206,361 @@ func TestWaitForJobPodCompletionWithContextCanceled(t *testing.T) {
 	}
 }
 
+func TestWaitForJobPodCompletionWithContextDeadlineExceeded(t *testing.T) {
+	const jobName = "foo"
+	const podName = "bar"
+	pod := newRunningTestPod(podName)
+	kubeClient := fake.NewSimpleClientset(pod)
+	errCh := make(chan error)
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	go func() {
+		errCh <- waitForJobPodCompletion(
+			ctx,
+			testNamespace,
+			jobName,
+			podName,
+			time.Minute,
+			kubeClient,
+		)
+	}()
+	select {
+	case err := <-errCh:
+		require.Error(t, err)
+		require.IsType(t, &timedOutError{}, err)
+	case <-time.After(5 * time.Second):
+		require.Fail(
+			t,
+			"timed out waiting for the watcher to exit due to exceeded deadline",
+		)
+	}
+}
+
+func TestCheckJobPodCompletion(t *testing.T) {
+	const jobName = "foo"
+	const podName = "bar"
+	testCases := []struct {
+		name       string
+		podStatus  v1.PodStatus
+		assertions func(*testing.T, bool, error)
+	}{
+		{
+			name: "running",
+			podStatus: v1.PodStatus{
+				Phase: v1.PodRunning,
+			},
+			assertions: func(t *testing.T, done bool, err error) {
+				require.False(t, done)
+				require.NoError(t, err)
+			},
+		},
+		{
+			name: "evicted",
+			podStatus: v1.PodStatus{
+				Phase:  v1.PodFailed,
+				Reason: "Evicted",
+			},
+			assertions: func(t *testing.T, done bool, err error) {
+				require.True(t, done)
+				require.IsType(t, &jobPodFailedError{}, err)
+				require.Equal(t, "Evicted", err.(*jobPodFailedError).reason)
+			},
+		},
+		{
+			name: "image pull error",
+			podStatus: v1.PodStatus{
+				Phase: v1.PodPending,
+				ContainerStatuses: []v1.ContainerStatus{
+					{
+						Name: "test-container",
+						State: v1.ContainerState{
+							Waiting: &v1.ContainerStateWaiting{
+								Reason: "ImagePullBackOff",
+							},
+						},
+					},
+				},
+			},
+			assertions: func(t *testing.T, done bool, err error) {
+				require.True(t, done)
+				require.IsType(t, &jobPodFailedError{}, err)
+				failedErr := err.(*jobPodFailedError)
+				require.Equal(t, "ImagePullBackOff", failedErr.reason)
+				require.False(t, failedErr.terminated)
+			},
+		},
+		{
+			name: "unschedulable",
+			podStatus: v1.PodStatus{
+				Phase: v1.PodPending,
+				Conditions: []v1.PodCondition{
+					{
+						Type:    v1.PodScheduled,
+						Status:  v1.ConditionFalse,
+						Reason:  v1.PodReasonUnschedulable,
+						Message: "0/3 nodes are available",
+					},
+				},
+			},
+			assertions: func(t *testing.T, done bool, err error) {
+				require.True(t, done)
+				require.IsType(t, &jobPodFailedError{}, err)
+				failedErr := err.(*jobPodFailedError)
+				require.Equal(t, v1.PodReasonUnschedulable, failedErr.reason)
+				require.Equal(t, "0/3 nodes are available", failedErr.message)
+			},
+		},
+		{
+			name: "container config error",
+			podStatus: v1.PodStatus{
+				Phase: v1.PodPending,
+				ContainerStatuses: []v1.ContainerStatus{
+					{
+						Name: "test-container",
+						State: v1.ContainerState{
+							Waiting: &v1.ContainerStateWaiting{
+								Reason: "CreateContainerConfigError",
+							},
+						},
+					},
+				},
+			},
+			assertions: func(t *testing.T, done bool, err error) {
+				require.True(t, done)
+				require.IsType(t, &jobPodFailedError{}, err)
+				require.Equal(
+					t,
+					"CreateContainerConfigError",
+					err.(*jobPodFailedError).reason,
+				)
+			},
+		},
+		{
+			name: "init container failure",
+			podStatus: v1.PodStatus{
+				Phase: v1.PodFailed,
+				InitContainerStatuses: []v1.ContainerStatus{
+					{
+						Name: "source-cloner",
+						State: v1.ContainerState{
+							Terminated: &v1.ContainerStateTerminated{
+								Reason:   "Error",
+								ExitCode: 128,
+							},
+						},
+					},
+				},
+			},
+			assertions: func(t *testing.T, done bool, err error) {
+				require.True(t, done)
+				require.IsType(t, &jobPodFailedError{}, err)
+				failedErr := err.(*jobPodFailedError)
+				require.Equal(t, "Init:Error", failedErr.reason)
+				require.Equal(t, int32(128), failedErr.exitCode)
+			},
+		},
+		{
+			name: "non-zero exit",
+			podStatus: v1.PodStatus{
+				Phase: v1.PodRunning,
+				ContainerStatuses: []v1.ContainerStatus{
+					{
+						Name: "test-container",
+						State: v1.ContainerState{
+							Terminated: &v1.ContainerStateTerminated{
+								Reason:   "Error",
+								ExitCode: 2,
+							},
+						},
+					},
+				},
+			},
+			assertions: func(t *testing.T, done bool, err error) {
+				require.True(t, done)
+				require.IsType(t, &jobPodFailedError{}, err)
+				failedErr := err.(*jobPodFailedError)
+				require.Equal(t, int32(2), failedErr.exitCode)
+				require.True(t, failedErr.terminated)
+			},
+		},
+	}
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			pod := newRunningTestPod(podName)
+			pod.Status = testCase.podStatus
+			done, err := checkJobPodCompletion(jobName, pod)
+			testCase.assertions(t, done, err)
+		})
+	}
+}
+
+func TestJobTimeout(t *testing.T) {
+	const jobName = "foo"
+	testCases := []struct {
+		name            string
+		project         brigade.Project
+		expectedTimeout time.Duration
+	}{
+		{
+			name:            "worker default",
+			project:         brigade.Project{},
+			expectedTimeout: 10 * time.Minute,
+		},
+		{
+			name: "project default",
+			project: brigade.Project{
+				JobTimeout: 5 * time.Minute,
+			},
+			expectedTimeout: 5 * time.Minute,
+		},
+		{
+			name: "job override",
+			project: brigade.Project{
+				JobTimeout: 5 * time.Minute,
+				Jobs: map[string]brigade.JobConfig{
+					jobName: {
+						Timeout: time.Hour,
+					},
+				},
+			},
+			expectedTimeout: time.Hour,
+		},
+		{
+			name: "override for a different job",
+			project: brigade.Project{
+				Jobs: map[string]brigade.JobConfig{
+					"bar": {
+						Timeout: time.Hour,
+					},
+				},
+			},
+			expectedTimeout: 10 * time.Minute,
+		},
+	}
+	workerConfig := brigade.NewWorkerConfigWithDefaults()
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			require.Equal(
+				t,
+				testCase.expectedTimeout,
+				jobTimeout(testCase.project, workerConfig, jobName),
+			)
+		})
+	}
+}
+
+// recordingJobStatusNotifier records the notifications it's sent for a job.
+// Its pipeline notification methods are not implemented.
+type recordingJobStatusNotifier struct {
+	drake.JobStatusNotifier
+	notifications []string
+}
+
+func (r *recordingJobStatusNotifier) SendInProgressNotification(
+	_ config.Job,
+	attempt int,
+) error {
+	r.notifications = append(
+		r.notifications,
+		fmt.Sprintf("in_progress %d", attempt),
+	)
+	return nil
+}
+
+func (r *recordingJobStatusNotifier) SendSuccessNotification(
+	config.Job,
+	drake.JobOutput,
+) error {
+	r.notifications = append(r.notifications, "success")
+	return nil
+}
+
+func (r *recordingJobStatusNotifier) SendFailureNotification(
+	config.Job,
+	drake.JobFailure,
+	drake.JobOutput,
+) error {
+	r.notifications = append(r.notifications, "failure")
+	return nil
+}
+
+func TestRunJobPodNotifiesEachAttempt(t *testing.T) {
+	kubeClient := fake.NewSimpleClientset()
+	attempts := 0
+	kubeClient.PrependReactor(
+		"create",
+		"pods",
+		func(k8stesting.Action) (bool, runtime.Object, error) {
+			attempts++
+			return false, nil, nil
+		},
+	)
+	// Every pod fails as soon as it's watched. The failure is one that leaves
+	// no logs to capture, since the fake client can't serve any.
+	kubeClient.PrependWatchReactor(
+		"pods",
+		func(k8stesting.Action) (bool, watch.Interface, error) {
+			watcher := watch.NewFakeWithChanSize(1, false)
+			pod := newRunningTestPod("foo")
+			pod.Status.ContainerStatuses = []v1.ContainerStatus{
+				{
+					Name: pod.Spec.Containers[0].Name,
+					State: v1.ContainerState{
+						Waiting: &v1.ContainerStateWaiting{
+							Reason: "ErrImagePull",
+						},
+					},
+				},
+			}
+			watcher.Modify(pod)
+			return true, watcher, nil
+		},
+	)
+	notifier := &recordingJobStatusNotifier{}
+	err := runJobPod(
+		context.Background(),
+		brigade.Project{
+			Kubernetes: brigade.KubernetesConfig{
+				Namespace: testNamespace,
+			},
+			Jobs: map[string]brigade.JobConfig{
+				"bar": {
+					Retry: brigade.RetryPolicy{MaxAttempts: 3},
+				},
+			},
+		},
+		brigade.Event{},
+		brigade.WorkerConfig{DefaultJobTimeout: time.Minute},
+		"foo",
+		&fakeJob{
+			name: "bar",
+			primaryContainer: &fakeContainer{
+				name: "test-container",
+			},
+		},
+		nil,
+		nil,
+		notifier,
+		nil,
+		nil,
+		kubeClient,
+	)
+	require.Error(t, err)
+	require.IsType(t, &jobPodFailedError{}, err)
+	require.Equal(t, 3, attempts)
+	require.Equal(
+		t,
+		[]string{"in_progress 1", "in_progress 2", "in_progress 3", "failure"},
+		notifier.notifications,
+	)
+}
+
 func TestBuildJobPod(t *testing.T) {
 	testCases := []struct {
 		name       string
 		project    brigade.Project
+		event      brigade.Event
+		caches     []jobCache
 		assertions func(*testing.T, *v1.Pod, error)
 	}{
 		{
@@ -180,15 +587,146 @@ func TestBuildJobPod(t *testing.T) {
 				require.Len(t, pod.Spec.ImagePullSecrets, 2)
 			},
 		},
+		{
+			name: "with standard env vars",
+			project: brigade.Project{
+				Kubernetes: brigade.KubernetesConfig{
+					Namespace: testNamespace,
+				},
+			},
+			event: brigade.Event{
+				Type: "push",
+			},
+			assertions: func(t *testing.T, pod *v1.Pod, err error) {
+				require.NoError(t, err)
+				for _, container := range pod.Spec.Containers {
+					require.Contains(
+						t,
+						container.Env,
+						v1.EnvVar{Name: "DRAKE_PIPELINE", Value: "foo"},
+					)
+					require.Contains(
+						t,
+						container.Env,
+						v1.EnvVar{Name: "DRAKE_JOB", Value: "bar"},
+					)
+					require.Contains(
+						t,
+						container.Env,
+						v1.EnvVar{Name: "DRAKE_EVENT_TYPE", Value: "push"},
+					)
+				}
+			},
+		},
+		{
+			name: "with event payload",
+			project: brigade.Project{
+				Kubernetes: brigade.KubernetesConfig{
+					Namespace: testNamespace,
+				},
+			},
+			event: brigade.Event{
+				BuildID: "ABC",
+				Payload: []byte("{}"),
+			},
+			assertions: func(t *testing.T, pod *v1.Pod, err error) {
+				require.NoError(t, err)
+				var payloadVolume *v1.Volume
+				for i, volume := range pod.Spec.Volumes {
+					if volume.Name == eventPayloadVolumeName {
+						payloadVolume = &pod.Spec.Volumes[i]
+					}
+				}
+				require.NotNil(t, payloadVolume)
+				require.NotNil(t, payloadVolume.Secret)
+				require.Equal(
+					t,
+					"abc-event-payload",
+					payloadVolume.Secret.SecretName,
+				)
+				for _, container := range pod.Spec.Containers {
+					require.Contains(
+						t,
+						container.VolumeMounts,
+						v1.VolumeMount{
+							Name:      eventPayloadVolumeName,
+							MountPath: eventPayloadMountPath,
+							ReadOnly:  true,
+						},
+					)
+				}
+			},
+		},
+		{
+			name: "with event payload too large to mount",
+			project: brigade.Project{
+				Kubernetes: brigade.KubernetesConfig{
+					Namespace: testNamespace,
+				},
+			},
+			event: brigade.Event{
+				BuildID: "ABC",
+				Payload: make([]byte, maxEventPayloadSize+1),
+			},
+			assertions: func(t *testing.T, pod *v1.Pod, err error) {
+				require.NoError(t, err)
+				for _, volume := range pod.Spec.Volumes {
+					require.NotEqual(t, eventPayloadVolumeName, volume.Name)
+				}
+				for _, container := range pod.Spec.Containers {
+					for _, volumeMount := range container.VolumeMounts {
+						require.NotEqual(t, eventPayloadVolumeName, volumeMount.Name)
+					}
+				}
+			},
+		},
+		{
+			name: "with caches",
+			project: brigade.Project{
+				Kubernetes: brigade.KubernetesConfig{
+					Namespace: testNamespace,
+				},
+			},
+			caches: []jobCache{
+				{
+					name:    "go-modules",
+					path:    "/go/pkg/mod",
+					pvcName: "cache-go-modules-0123456789abcdef",
+				},
+			},
+			assertions: func(t *testing.T, pod *v1.Pod, err error) {
+				require.NoError(t, err)
+				require.Contains(
+					t,
+					pod.Spec.Volumes,
+					v1.Volume{
+						Name: "cache-go-modules",
+						VolumeSource: v1.VolumeSource{
+							PersistentVolumeClaim: &v1.PersistentVolumeClaimVolumeSource{
+								ClaimName: "cache-go-modules-0123456789abcdef",
+							},
+						},
+					},
+				)
+				for _, container := range pod.Spec.Containers {
+					require.Contains(
+						t,
+						container.VolumeMounts,
+						v1.VolumeMount{
+							Name:      "cache-go-modules",
+							MountPath: "/go/pkg/mod",
+						},
+					)
+				}
+			},
+		},
 	}
 
-	event := brigade.Event{}
-
 	for _, testCase := range testCases {
 		t.Run(testCase.name, func(t *testing.T) {
 			pod, err := buildJobPod(
 				testCase.project,
-				event,
+				testCase.event,
 				"foo",
 				&fakeJob{
 					name: "bar",
@@ -205,6 +743,9 @@ func TestBuildJobPod(t *testing.T) {
 						},
 					},
 				},
+				nil,
+				testCase.caches,
+				1,
 			)
 			testCase.assertions(t, pod, err)
 		})
@@ -213,10 +754,12 @@ func TestBuildJobPod(t *testing.T) {
 
 func TestBuildJobPodContainer(t *testing.T) {
 	testCases := []struct {
-		name         string
-		project      brigade.Project
-		containerCfg config.Container
-		assertions   func(*testing.T, v1.Container, error)
+		name                string
+		project             brigade.Project
+		containerCfg        config.Container
+		brigadeContainerCfg brigade.ContainerConfig
+		environment         map[string]string
+		assertions          func(*testing.T, v1.Container, error)
 	}{
 		{
 			name:    "base case",
@@ -267,7 +810,58 @@ func TestBuildJobPodContainer(t *testing.T) {
 			},
 			assertions: func(t *testing.T, container v1.Container, err error) {
 				require.NoError(t, err)
-				require.Len(t, container.Env, 2)
+				require.Empty(t, container.Env)
+			},
+		},
+		{
+			name: "with requested project secrets",
+			project: brigade.Project{
+				Secrets: map[string]string{
+					"foo": "bar",
+					"bat": "baz",
+				},
+			},
+			containerCfg: &fakeContainer{
+				name: "foo",
+			},
+			brigadeContainerCfg: brigade.ContainerConfig{
+				Secrets: []string{"foo"},
+			},
+			assertions: func(t *testing.T, container v1.Container, err error) {
+				require.NoError(t, err)
+				require.Len(t, container.Env, 1)
+				require.Equal(t, "foo", container.Env[0].Name)
+				require.Equal(t, "foo", container.Env[0].ValueFrom.SecretKeyRef.Key)
+			},
+		},
+		{
+			name:    "with external secrets",
+			project: brigade.Project{},
+			containerCfg: &fakeContainer{
+				name: "foo",
+			},
+			brigadeContainerCfg: brigade.ContainerConfig{
+				ExternalSecrets: map[string]brigade.SecretKeyRef{
+					"KUBECONFIG_DATA": {
+						Name: "deploy-credentials",
+						Key:  "kubeconfig",
+					},
+				},
+			},
+			assertions: func(t *testing.T, container v1.Container, err error) {
+				require.NoError(t, err)
+				require.Len(t, container.Env, 1)
+				require.Equal(t, "KUBECONFIG_DATA", container.Env[0].Name)
+				require.Equal(
+					t,
+					&v1.SecretKeySelector{
+						LocalObjectReference: v1.LocalObjectReference{
+							Name: "deploy-credentials",
+						},
+						Key: "kubeconfig",
+					},
+					container.Env[0].ValueFrom.SecretKeyRef,
+				)
 			},
 		},
 		{
@@ -285,6 +879,32 @@ func TestBuildJobPodContainer(t *testing.T) {
 				require.Len(t, container.Env, 2)
 			},
 		},
+		{
+			name:    "with event-derived env vars",
+			project: brigade.Project{},
+			containerCfg: &fakeContainer{
+				name: "foo",
+				environment: []string{
+					"DRAKE_VERSION_BUILD=override",
+				},
+			},
+			environment: map[string]string{
+				"DRAKE_VERSION_MAJOR": "1",
+				"DRAKE_VERSION_BUILD": "",
+			},
+			assertions: func(t *testing.T, container v1.Container, err error) {
+				require.NoError(t, err)
+				require.Equal(
+					t,
+					[]v1.EnvVar{
+						{Name: "DRAKE_VERSION_BUILD"},
+						{Name: "DRAKE_VERSION_MAJOR", Value: "1"},
+						{Name: "DRAKE_VERSION_BUILD", Value: "override"},
+					},
+					container.Env,
+				)
+			},
+		},
 		{
 			name:    "with source mount path",
 			project: brigade.Project{},
@@ -322,6 +942,8 @@ func TestBuildJobPodContainer(t *testing.T) {
 				event,
 				testCase.containerCfg,
 				config.SourceMountModeReadOnly,
+				testCase.brigadeContainerCfg,
+				testCase.environment,
 			)
 			testCase.assertions(t, container, err)
 		})
@@ -0,0 +1,59 @@
+package executor
+
+import (
+	"time"
+
+	"github.com/lovethedrake/brigdrake/pkg/brigade"
+)
+
+// shouldRetry determines whether the given error, returned from an attempt to
+// run a job, represents a failure that the retry policy says should be
+// retried. Only failures of the job's pod are ever retried. Timeouts,
+// cancellations, and errors encountered in creating the pod are not.
+func shouldRetry(retryPolicy brigade.RetryPolicy, err error) bool {
+	failedErr, ok := err.(*jobPodFailedError)
+	if !ok {
+		return false
+	}
+	if len(retryPolicy.On) == 0 {
+		return true
+	}
+	for _, condition := range retryPolicy.On {
+		switch condition {
+		case brigade.RetryOnNonZeroExit:
+			if failedErr.exitCode != 0 && failedErr.reason != "OOMKilled" {
+				return true
+			}
+		case brigade.RetryOnEviction:
+			if failedErr.reason == "Evicted" {
+				return true
+			}
+		case brigade.RetryOnOOMKilled:
+			if failedErr.reason == "OOMKilled" {
+				return true
+			}
+		case brigade.RetryOnImagePullError:
+			if imagePullErrorReasons[failedErr.reason] {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// retryBackoff returns how long to wait after the given (failed) attempt
+// before making the next attempt. The wait doubles with each attempt, but
+// never exceeds the retry policy's maximum, if one is specified.
+func retryBackoff(retryPolicy brigade.RetryPolicy, attempt int) time.Duration {
+	backoff := retryPolicy.Backoff
+	for i := 1; i < attempt; i++ {
+		backoff *= 2
+		if retryPolicy.MaxBackoff > 0 && backoff >= retryPolicy.MaxBackoff {
+			break
+		}
+	}
+	if retryPolicy.MaxBackoff > 0 && backoff > retryPolicy.MaxBackoff {
+		return retryPolicy.MaxBackoff
+	}
+	return backoff
+}
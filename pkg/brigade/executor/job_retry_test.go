@@ -0,0 +1,107 @@
+package executor
+
+import (
+	"testing"
+	"time"
+
+	"github.com/lovethedrake/brigdrake/pkg/brigade"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/require"
+)
+
+func TestShouldRetry(t *testing.T) {
+	testCases := []struct {
+		name        string
+		retryPolicy brigade.RetryPolicy
+		err         error
+		shouldRetry bool
+	}{
+		{
+			name:        "error other than pod failure",
+			retryPolicy: brigade.RetryPolicy{},
+			err:         errors.New("foo"),
+			shouldRetry: false,
+		},
+		{
+			name:        "timed out",
+			retryPolicy: brigade.RetryPolicy{},
+			err:         &timedOutError{job: "foo"},
+			shouldRetry: false,
+		},
+		{
+			name:        "pod failure with no conditions specified",
+			retryPolicy: brigade.RetryPolicy{},
+			err:         &jobPodFailedError{reason: "Error", exitCode: 1},
+			shouldRetry: true,
+		},
+		{
+			name: "non-zero exit",
+			retryPolicy: brigade.RetryPolicy{
+				On: []brigade.RetryCondition{brigade.RetryOnNonZeroExit},
+			},
+			err:         &jobPodFailedError{reason: "Error", exitCode: 1},
+			shouldRetry: true,
+		},
+		{
+			name: "OOMKilled is not a non-zero exit",
+			retryPolicy: brigade.RetryPolicy{
+				On: []brigade.RetryCondition{brigade.RetryOnNonZeroExit},
+			},
+			err:         &jobPodFailedError{reason: "OOMKilled", exitCode: 137},
+			shouldRetry: false,
+		},
+		{
+			name: "OOMKilled",
+			retryPolicy: brigade.RetryPolicy{
+				On: []brigade.RetryCondition{brigade.RetryOnOOMKilled},
+			},
+			err:         &jobPodFailedError{reason: "OOMKilled", exitCode: 137},
+			shouldRetry: true,
+		},
+		{
+			name: "eviction",
+			retryPolicy: brigade.RetryPolicy{
+				On: []brigade.RetryCondition{brigade.RetryOnEviction},
+			},
+			err:         &jobPodFailedError{reason: "Evicted"},
+			shouldRetry: true,
+		},
+		{
+			name: "image pull error",
+			retryPolicy: brigade.RetryPolicy{
+				On: []brigade.RetryCondition{brigade.RetryOnImagePullError},
+			},
+			err:         &jobPodFailedError{reason: "ImagePullBackOff"},
+			shouldRetry: true,
+		},
+		{
+			name: "condition not enumerated",
+			retryPolicy: brigade.RetryPolicy{
+				On: []brigade.RetryCondition{brigade.RetryOnEviction},
+			},
+			err:         &jobPodFailedError{reason: "Error", exitCode: 1},
+			shouldRetry: false,
+		},
+	}
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			require.Equal(
+				t,
+				testCase.shouldRetry,
+				shouldRetry(testCase.retryPolicy, testCase.err),
+			)
+		})
+	}
+}
+
+func TestRetryBackoff(t *testing.T) {
+	retryPolicy := brigade.RetryPolicy{
+		Backoff:    10 * time.Second,
+		MaxBackoff: time.Minute,
+	}
+	require.Equal(t, 10*time.Second, retryBackoff(retryPolicy, 1))
+	require.Equal(t, 20*time.Second, retryBackoff(retryPolicy, 2))
+	require.Equal(t, 40*time.Second, retryBackoff(retryPolicy, 3))
+	require.Equal(t, time.Minute, retryBackoff(retryPolicy, 4))
+	require.Equal(t, time.Minute, retryBackoff(retryPolicy, 100))
+}
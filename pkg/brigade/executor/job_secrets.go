@@ -0,0 +1,133 @@
+package executor
+
+import (
+	"fmt"
+	"path"
+	"sort"
+
+	"github.com/lovethedrake/brigdrake/pkg/brigade"
+	"github.com/lovethedrake/brigdrake/pkg/drake"
+	"github.com/lovethedrake/drakecore/config"
+	"github.com/pkg/errors"
+	v1 "k8s.io/api/core/v1"
+)
+
+// secretsWithheld returns true if the project's policy requires that secrets
+// be withheld from jobs executed on behalf of an event with the given origin.
+func secretsWithheld(project brigade.Project, origin drake.EventOrigin) bool {
+	return (origin.Fork && !project.AllowSecretsForForks) ||
+		(origin.ExternalAuthor && !project.AllowSecretsForExternalAuthors)
+}
+
+// requiredSecrets returns the sorted names of all project secrets that are to
+// be exposed to the containers of the given pipeline executions' jobs, either
+// as environment variables or as files. Secrets
+// requested by jobs of pipeline executions from which secrets are withheld are
+// excluded. An error is returned if any job requests a secret that the project
+// does not define.
+func requiredSecrets(
+	project brigade.Project,
+	executions map[config.Pipeline]pipelineExecution,
+) ([]string, error) {
+	secretNameSet := map[string]struct{}{}
+	for _, execution := range executions {
+		for _, pipelineJob := range execution.jobs {
+			job := pipelineJob.Job()
+			jobCfg := project.Jobs[job.Name()]
+			containers := append(
+				[]config.Container{job.PrimaryContainer()},
+				job.SidecarContainers()...,
+			)
+			for _, container := range containers {
+				containerCfg := jobCfg.Container(container.Name())
+				secretNames := append([]string{}, containerCfg.Secrets...)
+				for _, secretFile := range containerCfg.SecretFiles {
+					if secretFile.Secret != "" {
+						secretNames = append(secretNames, secretFile.Secret)
+					}
+				}
+				for _, secretName := range secretNames {
+					if _, ok := project.Secrets[secretName]; !ok {
+						return nil, errors.Errorf(
+							"container %q of job %q requested secret %q, which is not "+
+								"defined by the project",
+							container.Name(),
+							job.Name(),
+							secretName,
+						)
+					}
+					if !execution.withholdSecrets {
+						secretNameSet[secretName] = struct{}{}
+					}
+				}
+			}
+		}
+	}
+	secretNames := make([]string, 0, len(secretNameSet))
+	for secretName := range secretNameSet {
+		secretNames = append(secretNames, secretName)
+	}
+	sort.Strings(secretNames)
+	return secretNames, nil
+}
+
+// projectWithoutSecrets returns a copy of the project from which all secrets,
+// including those that job configuration references, have been removed.
+func projectWithoutSecrets(project brigade.Project) brigade.Project {
+	project.Secrets = nil
+	jobs := make(map[string]brigade.JobConfig, len(project.Jobs))
+	for jobName, jobCfg := range project.Jobs {
+		jobs[jobName] = jobCfg.WithoutSecrets()
+	}
+	project.Jobs = jobs
+	return project
+}
+
+// buildSecretFileVolumes returns the volumes and corresponding volume mounts
+// needed to mount the given secret files into the container at the given
+// index within a job's pod. Each file is mounted from its own volume using a
+// subpath so that files can be mounted at arbitrary paths without obscuring
+// anything else in the same directory. Project secrets are mounted from the
+// build secret.
+func buildSecretFileVolumes(
+	event brigade.Event,
+	containerIndex int,
+	secretFiles []brigade.SecretFile,
+) ([]v1.Volume, []v1.VolumeMount) {
+	volumes := make([]v1.Volume, len(secretFiles))
+	volumeMounts := make([]v1.VolumeMount, len(secretFiles))
+	for i, secretFile := range secretFiles {
+		volumeName := fmt.Sprintf("secret-file-%d-%d", containerIndex, i)
+		fileName := path.Base(secretFile.Path)
+		secretName := buildSecretName(event)
+		key := secretFile.Secret
+		if secretFile.ExternalSecret != nil {
+			secretName = secretFile.ExternalSecret.Name
+			key = secretFile.ExternalSecret.Key
+		}
+		item := v1.KeyToPath{
+			Key:  key,
+			Path: fileName,
+		}
+		if secretFile.Mode != nil {
+			mode := int32(*secretFile.Mode)
+			item.Mode = &mode
+		}
+		volumes[i] = v1.Volume{
+			Name: volumeName,
+			VolumeSource: v1.VolumeSource{
+				Secret: &v1.SecretVolumeSource{
+					SecretName: secretName,
+					Items:      []v1.KeyToPath{item},
+				},
+			},
+		}
+		volumeMounts[i] = v1.VolumeMount{
+			Name:      volumeName,
+			MountPath: secretFile.Path,
+			SubPath:   fileName,
+			ReadOnly:  true,
+		}
+	}
+	return volumes, volumeMounts
+}
@@ -0,0 +1,276 @@
+package executor
+
+import (
+	"os"
+	"testing"
+
+	"github.com/lovethedrake/brigdrake/pkg/brigade"
+	"github.com/lovethedrake/brigdrake/pkg/drake"
+	"github.com/lovethedrake/drakecore/config"
+	"github.com/stretchr/testify/require"
+	v1 "k8s.io/api/core/v1"
+)
+
+func TestSecretsWithheld(t *testing.T) {
+	testCases := []struct {
+		name     string
+		project  brigade.Project
+		origin   drake.EventOrigin
+		withheld bool
+	}{
+		{
+			name:     "trusted origin",
+			project:  brigade.Project{},
+			origin:   drake.EventOrigin{},
+			withheld: false,
+		},
+		{
+			name:     "fork not allowed",
+			project:  brigade.Project{},
+			origin:   drake.EventOrigin{Fork: true},
+			withheld: true,
+		},
+		{
+			name:     "fork allowed",
+			project:  brigade.Project{AllowSecretsForForks: true},
+			origin:   drake.EventOrigin{Fork: true},
+			withheld: false,
+		},
+		{
+			name:     "external author not allowed",
+			project:  brigade.Project{AllowSecretsForForks: true},
+			origin:   drake.EventOrigin{ExternalAuthor: true},
+			withheld: true,
+		},
+		{
+			name:     "external author allowed",
+			project:  brigade.Project{AllowSecretsForExternalAuthors: true},
+			origin:   drake.EventOrigin{ExternalAuthor: true},
+			withheld: false,
+		},
+		{
+			name:    "external author allowed, but fork not allowed",
+			project: brigade.Project{AllowSecretsForExternalAuthors: true},
+			origin: drake.EventOrigin{
+				Fork:           true,
+				ExternalAuthor: true,
+			},
+			withheld: true,
+		},
+	}
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			require.Equal(
+				t,
+				testCase.withheld,
+				secretsWithheld(testCase.project, testCase.origin),
+			)
+		})
+	}
+}
+
+func TestRequiredSecrets(t *testing.T) {
+	cfg, err := config.NewConfigFromYAML([]byte(testDrakefile))
+	require.NoError(t, err)
+	pipelines, err := cfg.Pipelines("ci")
+	require.NoError(t, err)
+	pipeline := pipelines[0]
+	testCases := []struct {
+		name            string
+		jobs            map[string]brigade.JobConfig
+		withholdSecrets bool
+		assertions      func(*testing.T, []string, error)
+	}{
+		{
+			name: "no secrets requested",
+			assertions: func(t *testing.T, secretNames []string, err error) {
+				require.NoError(t, err)
+				require.Empty(t, secretNames)
+			},
+		},
+		{
+			name: "secrets requested",
+			jobs: map[string]brigade.JobConfig{
+				"test": {
+					Secrets: []string{"CODECOV_TOKEN"},
+				},
+				"publish": {
+					Secrets: []string{"DOCKER_PASSWORD", "CODECOV_TOKEN"},
+				},
+			},
+			assertions: func(t *testing.T, secretNames []string, err error) {
+				require.NoError(t, err)
+				require.Equal(
+					t,
+					[]string{"CODECOV_TOKEN", "DOCKER_PASSWORD"},
+					secretNames,
+				)
+			},
+		},
+		{
+			name: "container secrets override job secrets",
+			jobs: map[string]brigade.JobConfig{
+				"publish": {
+					Secrets: []string{"DOCKER_PASSWORD"},
+					Containers: map[string]brigade.ContainerConfig{
+						"go": {
+							Secrets: []string{"CODECOV_TOKEN"},
+						},
+					},
+				},
+			},
+			assertions: func(t *testing.T, secretNames []string, err error) {
+				require.NoError(t, err)
+				require.Equal(t, []string{"CODECOV_TOKEN"}, secretNames)
+			},
+		},
+		{
+			name: "secret files requested",
+			jobs: map[string]brigade.JobConfig{
+				"publish": {
+					SecretFiles: []brigade.SecretFile{
+						{
+							Secret: "DOCKER_PASSWORD",
+							Path:   "/var/run/secrets/docker-password",
+						},
+						{
+							ExternalSecret: &brigade.SecretKeyRef{
+								Name: "deploy-credentials",
+								Key:  "kubeconfig",
+							},
+							Path: "/root/.kube/config",
+						},
+					},
+				},
+			},
+			assertions: func(t *testing.T, secretNames []string, err error) {
+				require.NoError(t, err)
+				require.Equal(t, []string{"DOCKER_PASSWORD"}, secretNames)
+			},
+		},
+		{
+			name: "secrets withheld",
+			jobs: map[string]brigade.JobConfig{
+				"publish": {
+					Secrets: []string{"DOCKER_PASSWORD"},
+				},
+			},
+			withholdSecrets: true,
+			assertions: func(t *testing.T, secretNames []string, err error) {
+				require.NoError(t, err)
+				require.Empty(t, secretNames)
+			},
+		},
+		{
+			name: "undefined secret requested",
+			jobs: map[string]brigade.JobConfig{
+				"publish": {
+					Secrets: []string{"NOPE"},
+				},
+			},
+			assertions: func(t *testing.T, _ []string, err error) {
+				require.Error(t, err)
+				require.Contains(t, err.Error(), "not defined by the project")
+			},
+		},
+	}
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			secretNames, err := requiredSecrets(
+				brigade.Project{
+					Secrets: map[string]string{
+						"BRIGDRAKE_GITHUB_KEY": "foo",
+						"CODECOV_TOKEN":        "bar",
+						"DOCKER_PASSWORD":      "bat",
+					},
+					Jobs: testCase.jobs,
+				},
+				map[config.Pipeline]pipelineExecution{
+					pipeline: {
+						jobs:            pipeline.Jobs(),
+						withholdSecrets: testCase.withholdSecrets,
+					},
+				},
+			)
+			testCase.assertions(t, secretNames, err)
+		})
+	}
+}
+
+func TestProjectWithoutSecrets(t *testing.T) {
+	project := brigade.Project{
+		ID: "foo",
+		Secrets: map[string]string{
+			"DOCKER_PASSWORD": "bar",
+		},
+		Jobs: map[string]brigade.JobConfig{
+			"publish": {
+				Secrets: []string{"DOCKER_PASSWORD"},
+				Containers: map[string]brigade.ContainerConfig{
+					"helper": {
+						ExternalSecrets: map[string]brigade.SecretKeyRef{
+							"TOKEN": {Name: "bat", Key: "baz"},
+						},
+					},
+				},
+			},
+		},
+	}
+	withoutSecrets := projectWithoutSecrets(project)
+	require.Equal(t, "foo", withoutSecrets.ID)
+	require.Empty(t, withoutSecrets.Secrets)
+	require.Equal(
+		t,
+		brigade.ContainerConfig{},
+		withoutSecrets.Jobs["publish"].Container("helper"),
+	)
+	// The original project must not have been modified
+	require.Len(t, project.Secrets, 1)
+	require.Len(t, project.Jobs["publish"].Secrets, 1)
+}
+
+func TestBuildSecretFileVolumes(t *testing.T) {
+	mode := os.FileMode(0400)
+	volumes, volumeMounts := buildSecretFileVolumes(
+		brigade.Event{BuildID: "ABC"},
+		1,
+		[]brigade.SecretFile{
+			{
+				Secret: "GPG_KEY",
+				Path:   "/root/.gnupg/key.asc",
+				Mode:   &mode,
+			},
+			{
+				ExternalSecret: &brigade.SecretKeyRef{
+					Name: "deploy-credentials",
+					Key:  "kubeconfig",
+				},
+				Path: "/root/.kube/config",
+			},
+		},
+	)
+	require.Len(t, volumes, 2)
+	require.Len(t, volumeMounts, 2)
+
+	require.Equal(t, "secret-file-1-0", volumes[0].Name)
+	require.Equal(t, "abc", volumes[0].Secret.SecretName)
+	require.Equal(t, "GPG_KEY", volumes[0].Secret.Items[0].Key)
+	require.Equal(t, "key.asc", volumes[0].Secret.Items[0].Path)
+	require.Equal(t, int32(0400), *volumes[0].Secret.Items[0].Mode)
+	require.Equal(
+		t,
+		v1.VolumeMount{
+			Name:      "secret-file-1-0",
+			MountPath: "/root/.gnupg/key.asc",
+			SubPath:   "key.asc",
+			ReadOnly:  true,
+		},
+		volumeMounts[0],
+	)
+
+	require.Equal(t, "secret-file-1-1", volumes[1].Name)
+	require.Equal(t, "deploy-credentials", volumes[1].Secret.SecretName)
+	require.Equal(t, "kubeconfig", volumes[1].Secret.Items[0].Key)
+	require.Nil(t, volumes[1].Secret.Items[0].Mode)
+	require.Equal(t, "/root/.kube/config", volumeMounts[1].MountPath)
+}
@@ -0,0 +1,250 @@
+package executor
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/lovethedrake/brigdrake/pkg/brigade"
+	"github.com/pkg/errors"
+)
+
+const (
+	logSinkStdout = "stdout"
+	logSinkFile   = "file"
+)
+
+// mountInfoPath is where the worker's mounts are listed. It's a variable only
+// so that tests can substitute their own list.
+var mountInfoPath = "/proc/self/mountinfo"
+
+// mountInfoUnescaper reverses the escaping of whitespace and backslashes in
+// the mount points listed in mountinfo.
+var mountInfoUnescaper = strings.NewReplacer(
+	`\040`, " ",
+	`\011`, "\t",
+	`\012`, "\n",
+	`\134`, `\`,
+)
+
+// LogSink is the interface for components that job logs can be streamed to
+// so that they outlive the job pods they came from.
+type LogSink interface {
+	// NewWriter returns an io.WriteCloser to which logs from the named container
+	// of a job's pod can be written. The caller must close the writer when it is
+	// done writing.
+	NewWriter(
+		pipelineName string,
+		jobName string,
+		containerName string,
+		attempt int,
+	) (io.WriteCloser, error)
+}
+
+// newLogSinks returns the LogSinks enabled by the worker configuration. The
+// "file" job log sink requires the job logs directory to be on a volume that
+// is mounted into the worker's pod, e.g. a persistent volume. BrigDrake does
+// not provision that volume. If nothing is mounted there, logs written to it
+// would disappear along with the worker, so that is an error.
+func newLogSinks(
+	event brigade.Event,
+	workerConfig brigade.WorkerConfig,
+) ([]LogSink, error) {
+	logSinks := make([]LogSink, len(workerConfig.JobLogSinks))
+	for i, logSinkName := range workerConfig.JobLogSinks {
+		switch logSinkName {
+		case logSinkStdout:
+			logSinks[i] = &stdoutLogSink{
+				out: os.Stdout,
+			}
+		case logSinkFile:
+			if workerConfig.JobLogsDir == "" {
+				return nil, errors.Errorf(
+					"the %q job log sink requires a job logs directory to be specified",
+					logSinkFile,
+				)
+			}
+			if err := checkJobLogsDirMounted(workerConfig.JobLogsDir); err != nil {
+				return nil, err
+			}
+			logSinks[i] = &fileLogSink{
+				dir: filepath.Join(
+					workerConfig.JobLogsDir,
+					strings.ToLower(event.BuildID),
+				),
+			}
+		default:
+			return nil, errors.Errorf("unrecognized job log sink %q", logSinkName)
+		}
+	}
+	return logSinks, nil
+}
+
+// stdoutLogSink is a LogSink that writes job logs to the worker's own stdout,
+// prefixing each line with the pipeline, job, and container it came from.
+// This allows the worker's logs to show every job's output, interleaved and
+// labeled.
+type stdoutLogSink struct {
+	out io.Writer
+	// mutex prevents lines written concurrently from different containers from
+	// being garbled together.
+	mutex sync.Mutex
+}
+
+func (s *stdoutLogSink) NewWriter(
+	pipelineName string,
+	jobName string,
+	containerName string,
+	attempt int,
+) (io.WriteCloser, error) {
+	prefix := fmt.Sprintf("[%s/%s/%s]", pipelineName, jobName, containerName)
+	if attempt > 1 {
+		prefix = fmt.Sprintf(
+			"[%s/%s(attempt %d)/%s]",
+			pipelineName,
+			jobName,
+			attempt,
+			containerName,
+		)
+	}
+	return &linePrefixWriter{
+		sink:   s,
+		prefix: []byte(prefix + " "),
+	}, nil
+}
+
+// linePrefixWriter is an io.WriteCloser that writes complete, prefixed lines
+// to a stdoutLogSink. Incomplete lines are buffered until they are completed
+// or the writer is closed.
+type linePrefixWriter struct {
+	sink   *stdoutLogSink
+	prefix []byte
+	buf    []byte
+}
+
+func (l *linePrefixWriter) Write(p []byte) (int, error) {
+	l.buf = append(l.buf, p...)
+	i := bytes.LastIndexByte(l.buf, '\n')
+	if i < 0 {
+		return len(p), nil
+	}
+	lines := l.buf[:i+1]
+	if err := l.writeLines(lines); err != nil {
+		return 0, err
+	}
+	l.buf = append(l.buf[:0], l.buf[i+1:]...)
+	return len(p), nil
+}
+
+func (l *linePrefixWriter) Close() error {
+	if len(l.buf) == 0 {
+		return nil
+	}
+	err := l.writeLines(append(l.buf, '\n'))
+	l.buf = nil
+	return err
+}
+
+func (l *linePrefixWriter) writeLines(lines []byte) error {
+	prefixed := &bytes.Buffer{}
+	for _, line := range bytes.SplitAfter(lines, []byte("\n")) {
+		if len(line) == 0 {
+			continue
+		}
+		prefixed.Write(l.prefix)
+		prefixed.Write(line)
+	}
+	l.sink.mutex.Lock()
+	defer l.sink.mutex.Unlock()
+	_, err := l.sink.out.Write(prefixed.Bytes())
+	return err
+}
+
+// fileLogSink is a LogSink that writes the logs from each container of each
+// job to its own file beneath a directory that is specific to the build. The
+// directory is on a volume mounted into the worker's pod.
+type fileLogSink struct {
+	dir string
+}
+
+func (f *fileLogSink) NewWriter(
+	pipelineName string,
+	jobName string,
+	containerName string,
+	attempt int,
+) (io.WriteCloser, error) {
+	dir := filepath.Join(f.dir, pipelineName, jobName)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, errors.Wrapf(err, "error creating job log directory %q", dir)
+	}
+	fileName := fmt.Sprintf("%s.log", containerName)
+	if attempt > 1 {
+		fileName = fmt.Sprintf("%s-%d.log", containerName, attempt)
+	}
+	path := filepath.Join(dir, fileName)
+	file, err := os.Create(path)
+	if err != nil {
+		return nil, errors.Wrapf(err, "error creating job log file %q", path)
+	}
+	return file, nil
+}
+
+// checkJobLogsDirMounted returns an error if the given job logs directory is
+// not on a volume that is mounted into the worker's pod, i.e. if it is on the
+// container's own root file system.
+func checkJobLogsDirMounted(dir string) error {
+	if !filepath.IsAbs(dir) {
+		return errors.Errorf("job logs directory %q is not an absolute path", dir)
+	}
+	mountInfo, err := os.Open(mountInfoPath)
+	if err != nil {
+		return errors.Wrap(err, "error listing mounts")
+	}
+	defer mountInfo.Close()
+	mountPoint, err := mountPointOf(mountInfo, dir)
+	if err != nil {
+		return err
+	}
+	if mountPoint == "" || mountPoint == "/" {
+		return errors.Errorf(
+			"job logs directory %q is not on a mounted volume; a persistent "+
+				"volume must be mounted into the worker at or above it",
+			dir,
+		)
+	}
+	return nil
+}
+
+// mountPointOf returns the mount point, of those listed in the given
+// mountinfo, of the file system that the given absolute path is on. If none of
+// the mount points contains the path, it returns an empty string.
+func mountPointOf(mountInfo io.Reader, path string) (string, error) {
+	path = filepath.Clean(path)
+	var mountPoint string
+	scanner := bufio.NewScanner(mountInfo)
+	for scanner.Scan() {
+		// The mount point is the fifth field of each line
+		fields := strings.Fields(scanner.Text())
+		if len(fields) < 5 {
+			continue
+		}
+		candidate := mountInfoUnescaper.Replace(fields[4])
+		if candidate != "/" &&
+			path != candidate &&
+			!strings.HasPrefix(path, candidate+"/") {
+			continue
+		}
+		if len(candidate) > len(mountPoint) {
+			mountPoint = candidate
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return "", errors.Wrap(err, "error reading mounts")
+	}
+	return mountPoint, nil
+}
@@ -0,0 +1,170 @@
+package executor
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/lovethedrake/brigdrake/pkg/brigade"
+	"github.com/stretchr/testify/require"
+)
+
+// testMountInfo lists the mounts of a worker that has a persistent volume
+// mounted at /var/log/jobs.
+const testMountInfo = `1 0 0:1 / / rw - overlay overlay rw
+2 1 0:2 / /proc rw - proc proc rw
+3 1 8:1 /pvc /var/log/jobs rw - ext4 /dev/sda1 rw
+4 1 8:1 /etc /etc/host\040name rw - ext4 /dev/sda1 rw
+`
+
+func TestNewLogSinks(t *testing.T) {
+	testCases := []struct {
+		name         string
+		workerConfig brigade.WorkerConfig
+		assertions   func(*testing.T, []LogSink, error)
+	}{
+		{
+			name:         "no log sinks",
+			workerConfig: brigade.WorkerConfig{},
+			assertions: func(t *testing.T, logSinks []LogSink, err error) {
+				require.NoError(t, err)
+				require.Empty(t, logSinks)
+			},
+		},
+		{
+			name: "file log sink without a directory",
+			workerConfig: brigade.WorkerConfig{
+				JobLogSinks: []string{logSinkFile},
+			},
+			assertions: func(t *testing.T, _ []LogSink, err error) {
+				require.Error(t, err)
+				require.Contains(t, err.Error(), "requires a job logs directory")
+			},
+		},
+		{
+			name: "file log sink with a directory that isn't mounted",
+			workerConfig: brigade.WorkerConfig{
+				JobLogSinks: []string{logSinkFile},
+				JobLogsDir:  "/var/log/other",
+			},
+			assertions: func(t *testing.T, _ []LogSink, err error) {
+				require.Error(t, err)
+				require.Contains(t, err.Error(), "is not on a mounted volume")
+			},
+		},
+		{
+			name: "unrecognized log sink",
+			workerConfig: brigade.WorkerConfig{
+				JobLogSinks: []string{"foo"},
+			},
+			assertions: func(t *testing.T, _ []LogSink, err error) {
+				require.Error(t, err)
+				require.Contains(t, err.Error(), "unrecognized job log sink")
+			},
+		},
+		{
+			name: "stdout and file log sinks",
+			workerConfig: brigade.WorkerConfig{
+				JobLogSinks: []string{logSinkStdout, logSinkFile},
+				JobLogsDir:  "/var/log/jobs",
+			},
+			assertions: func(t *testing.T, logSinks []LogSink, err error) {
+				require.NoError(t, err)
+				require.Len(t, logSinks, 2)
+				require.IsType(t, &stdoutLogSink{}, logSinks[0])
+				require.IsType(t, &fileLogSink{}, logSinks[1])
+				require.Equal(
+					t,
+					"/var/log/jobs/foo",
+					logSinks[1].(*fileLogSink).dir,
+				)
+			},
+		},
+	}
+	mountInfoFile, err := ioutil.TempFile("", "mountinfo")
+	require.NoError(t, err)
+	defer os.Remove(mountInfoFile.Name())
+	_, err = mountInfoFile.WriteString(testMountInfo)
+	require.NoError(t, err)
+	require.NoError(t, mountInfoFile.Close())
+	defer func(path string) {
+		mountInfoPath = path
+	}(mountInfoPath)
+	mountInfoPath = mountInfoFile.Name()
+	event := brigade.Event{
+		BuildID: "FOO",
+	}
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			logSinks, err := newLogSinks(event, testCase.workerConfig)
+			testCase.assertions(t, logSinks, err)
+		})
+	}
+}
+
+func TestStdoutLogSink(t *testing.T) {
+	out := &bytes.Buffer{}
+	logSink := &stdoutLogSink{
+		out: out,
+	}
+	writer, err := logSink.NewWriter("foo", "bar", "bat", 1)
+	require.NoError(t, err)
+	_, err = writer.Write([]byte("line 1\nline"))
+	require.NoError(t, err)
+	_, err = writer.Write([]byte(" 2\nline 3"))
+	require.NoError(t, err)
+	require.Equal(
+		t,
+		"[foo/bar/bat] line 1\n[foo/bar/bat] line 2\n",
+		out.String(),
+	)
+	require.NoError(t, writer.Close())
+	require.Equal(
+		t,
+		"[foo/bar/bat] line 1\n[foo/bar/bat] line 2\n[foo/bar/bat] line 3\n",
+		out.String(),
+	)
+}
+
+func TestFileLogSink(t *testing.T) {
+	dir, err := ioutil.TempDir("", "")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	logSink := &fileLogSink{
+		dir: dir,
+	}
+	writer, err := logSink.NewWriter("foo", "bar", "bat", 2)
+	require.NoError(t, err)
+	_, err = writer.Write([]byte("foo\n"))
+	require.NoError(t, err)
+	require.NoError(t, writer.Close())
+	logBytes, err :=
+		ioutil.ReadFile(filepath.Join(dir, "foo", "bar", "bat-2.log"))
+	require.NoError(t, err)
+	require.Equal(t, "foo\n", string(logBytes))
+}
+
+func TestMountPointOf(t *testing.T) {
+	testCases := []struct {
+		path     string
+		expected string
+	}{
+		{path: "/var/log/jobs", expected: "/var/log/jobs"},
+		{path: "/var/log/jobs/", expected: "/var/log/jobs"},
+		{path: "/var/log/jobs/foo", expected: "/var/log/jobs"},
+		{path: "/var/log/jobsfoo", expected: "/"},
+		{path: "/var/log", expected: "/"},
+		{path: "/etc/host name/foo", expected: "/etc/host name"},
+	}
+	for _, testCase := range testCases {
+		t.Run(testCase.path, func(t *testing.T) {
+			mountPoint, err :=
+				mountPointOf(strings.NewReader(testMountInfo), testCase.path)
+			require.NoError(t, err)
+			require.Equal(t, testCase.expected, mountPoint)
+		})
+	}
+}
@@ -0,0 +1,104 @@
+package executor
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+const (
+	// maxNameLength is the maximum length of both a DNS-1123 label, which is
+	// what we constrain the names of all the resources we create to, and a
+	// label value.
+	maxNameLength = 63
+	// nameHashLength is the length of the hash suffix that's appended to names
+	// that had to be altered to make them valid. The hash is computed from the
+	// unaltered name so that distinct names remain distinct.
+	nameHashLength = 8
+)
+
+var (
+	invalidResourceNameChars = regexp.MustCompile(`[^a-z0-9-]+`)
+	invalidLabelValueChars   = regexp.MustCompile(`[^A-Za-z0-9_.-]+`)
+	labelValueRegex          = regexp.MustCompile(
+		`^(([A-Za-z0-9][-A-Za-z0-9_.]*)?[A-Za-z0-9])?$`,
+	)
+)
+
+// resourceName joins the given parts with hyphens and returns the result as a
+// valid DNS-1123 label, suitable for use as the name of any Kubernetes
+// resource. If the name is not already valid, it is lowercased, invalid
+// characters are replaced, it is truncated, and a hash of the original name is
+// appended so that the result is both stable and unique. The hash is appended
+// even if the name only had to be lowercased, since names differing only in
+// case would otherwise collide. Brigade's worker and build IDs are not
+// case-sensitive, so callers lowercase those themselves.
+func resourceName(parts ...string) string {
+	name := strings.Join(parts, "-")
+	sanitized := strings.Trim(
+		invalidResourceNameChars.ReplaceAllString(strings.ToLower(name), "-"),
+		"-",
+	)
+	if sanitized == name && sanitized != "" && len(sanitized) <= maxNameLength {
+		return sanitized
+	}
+	return withNameHash(sanitized, name, "-")
+}
+
+// labelValue returns the given value if it is a valid label value. Otherwise,
+// invalid characters are replaced, the value is truncated, and a hash of the
+// original value is appended so that the result is both stable and unique.
+func labelValue(value string) string {
+	if len(value) <= maxNameLength && labelValueRegex.MatchString(value) {
+		return value
+	}
+	sanitized := strings.Trim(
+		invalidLabelValueChars.ReplaceAllString(value, "-"),
+		"-_.",
+	)
+	return withNameHash(sanitized, value, "-_.")
+}
+
+// withNameHash truncates the sanitized name so that, with a hash of the
+// original name appended, it fits within maxNameLength. Any characters in
+// cutset that are left at the end of the truncated name are trimmed.
+func withNameHash(sanitized, original, cutset string) string {
+	hash := sha256.Sum256([]byte(original))
+	hashStr := hex.EncodeToString(hash[:])[:nameHashLength]
+	if len(sanitized) > maxNameLength-nameHashLength-1 {
+		sanitized = sanitized[:maxNameLength-nameHashLength-1]
+	}
+	sanitized = strings.TrimRight(sanitized, cutset)
+	if sanitized == "" {
+		return hashStr
+	}
+	return fmt.Sprintf("%s-%s", sanitized, hashStr)
+}
+
+// qualifiedJobName returns the name by which a job is identified in logs and
+// errors and in the "jobname" label of its pods.
+func qualifiedJobName(pipelineName, jobName string) string {
+	return fmt.Sprintf("%s-%s", pipelineName, jobName)
+}
+
+// jobPodName returns the name of the pod for the given attempt of the named
+// job.
+func jobPodName(
+	pipelineName string,
+	jobName string,
+	buildID string,
+	attempt int,
+) string {
+	if attempt > 1 {
+		return resourceName(
+			pipelineName,
+			jobName,
+			strings.ToLower(buildID),
+			strconv.Itoa(attempt),
+		)
+	}
+	return resourceName(pipelineName, jobName, strings.ToLower(buildID))
+}
@@ -0,0 +1,191 @@
+package executor
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"k8s.io/apimachinery/pkg/util/validation"
+)
+
+func TestResourceName(t *testing.T) {
+	testCases := []struct {
+		name       string
+		parts      []string
+		assertions func(*testing.T, string)
+	}{
+		{
+			name:  "valid name",
+			parts: []string{"ci", "test", "01dx6ezmhdrsxpx2vfdx9b5c8m"},
+			assertions: func(t *testing.T, name string) {
+				require.Equal(t, "ci-test-01dx6ezmhdrsxpx2vfdx9b5c8m", name)
+			},
+		},
+		{
+			name:  "uppercase name",
+			parts: []string{"CI", "Test", "01DX6EZMHDRSXPX2VFDX9B5C8M"},
+			assertions: func(t *testing.T, name string) {
+				require.True(
+					t,
+					strings.HasPrefix(name, "ci-test-01dx6ezmhdrsxpx2vfdx9b5c8m-"),
+				)
+			},
+		},
+		{
+			name:  "name differing from another only in case",
+			parts: []string{"Build"},
+			assertions: func(t *testing.T, name string) {
+				require.True(t, strings.HasPrefix(name, "build-"))
+				require.NotEqual(t, resourceName("build"), name)
+				require.NotEqual(t, resourceName("BUILD"), name)
+			},
+		},
+		{
+			name:  "invalid characters",
+			parts: []string{"ci", "unit_tests", "abc"},
+			assertions: func(t *testing.T, name string) {
+				require.True(t, strings.HasPrefix(name, "ci-unit-tests-abc-"))
+				require.Len(t, name, len("ci-unit-tests-abc-")+nameHashLength)
+				require.NotEqual(t, resourceName("ci", "unit-tests", "abc"), name)
+			},
+		},
+		{
+			name:  "leading and trailing invalid characters",
+			parts: []string{"_ci", "test_"},
+			assertions: func(t *testing.T, name string) {
+				require.True(t, strings.HasPrefix(name, "ci-test-"))
+			},
+		},
+		{
+			name:  "only invalid characters",
+			parts: []string{"___"},
+			assertions: func(t *testing.T, name string) {
+				require.Len(t, name, nameHashLength)
+			},
+		},
+		{
+			name:  "exactly the maximum length",
+			parts: []string{strings.Repeat("a", 30), strings.Repeat("b", 32)},
+			assertions: func(t *testing.T, name string) {
+				require.Len(t, name, maxNameLength)
+				require.Equal(
+					t,
+					strings.Repeat("a", 30)+"-"+strings.Repeat("b", 32),
+					name,
+				)
+			},
+		},
+		{
+			name:  "too long",
+			parts: []string{strings.Repeat("a", 30), strings.Repeat("b", 33)},
+			assertions: func(t *testing.T, name string) {
+				require.Len(t, name, maxNameLength)
+				require.True(t, strings.HasPrefix(name, strings.Repeat("a", 30)))
+				require.NotEqual(
+					t,
+					resourceName(strings.Repeat("a", 30), strings.Repeat("b", 34)),
+					name,
+				)
+			},
+		},
+		{
+			name:  "truncated at a hyphen",
+			parts: []string{strings.Repeat("a", 53), strings.Repeat("b", 20)},
+			assertions: func(t *testing.T, name string) {
+				// The hyphen that would otherwise have been the last character of the
+				// truncated name is trimmed
+				require.Equal(t, strings.Repeat("a", 53), name[:53])
+				require.Equal(t, "-", name[53:54])
+				require.Len(t, name, 54+nameHashLength)
+			},
+		},
+	}
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			name := resourceName(testCase.parts...)
+			require.Empty(t, validation.IsDNS1123Label(name))
+			// Names must be stable
+			require.Equal(t, name, resourceName(testCase.parts...))
+			testCase.assertions(t, name)
+		})
+	}
+}
+
+func TestLabelValue(t *testing.T) {
+	testCases := []struct {
+		name       string
+		value      string
+		assertions func(*testing.T, string)
+	}{
+		{
+			name:  "empty value",
+			value: "",
+			assertions: func(t *testing.T, value string) {
+				require.Equal(t, "", value)
+			},
+		},
+		{
+			name:  "valid value",
+			value: "Unit_Tests.v2",
+			assertions: func(t *testing.T, value string) {
+				require.Equal(t, "Unit_Tests.v2", value)
+			},
+		},
+		{
+			name:  "invalid characters",
+			value: "unit tests/v2",
+			assertions: func(t *testing.T, value string) {
+				require.True(t, strings.HasPrefix(value, "unit-tests-v2-"))
+			},
+		},
+		{
+			name:  "leading and trailing invalid characters",
+			value: "_unit-tests.",
+			assertions: func(t *testing.T, value string) {
+				require.True(t, strings.HasPrefix(value, "unit-tests-"))
+			},
+		},
+		{
+			name:  "too long",
+			value: strings.Repeat("a", 64),
+			assertions: func(t *testing.T, value string) {
+				require.Len(t, value, maxNameLength)
+				require.NotEqual(t, labelValue(strings.Repeat("a", 65)), value)
+			},
+		},
+	}
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			value := labelValue(testCase.value)
+			require.Empty(t, validation.IsValidLabelValue(value))
+			require.Equal(t, value, labelValue(testCase.value))
+			testCase.assertions(t, value)
+		})
+	}
+}
+
+func TestJobPodName(t *testing.T) {
+	require.Equal(
+		t,
+		"ci-test-01dx6ezmhdrsxpx2vfdx9b5c8m",
+		jobPodName("ci", "test", "01DX6EZMHDRSXPX2VFDX9B5C8M", 1),
+	)
+	require.Equal(
+		t,
+		"ci-test-01dx6ezmhdrsxpx2vfdx9b5c8m-2",
+		jobPodName("ci", "test", "01DX6EZMHDRSXPX2VFDX9B5C8M", 2),
+	)
+	// Retries of jobs with long names must still have distinct pod names
+	longJobName := strings.Repeat("a", 60)
+	require.NotEqual(
+		t,
+		jobPodName("ci", longJobName, "01DX6EZMHDRSXPX2VFDX9B5C8M", 2),
+		jobPodName("ci", longJobName, "01DX6EZMHDRSXPX2VFDX9B5C8M", 3),
+	)
+	// Jobs whose names differ only in case must have distinct pod names
+	require.NotEqual(
+		t,
+		jobPodName("ci", "build", "01DX6EZMHDRSXPX2VFDX9B5C8M", 1),
+		jobPodName("ci", "Build", "01DX6EZMHDRSXPX2VFDX9B5C8M", 1),
+	)
+}
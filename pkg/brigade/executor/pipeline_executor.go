@@ -4,23 +4,43 @@ import (
 	"context"
 	"log"
 	"sync"
+	"time"
 
 	"github.com/lovethedrake/brigdrake/pkg/brigade"
 	"github.com/lovethedrake/brigdrake/pkg/drake"
 	"github.com/lovethedrake/drakecore/config"
+	"github.com/pkg/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/labels"
 	"k8s.io/apimachinery/pkg/selection"
 	"k8s.io/client-go/kubernetes"
 )
 
+// pipelineExecution represents the jobs of a pipeline that are to be executed,
+// environment variables to be exposed to all of those jobs' containers, and
+// the JobStatusNotifier to be used in reporting on their progress. It also
+// records the trigger match that made the pipeline eligible for execution and
+// indicates whether project secrets are to be withheld from the jobs.
+type pipelineExecution struct {
+	matchResult       drake.MatchResult
+	jobs              []config.PipelineJob
+	environment       map[string]string
+	jobStatusNotifier drake.JobStatusNotifier
+	withholdSecrets   bool
+}
+
 func executePipeline(
 	ctx context.Context,
 	project brigade.Project,
 	event brigade.Event,
 	workerConfig brigade.WorkerConfig,
 	pipeline config.Pipeline,
+	matchResult drake.MatchResult,
+	jobs []config.PipelineJob,
+	environment map[string]string,
 	jobStatusNotifier drake.JobStatusNotifier,
+	logSinks []LogSink,
+	artifactStore ArtifactStore,
 	kubeClient kubernetes.Interface,
 	wg *sync.WaitGroup,
 	errCh chan<- error,
@@ -28,20 +48,77 @@ func executePipeline(
 	defer wg.Done()
 	log.Printf("executing pipeline %q", pipeline.Name())
 
+	// Every job's result is recorded here for inclusion in the pipeline's final
+	// status notification. Jobs that never get as far as starting are reported
+	// as skipped. Each job's manager goroutine writes only to its own element.
+	results := make([]drake.JobResult, len(jobs))
+	for i, job := range jobs {
+		results[i] = drake.JobResult{
+			Job:        job.Job().Name(),
+			Conclusion: drake.JobConclusionSkipped,
+		}
+	}
+	var pipelineFailed bool
+	// All errors are reported through this so that we know whether the pipeline
+	// failed.
+	reportErr := func(err error) {
+		if err != nil {
+			pipelineFailed = true
+		}
+		errCh <- err
+	}
+	if jobStatusNotifier != nil {
+		err := jobStatusNotifier.SendPipelineInProgressNotification(
+			pipeline,
+			matchResult,
+		)
+		if err != nil {
+			log.Printf("error sending pipeline status notification: %s", err)
+		}
+		defer func() {
+			var err error
+			if pipelineFailed {
+				err = jobStatusNotifier.SendPipelineFailureNotification(
+					pipeline,
+					matchResult,
+					results,
+				)
+			} else {
+				err = jobStatusNotifier.SendPipelineSuccessNotification(
+					pipeline,
+					matchResult,
+					results,
+				)
+			}
+			if err != nil {
+				log.Printf("error sending pipeline status notification: %s", err)
+			}
+		}()
+	}
+
+	// If the pipeline has a deadline, everything that follows happens within
+	// this context, so that exceeding the deadline cancels the pipeline's jobs.
+	pipelineCtx := ctx
+	if timeout := pipelineTimeout(project, workerConfig); timeout > 0 {
+		var cancel context.CancelFunc
+		pipelineCtx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+
 	// If ANY of the pipeline's jobs' containers mounts shared storage, we need to
-	// create a volume.
+	// create a volume. Artifacts are collected from shared storage, so any job
+	// that declares artifacts must mount it.
 	var pipelineNeedsSharedStorage bool
-jobsLoop:
-	for _, pipelineJob := range pipeline.Jobs() {
-		if pipelineJob.Job().PrimaryContainer().SharedStorageMountPath() != "" {
+	for _, pipelineJob := range jobs {
+		if jobUsesSharedStorage(pipelineJob.Job()) {
 			pipelineNeedsSharedStorage = true
-			break jobsLoop
-		}
-		for _, sidecarContainer := range pipelineJob.Job().SidecarContainers() {
-			if sidecarContainer.SharedStorageMountPath() != "" {
-				pipelineNeedsSharedStorage = true
-				break jobsLoop
-			}
+		} else if len(project.Jobs[pipelineJob.Job().Name()].Artifacts) > 0 {
+			reportErr(errors.Errorf(
+				"job %q declares artifacts, but none of its containers mount shared "+
+					"storage",
+				pipelineJob.Job().Name(),
+			))
+			return
 		}
 	}
 	if pipelineNeedsSharedStorage {
@@ -54,7 +131,7 @@ jobsLoop:
 			pipeline.Name(),
 			kubeClient,
 		); err != nil {
-			errCh <- err
+			reportErr(err)
 			return
 		}
 		log.Printf("created shared storage for pipeline %q", pipeline.Name())
@@ -62,23 +139,35 @@ jobsLoop:
 
 	var err error
 	defer func() {
-		// If context was canceled, we have a bunch of job pods to get rid of that
-		// we'd like to keep otherwise.
+		// If context was canceled or the pipeline exceeded its deadline, we have
+		// a bunch of job pods to get rid of that we'd like to keep otherwise.
 		select {
-		case <-ctx.Done():
+		case <-pipelineCtx.Done():
 			labelSelector := labels.NewSelector()
-			if workerRequirement, rerr := labels.NewRequirement(
+			workerRequirement, rerr := labels.NewRequirement(
 				"worker",
 				selection.Equals,
-				[]string{event.WorkerID},
-			); rerr != nil {
+				[]string{labelValue(event.WorkerID)},
+			)
+			var pipelineRequirement *labels.Requirement
+			if rerr == nil {
+				pipelineRequirement, rerr = labels.NewRequirement(
+					"thedrake.io/pipeline",
+					selection.Equals,
+					[]string{labelValue(pipeline.Name())},
+				)
+			}
+			if rerr != nil {
 				log.Printf(
 					"error deleting pods for pipeline %q: %s",
 					pipeline.Name(),
 					rerr,
 				)
 			} else {
-				labelSelector = labelSelector.Add(*workerRequirement)
+				labelSelector = labelSelector.Add(
+					*workerRequirement,
+					*pipelineRequirement,
+				)
 				log.Printf("deleting pods %q", labelSelector.String())
 				if derr := kubeClient.CoreV1().Pods(
 					project.Kubernetes.Namespace,
@@ -119,10 +208,47 @@ jobsLoop:
 				)
 			}
 		}
-		errCh <- err
+		reportErr(err)
 	}()
 
-	jobs := pipeline.Jobs()
+	// Resolve the keys of any caches the pipeline's jobs use and make sure each
+	// cache exists.
+	var caches map[string][]jobCache
+	if caches, err = resolveJobCaches(
+		project,
+		event,
+		pipeline.Name(),
+		jobs,
+		environment,
+		workerSourceDir,
+	); err != nil {
+		return
+	}
+	now := time.Now()
+	for _, jobCaches := range caches {
+		for _, cache := range jobCaches {
+			if err = ensureCachePVC(
+				project,
+				workerConfig,
+				cache,
+				now,
+				kubeClient,
+			); err != nil {
+				return
+			}
+		}
+	}
+
+	// Report all of the pipeline's jobs as queued up front so that jobs waiting
+	// on their dependencies are visible.
+	if jobStatusNotifier != nil {
+		for _, job := range jobs {
+			if nerr :=
+				jobStatusNotifier.SendQueuedNotification(job.Job()); nerr != nil {
+				log.Printf("error sending job status notification: %s", nerr)
+			}
+		}
+	}
 
 	// Build a map of channels that lets the job scheduler subscribe to the
 	// completion of each job's dependencies. (A given dependency is complete if
@@ -135,7 +261,7 @@ jobsLoop:
 	// We'll cancel this context if a job fails and we don't want to start any
 	// new ones that may be pending. This does NOT mean we cancel jobs that are
 	// already in-progress.
-	pendingJobsCtx, cancelPendingJobs := context.WithCancel(ctx)
+	pendingJobsCtx, cancelPendingJobs := context.WithCancel(pipelineCtx)
 	defer cancelPendingJobs()
 
 	// Start a goroutine to manage each job. This doesn't automatically run
@@ -143,8 +269,9 @@ jobsLoop:
 	// executing it.
 	managersWg := &sync.WaitGroup{}
 	localErrCh := make(chan error)
-	for _, j := range jobs {
+	for i, j := range jobs {
 		job := j
+		result := &results[i]
 		managersWg.Add(1)
 		go func() {
 			defer managersWg.Done()
@@ -155,23 +282,34 @@ jobsLoop:
 					// Continue to wait for the next dependency
 				case <-pendingJobsCtx.Done():
 					// Pending jobs were canceled; abort
+					abandonPendingJob(pipelineCtx, job.Job(), result, jobStatusNotifier)
 					localErrCh <- &pendingJobCanceledError{job: job.Job().Name()}
 					return
-				case <-ctx.Done():
+				case <-pipelineCtx.Done():
 					// Everything was canceled; abort
+					abandonPendingJob(pipelineCtx, job.Job(), result, jobStatusNotifier)
 					localErrCh <- &pendingJobCanceledError{job: job.Job().Name()}
 					return
 				}
 			}
-			if err := runJobPod(
-				ctx,
+			start := time.Now()
+			err := runJobPod(
+				pipelineCtx,
 				project,
 				event,
+				workerConfig,
 				pipeline.Name(),
 				job.Job(),
+				environment,
+				caches[job.Job().Name()],
 				jobStatusNotifier,
+				logSinks,
+				artifactStore,
 				kubeClient,
-			); err != nil {
+			)
+			result.Conclusion = jobConclusion(err)
+			result.Duration = time.Since(start)
+			if err != nil {
 				// This localErrCh write isn't in a select because we don't want it to
 				// be interruptable since we never want to lose an error message. And we
 				// know the goroutine that is collecting errors is also not
@@ -215,8 +353,94 @@ errLoop:
 	}
 
 	if len(errs) > 1 {
-		errCh <- &multiError{errs: errs}
+		reportErr(&multiError{errs: errs})
 	} else if len(errs) == 1 {
-		errCh <- errs[0]
+		reportErr(errs[0])
+	}
+}
+
+// abandonPendingJob records the conclusion of a job that will never start and
+// reports it via the job status notifier, if there is one. If the pipeline as
+// a whole was canceled or timed out, the job is considered canceled. Otherwise,
+// it's considered skipped because a job it depends on failed.
+func abandonPendingJob(
+	pipelineCtx context.Context,
+	job config.Job,
+	result *drake.JobResult,
+	jobStatusNotifier drake.JobStatusNotifier,
+) {
+	result.Conclusion = drake.JobConclusionSkipped
+	if pipelineCtx.Err() != nil {
+		result.Conclusion = drake.JobConclusionCancelled
+	}
+	if jobStatusNotifier == nil {
+		return
+	}
+	var err error
+	if result.Conclusion == drake.JobConclusionCancelled {
+		err = jobStatusNotifier.SendCancelledNotification(job, drake.JobOutput{})
+	} else {
+		err = jobStatusNotifier.SendSkippedNotification(job)
+	}
+	if err != nil {
+		log.Printf("error sending job status notification: %s", err)
+	}
+}
+
+// pipelineTimeout determines how long a pipeline may run, in total. The
+// project's default takes precedence over the worker's default. A zero value
+// means the pipeline has no deadline.
+func pipelineTimeout(
+	project brigade.Project,
+	workerConfig brigade.WorkerConfig,
+) time.Duration {
+	if project.PipelineTimeout > 0 {
+		return project.PipelineTimeout
+	}
+	return workerConfig.DefaultPipelineTimeout
+}
+
+// selectPipelineJobs returns the pipeline's jobs having the given names, along
+// with all the jobs that those depend upon, directly or indirectly. Jobs are
+// returned in the order they appear in the pipeline. If no names are given, or
+// the pipeline's own name is among them, all of the pipeline's jobs are
+// returned.
+func selectPipelineJobs(
+	pipeline config.Pipeline,
+	jobNames []string,
+) []config.PipelineJob {
+	jobs := pipeline.Jobs()
+	if len(jobNames) == 0 {
+		return jobs
+	}
+	for _, jobName := range jobNames {
+		if jobName == pipeline.Name() {
+			return jobs
+		}
+	}
+	selected := map[string]bool{}
+	var selectJob func(config.PipelineJob)
+	selectJob = func(job config.PipelineJob) {
+		if selected[job.Job().Name()] {
+			return
+		}
+		selected[job.Job().Name()] = true
+		for _, dependency := range job.Dependencies() {
+			selectJob(dependency)
+		}
+	}
+	for _, job := range jobs {
+		for _, jobName := range jobNames {
+			if job.Job().Name() == jobName {
+				selectJob(job)
+			}
+		}
+	}
+	selectedJobs := []config.PipelineJob{}
+	for _, job := range jobs {
+		if selected[job.Job().Name()] {
+			selectedJobs = append(selectedJobs, job)
+		}
 	}
+	return selectedJobs
 }
@@ -0,0 +1,156 @@
+package executor
+
+import (
+	"context"
+	"testing"
+
+	"github.com/lovethedrake/brigdrake/pkg/drake"
+	"github.com/lovethedrake/drakecore/config"
+	"github.com/stretchr/testify/require"
+)
+
+const testDrakefile = `
+specUri: github.com/lovethedrake/drakespec
+specVersion: v0.6.0
+jobs:
+  lint:
+    primaryContainer:
+      name: go
+      image: golang
+  test:
+    primaryContainer:
+      name: go
+      image: golang
+  build:
+    primaryContainer:
+      name: go
+      image: golang
+  publish:
+    primaryContainer:
+      name: go
+      image: golang
+pipelines:
+  ci:
+    jobs:
+    - name: lint
+    - name: test
+    - name: build
+      dependencies:
+      - lint
+      - test
+    - name: publish
+      dependencies:
+      - build
+`
+
+func TestSelectPipelineJobs(t *testing.T) {
+	cfg, err := config.NewConfigFromYAML([]byte(testDrakefile))
+	require.NoError(t, err)
+	pipelines, err := cfg.Pipelines("ci")
+	require.NoError(t, err)
+	pipeline := pipelines[0]
+	testCases := []struct {
+		name     string
+		jobNames []string
+		expected []string
+	}{
+		{
+			name:     "no jobs selected",
+			expected: []string{"lint", "test", "build", "publish"},
+		},
+		{
+			name:     "job without dependencies selected",
+			jobNames: []string{"test"},
+			expected: []string{"test"},
+		},
+		{
+			name:     "job with transitive dependencies selected",
+			jobNames: []string{"publish"},
+			expected: []string{"lint", "test", "build", "publish"},
+		},
+		{
+			name:     "multiple jobs selected",
+			jobNames: []string{"lint", "build"},
+			expected: []string{"lint", "test", "build"},
+		},
+		{
+			name:     "pipeline selected",
+			jobNames: []string{"ci"},
+			expected: []string{"lint", "test", "build", "publish"},
+		},
+		{
+			name:     "job not in pipeline selected",
+			jobNames: []string{"deploy"},
+			expected: []string{},
+		},
+	}
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			jobs := selectPipelineJobs(pipeline, testCase.jobNames)
+			jobNames := make([]string, len(jobs))
+			for i, job := range jobs {
+				jobNames[i] = job.Job().Name()
+			}
+			require.Equal(t, testCase.expected, jobNames)
+		})
+	}
+}
+
+// fakeJobStatusNotifier records the conclusions of jobs it's notified were
+// skipped or canceled. Its other methods are not implemented.
+type fakeJobStatusNotifier struct {
+	drake.JobStatusNotifier
+	conclusions map[string]string
+}
+
+func (f *fakeJobStatusNotifier) SendCancelledNotification(
+	job config.Job,
+	_ drake.JobOutput,
+) error {
+	f.conclusions[job.Name()] = "cancelled"
+	return nil
+}
+
+func (f *fakeJobStatusNotifier) SendSkippedNotification(job config.Job) error {
+	f.conclusions[job.Name()] = "skipped"
+	return nil
+}
+
+func TestAbandonPendingJob(t *testing.T) {
+	job := &fakeJob{name: "foo"}
+	testCases := []struct {
+		name               string
+		cancelPipeline     bool
+		expectedConclusion drake.JobConclusion
+	}{
+		{
+			name:               "upstream job failed",
+			expectedConclusion: drake.JobConclusionSkipped,
+		},
+		{
+			name:               "pipeline canceled",
+			cancelPipeline:     true,
+			expectedConclusion: drake.JobConclusionCancelled,
+		},
+	}
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			pipelineCtx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+			if testCase.cancelPipeline {
+				cancel()
+			}
+			result := &drake.JobResult{Job: job.Name()}
+			notifier := &fakeJobStatusNotifier{conclusions: map[string]string{}}
+			abandonPendingJob(pipelineCtx, job, result, notifier)
+			require.Equal(t, testCase.expectedConclusion, result.Conclusion)
+			require.Equal(
+				t,
+				string(testCase.expectedConclusion),
+				notifier.conclusions[job.Name()],
+			)
+			// A nil notifier is tolerated
+			abandonPendingJob(pipelineCtx, job, result, nil)
+		})
+	}
+}
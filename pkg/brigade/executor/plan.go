@@ -0,0 +1,248 @@
+package executor
+
+import (
+	"fmt"
+	"io"
+	"text/tabwriter"
+	"time"
+
+	"github.com/lovethedrake/brigdrake/pkg/brigade"
+	"github.com/lovethedrake/brigdrake/pkg/drake"
+	"github.com/lovethedrake/drakecore/config"
+	"github.com/pkg/errors"
+	v1 "k8s.io/api/core/v1"
+)
+
+// TriggerEvaluation describes the outcome of evaluating one of a pipeline's
+// triggers against an event.
+type TriggerEvaluation struct {
+	// SpecURI identifies the spec the trigger implements.
+	SpecURI string
+	// Supported indicates whether a trigger implementation is registered for
+	// the trigger's spec and spec version. Unsupported triggers never match.
+	Supported bool
+	// Result explains whether the trigger matched the event and why. It is the
+	// zero value for unsupported triggers.
+	Result drake.MatchResult
+}
+
+// writeTriggerEvaluations writes one tab-separated row per evaluated trigger
+// of the named pipeline, for use with the tabwriter returned by
+// newTriggerEvaluationsWriter.
+func writeTriggerEvaluations(
+	w io.Writer,
+	pipelineName string,
+	evaluations []TriggerEvaluation,
+) {
+	if len(evaluations) == 0 {
+		fmt.Fprintf(
+			w,
+			"%s\t-\t-\tno match\t-\tno triggers are defined\n",
+			pipelineName,
+		)
+		return
+	}
+	for i, evaluation := range evaluations {
+		result := "no match"
+		selector := evaluation.Result.Selector
+		reason := evaluation.Result.Reason
+		if !evaluation.Supported {
+			result = "unsupported"
+			reason = "trigger spec is not supported"
+		} else if evaluation.Result.Matched {
+			result = "match"
+		}
+		if selector == "" {
+			selector = "-"
+		}
+		fmt.Fprintf(
+			w,
+			"%s\t%d\t%s\t%s\t%s\t%s\n",
+			pipelineName,
+			i,
+			evaluation.SpecURI,
+			result,
+			selector,
+			reason,
+		)
+	}
+}
+
+// newTriggerEvaluationsWriter returns a tabwriter that writes a table of
+// trigger evaluations, beginning with its header row, to w.
+func newTriggerEvaluationsWriter(w io.Writer) *tabwriter.Writer {
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, "PIPELINE\tTRIGGER\tSPEC\tRESULT\tSELECTOR\tREASON")
+	return tw
+}
+
+// PlannedJob is a job that would be executed as part of a pipeline, along with
+// the names of the jobs it depends on.
+type PlannedJob struct {
+	Name         string
+	Dependencies []string
+}
+
+// PipelinePlan describes whether a pipeline would be executed in response to
+// an event and, if so, how.
+type PipelinePlan struct {
+	// Name is the name of the pipeline.
+	Name string
+	// TriggerEvaluations describes the evaluation of each of the pipeline's
+	// triggers, up to and including the first that matched the event.
+	TriggerEvaluations []TriggerEvaluation
+	// Matched indicates whether the pipeline would be executed.
+	Matched bool
+	// SecretsWithheld indicates whether project secrets would be withheld from
+	// the pipeline's jobs because of the event's origin.
+	SecretsWithheld bool
+	// Jobs are the pipeline's jobs that would be executed, in the order they
+	// appear in the pipeline.
+	Jobs []PlannedJob
+	// Pods are the pods that would be created for the first attempt of each of
+	// the jobs.
+	Pods []*v1.Pod
+	// PersistentVolumeClaims are the PVCs that would be created for the
+	// pipeline's shared storage and for its jobs' caches.
+	PersistentVolumeClaims []*v1.PersistentVolumeClaim
+}
+
+// PlanBuild evaluates the triggers of every pipeline defined in the Drakefile
+// at the given path against the event exactly as ExecuteBuild would, but
+// rather than executing the pipelines that match, it describes what would be
+// executed, including the Kubernetes resources that would be created. It
+// requires no Kubernetes cluster and, since no job status is reported, makes
+// no calls to the event provider's API to set up reporting. Any cache key
+// files are read relative to sourceDir.
+func PlanBuild(
+	project brigade.Project,
+	event brigade.Event,
+	workerConfig brigade.WorkerConfig,
+	drakefilePath string,
+	sourceDir string,
+) ([]PipelinePlan, error) {
+	cfg, err := config.NewConfigFromFile(drakefilePath)
+	if err != nil {
+		return nil, errors.Wrapf(err, "error reading %s", drakefilePath)
+	}
+	if errs := validateDrakefile(project, cfg); len(errs) > 0 {
+		return nil, &drakefileValidationError{errs: errs}
+	}
+	pipelines := cfg.AllPipelines()
+	plans := make([]PipelinePlan, len(pipelines))
+	for i, pipeline := range pipelines {
+		execution, evaluations, err := evaluatePipeline(
+			project,
+			event,
+			pipeline,
+			false,
+		)
+		if err != nil {
+			return nil, err
+		}
+		plans[i] = PipelinePlan{
+			Name:               pipeline.Name(),
+			TriggerEvaluations: evaluations,
+		}
+		if execution == nil {
+			continue
+		}
+		if err = planPipeline(
+			project,
+			event,
+			workerConfig,
+			pipeline.Name(),
+			*execution,
+			sourceDir,
+			&plans[i],
+		); err != nil {
+			return nil, err
+		}
+	}
+	return plans, nil
+}
+
+// planPipeline completes the plan for a pipeline that would be executed by
+// building all the Kubernetes resources that would be created for it.
+func planPipeline(
+	project brigade.Project,
+	event brigade.Event,
+	workerConfig brigade.WorkerConfig,
+	pipelineName string,
+	execution pipelineExecution,
+	sourceDir string,
+	plan *PipelinePlan,
+) error {
+	plan.Matched = true
+	plan.SecretsWithheld = execution.withholdSecrets
+	if execution.withholdSecrets {
+		project = projectWithoutSecrets(project)
+	}
+	var pipelineNeedsSharedStorage bool
+	for _, pipelineJob := range execution.jobs {
+		plannedJob := PlannedJob{
+			Name:         pipelineJob.Job().Name(),
+			Dependencies: []string{},
+		}
+		for _, dependency := range pipelineJob.Dependencies() {
+			plannedJob.Dependencies =
+				append(plannedJob.Dependencies, dependency.Job().Name())
+		}
+		plan.Jobs = append(plan.Jobs, plannedJob)
+		if jobUsesSharedStorage(pipelineJob.Job()) {
+			pipelineNeedsSharedStorage = true
+		}
+	}
+	if pipelineNeedsSharedStorage {
+		pvc, err := buildSharedStoragePVC(
+			project,
+			event,
+			workerConfig,
+			pipelineName,
+		)
+		if err != nil {
+			return err
+		}
+		plan.PersistentVolumeClaims = append(plan.PersistentVolumeClaims, pvc)
+	}
+	caches, err := resolveJobCaches(
+		project,
+		event,
+		pipelineName,
+		execution.jobs,
+		execution.environment,
+		sourceDir,
+	)
+	if err != nil {
+		return err
+	}
+	now := time.Now()
+	// Jobs may share caches, but each cache's PVC would only be created once
+	plannedCachePVCs := map[string]bool{}
+	for _, pipelineJob := range execution.jobs {
+		for _, cache := range caches[pipelineJob.Job().Name()] {
+			if plannedCachePVCs[cache.pvcName] {
+				continue
+			}
+			plannedCachePVCs[cache.pvcName] = true
+			plan.PersistentVolumeClaims = append(
+				plan.PersistentVolumeClaims,
+				buildCachePVC(project, workerConfig, cache, now),
+			)
+		}
+		pod, err := buildJobPod(
+			project,
+			event,
+			pipelineName,
+			pipelineJob.Job(),
+			execution.environment,
+			caches[pipelineJob.Job().Name()],
+			1,
+		)
+		if err != nil {
+			return err
+		}
+		plan.Pods = append(plan.Pods, pod)
+	}
+	return nil
+}
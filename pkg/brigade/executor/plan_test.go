@@ -0,0 +1,280 @@
+package executor
+
+import (
+	"bytes"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/lovethedrake/brigdrake/pkg/brigade"
+	"github.com/lovethedrake/brigdrake/pkg/drake"
+	// Register the triggers used by the Drakefiles in this package's tests
+	_ "github.com/lovethedrake/brigdrake/pkg/drake/brig"
+	_ "github.com/lovethedrake/brigdrake/pkg/drake/github"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/require"
+)
+
+const testPlanDrakefile = `
+specUri: github.com/lovethedrake/drakespec
+specVersion: v0.6.0
+jobs:
+  test:
+    primaryContainer:
+      name: go
+      image: golang
+      sharedStorageMountPath: /shared
+  build:
+    primaryContainer:
+      name: go
+      image: golang
+pipelines:
+  ci:
+    triggers:
+    - specUri: github.com/lovethedrake/drakespec-brig
+      specVersion: v1.0.0
+      config:
+        eventTypes:
+        - exec
+    - specUri: github.com/lovethedrake/drakespec-unsupported
+      specVersion: v1.0.0
+    jobs:
+    - name: test
+    - name: build
+      dependencies:
+      - test
+  release:
+    triggers:
+    - specUri: github.com/lovethedrake/drakespec-unsupported
+      specVersion: v1.0.0
+    - specUri: github.com/lovethedrake/drakespec-brig
+      specVersion: v1.0.0
+      config:
+        eventTypes:
+        - release
+    jobs:
+    - name: build
+`
+
+func TestPlanBuild(t *testing.T) {
+	dir, err := ioutil.TempDir("", "plan")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	drakefilePath := filepath.Join(dir, "Drakefile.yaml")
+	err = ioutil.WriteFile(drakefilePath, []byte(testPlanDrakefile), 0644)
+	require.NoError(t, err)
+	project := brigade.Project{
+		ID: "brigade-123",
+		Kubernetes: brigade.KubernetesConfig{
+			Namespace:        "default",
+			BuildStorageSize: "50Mi",
+		},
+	}
+	event := brigade.Event{
+		WorkerID: "worker-abc",
+		BuildID:  "abc",
+		Provider: "brigade-cli",
+		Type:     "exec",
+	}
+	plans, err := PlanBuild(
+		project,
+		event,
+		brigade.WorkerConfig{},
+		drakefilePath,
+		dir,
+	)
+	require.NoError(t, err)
+	require.Len(t, plans, 2)
+	plansByName := map[string]PipelinePlan{}
+	for _, plan := range plans {
+		plansByName[plan.Name] = plan
+	}
+
+	ciPlan := plansByName["ci"]
+	require.True(t, ciPlan.Matched)
+	require.Equal(
+		t,
+		[]TriggerEvaluation{
+			{
+				SpecURI:   "github.com/lovethedrake/drakespec-brig",
+				Supported: true,
+				Result: drake.Match(
+					"eventTypes",
+					`event type "exec" in [exec]`,
+				),
+			},
+		},
+		ciPlan.TriggerEvaluations,
+	)
+	require.Equal(
+		t,
+		[]PlannedJob{
+			{Name: "test", Dependencies: []string{}},
+			{Name: "build", Dependencies: []string{"test"}},
+		},
+		ciPlan.Jobs,
+	)
+	require.Len(t, ciPlan.Pods, 2)
+	require.Equal(t, "ci-test-abc", ciPlan.Pods[0].Name)
+	require.Equal(t, "ci-build-abc", ciPlan.Pods[1].Name)
+	require.Len(t, ciPlan.PersistentVolumeClaims, 1)
+	require.Equal(t, "worker-abc-ci", ciPlan.PersistentVolumeClaims[0].Name)
+
+	releasePlan := plansByName["release"]
+	require.False(t, releasePlan.Matched)
+	require.Equal(
+		t,
+		[]TriggerEvaluation{
+			{SpecURI: "github.com/lovethedrake/drakespec-unsupported"},
+			{
+				SpecURI:   "github.com/lovethedrake/drakespec-brig",
+				Supported: true,
+				Result: drake.NoMatch(
+					"eventTypes",
+					`event type "exec" not in [release]`,
+				),
+			},
+		},
+		releasePlan.TriggerEvaluations,
+	)
+	require.Empty(t, releasePlan.Jobs)
+	require.Empty(t, releasePlan.Pods)
+}
+
+const testGithubPlanDrakefile = `
+specUri: github.com/lovethedrake/drakespec
+specVersion: v0.6.0
+jobs:
+  test:
+    primaryContainer:
+      name: go
+      image: golang
+pipelines:
+  ci:
+    triggers:
+    - specUri: github.com/lovethedrake/drakespec-github
+      specVersion: v1.0.0
+      config:
+        push:
+          branches:
+            only:
+            - master
+    jobs:
+    - name: test
+`
+
+// recordingRoundTripper records the URL of every request it's asked to make
+// and fails them all.
+type recordingRoundTripper struct {
+	urls []string
+}
+
+func (r *recordingRoundTripper) RoundTrip(
+	req *http.Request,
+) (*http.Response, error) {
+	r.urls = append(r.urls, req.URL.String())
+	return nil, errors.New("unexpected request")
+}
+
+func TestPlanBuildMakesNoGithubAPICalls(t *testing.T) {
+	dir, err := ioutil.TempDir("", "plan")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	drakefilePath := filepath.Join(dir, "Drakefile.yaml")
+	err = ioutil.WriteFile(drakefilePath, []byte(testGithubPlanDrakefile), 0644)
+	require.NoError(t, err)
+	// With valid GitHub App credentials, executing the build would obtain an
+	// installation token in order to report job status.
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	keyPEM := pem.EncodeToMemory(
+		&pem.Block{
+			Type:  "RSA PRIVATE KEY",
+			Bytes: x509.MarshalPKCS1PrivateKey(key),
+		},
+	)
+	project := brigade.Project{
+		ID: "brigade-123",
+		Kubernetes: brigade.KubernetesConfig{
+			Namespace:        "default",
+			BuildStorageSize: "50Mi",
+		},
+		Secrets: map[string]string{
+			"BRIGDRAKE_GITHUB_APP_ID": "42",
+			"BRIGDRAKE_GITHUB_KEY":    base64.StdEncoding.EncodeToString(keyPEM),
+		},
+	}
+	event := brigade.Event{
+		WorkerID: "worker-abc",
+		BuildID:  "abc",
+		Provider: "github",
+		Type:     "push",
+		Payload: []byte(
+			`{"ref":"refs/heads/master","after":"1234567",` +
+				`"installation":{"id":1},` +
+				`"repository":{"name":"brigdrake","owner":{"login":"lovethedrake"}}}`,
+		),
+	}
+	roundTripper := &recordingRoundTripper{}
+	defaultTransport := http.DefaultTransport
+	http.DefaultTransport = roundTripper
+	defer func() {
+		http.DefaultTransport = defaultTransport
+	}()
+	plans, err := PlanBuild(
+		project,
+		event,
+		brigade.WorkerConfig{},
+		drakefilePath,
+		dir,
+	)
+	require.NoError(t, err)
+	require.Len(t, plans, 1)
+	require.True(t, plans[0].Matched)
+	require.Empty(t, roundTripper.urls)
+}
+
+func TestWriteTriggerEvaluations(t *testing.T) {
+	buf := &bytes.Buffer{}
+	w := newTriggerEvaluationsWriter(buf)
+	writeTriggerEvaluations(
+		w,
+		"ci",
+		[]TriggerEvaluation{
+			{SpecURI: "example.com/unsupported"},
+			{
+				SpecURI:   "example.com/supported",
+				Supported: true,
+				Result:    drake.Match("push.branches", "branch master"),
+			},
+		},
+	)
+	writeTriggerEvaluations(w, "release", nil)
+	require.NoError(t, w.Flush())
+	require.Equal(
+		t,
+		strings.Join(
+			[]string{
+				"PIPELINE  TRIGGER  SPEC                     RESULT       " +
+					"SELECTOR       REASON",
+				"ci        0        example.com/unsupported  unsupported  " +
+					"-              trigger spec is not supported",
+				"ci        1        example.com/supported    match        " +
+					"push.branches  branch master",
+				"release   -        -                        no match     " +
+					"-              no triggers are defined",
+				"",
+			},
+			"\n",
+		),
+		buf.String(),
+	)
+}
@@ -1,7 +1,6 @@
 package executor
 
 import (
-	"fmt"
 	"strings"
 
 	"github.com/lovethedrake/brigdrake/pkg/brigade"
@@ -58,10 +57,10 @@ func buildSharedStoragePVC(
 			Labels: map[string]string{
 				"heritage":  "brigade",
 				"component": "buildStorage",
-				"project":   project.ID,
-				"worker":    strings.ToLower(event.WorkerID),
-				"build":     event.BuildID,
-				"pipeline":  pipelineName,
+				"project":   labelValue(project.ID),
+				"worker":    labelValue(strings.ToLower(event.WorkerID)),
+				"build":     labelValue(event.BuildID),
+				"pipeline":  labelValue(pipelineName),
 			},
 		},
 		Spec: v1.PersistentVolumeClaimSpec{
@@ -106,7 +105,5 @@ func destroySharedStoragePVC(
 // storage PVC by name to reliably use the correct name as long as they have the
 // workerID and pipelineName.
 func sharedStoragePVCName(workerID, pipelineName string) string {
-	workerIDLower := strings.ToLower(workerID)
-	pipelineNameLower := strings.ToLower(pipelineName)
-	return fmt.Sprintf("%s-%s", workerIDLower, pipelineNameLower)
+	return resourceName(strings.ToLower(workerID), pipelineName)
 }
@@ -133,5 +133,11 @@ func TestDestroySharedStoragePVC(t *testing.T) {
 }
 
 func TestSharedStoragePVCName(t *testing.T) {
-	require.Equal(t, "foo-bar", sharedStoragePVCName("FOO", "BAR"))
+	require.Equal(t, "foo-bar", sharedStoragePVCName("FOO", "bar"))
+	// Pipeline names that differ only in case get distinct PVCs
+	require.NotEqual(
+		t,
+		sharedStoragePVCName("foo", "bar"),
+		sharedStoragePVCName("foo", "BAR"),
+	)
 }
@@ -0,0 +1,186 @@
+package executor
+
+import (
+	"log"
+	"strings"
+
+	"github.com/lovethedrake/brigdrake/pkg/brigade"
+	"github.com/lovethedrake/brigdrake/pkg/drake"
+	"github.com/lovethedrake/drakecore/config"
+	"github.com/pkg/errors"
+	"k8s.io/apimachinery/pkg/util/validation"
+)
+
+// validateDrakefile checks everything about the Drakefile that would otherwise
+// only be found to be wrong once a pipeline was being executed: that every
+// trigger's configuration can be parsed, that no container requests anything
+// the project doesn't permit, that resource requests are sensible, and that
+// container names are valid. Triggers must specify a version of their spec
+// that is supported. Triggers whose spec isn't supported at all are logged,
+// but aren't errors, since they are simply never matched. All of the errors
+// found are returned together.
+func validateDrakefile(project brigade.Project, cfg config.Config) []error {
+	errs := []error{}
+	for _, pipeline := range cfg.AllPipelines() {
+		for i, pipelineTrigger := range pipeline.Triggers() {
+			triggerBuilderFn, ok := drake.LookupTrigger(
+				pipelineTrigger.SpecURI(),
+				pipelineTrigger.SpecVersion(),
+			)
+			if !ok {
+				// A spec that isn't supported at all may be meant for some other
+				// tool, but a spec version that isn't supported means the trigger's
+				// configuration can't be relied upon to be interpreted correctly.
+				constraints :=
+					drake.TriggerVersionConstraints(pipelineTrigger.SpecURI())
+				if len(constraints) == 0 {
+					log.Printf(
+						"trigger %d (%q) for pipeline %q implements an unsupported "+
+							"spec and will never match",
+						i,
+						pipelineTrigger.SpecURI(),
+						pipeline.Name(),
+					)
+					continue
+				}
+				errs = append(
+					errs,
+					errors.Errorf(
+						"trigger %d (%q) for pipeline %q specifies unsupported spec "+
+							"version %q; supported versions are %s",
+						i,
+						pipelineTrigger.SpecURI(),
+						pipeline.Name(),
+						pipelineTrigger.SpecVersion(),
+						strings.Join(constraints, " || "),
+					),
+				)
+				continue
+			}
+			if _, err := triggerBuilderFn(pipelineTrigger.Config()); err != nil {
+				errs = append(
+					errs,
+					errors.Wrapf(
+						err,
+						"error parsing trigger %d (%q) configuration for pipeline %q",
+						i,
+						pipelineTrigger.SpecURI(),
+						pipeline.Name(),
+					),
+				)
+			}
+		}
+	}
+	for _, job := range cfg.AllJobs() {
+		containers := append(
+			[]config.Container{job.PrimaryContainer()},
+			job.SidecarContainers()...,
+		)
+		for _, container := range containers {
+			for _, err := range validateContainer(project, container) {
+				errs = append(errs, errors.Wrapf(err, "job %q", job.Name()))
+			}
+		}
+	}
+	return errs
+}
+
+// validateContainer returns all the reasons a pod could not be created for the
+// given container.
+func validateContainer(
+	project brigade.Project,
+	container config.Container,
+) []error {
+	errs := containerPermissionErrors(project, container)
+	for _, msg := range validation.IsDNS1123Label(container.Name()) {
+		errs = append(
+			errs,
+			errors.Errorf("container name %q is invalid: %s", container.Name(), msg),
+		)
+	}
+	// The DrakeSpec schema ensures resource values are positive, but not that
+	// requests are within limits, which Kubernetes requires.
+	cpu := container.Resources().CPU()
+	if cpu.RequestedMillicores() > cpu.MaxMillicores() {
+		errs = append(
+			errs,
+			errors.Errorf(
+				"container %q requests %d CPU millicores, which exceeds its "+
+					"maximum of %d",
+				container.Name(),
+				cpu.RequestedMillicores(),
+				cpu.MaxMillicores(),
+			),
+		)
+	}
+	mem := container.Resources().Memory()
+	if mem.RequestedMegabytes() > mem.MaxMegabytes() {
+		errs = append(
+			errs,
+			errors.Errorf(
+				"container %q requests %d megabytes of memory, which exceeds its "+
+					"maximum of %d",
+				container.Name(),
+				mem.RequestedMegabytes(),
+				mem.MaxMegabytes(),
+			),
+		)
+	}
+	return errs
+}
+
+// containerPermissionErrors returns an error for each thing the container
+// requests that the project does not permit.
+func containerPermissionErrors(
+	project brigade.Project,
+	container config.Container,
+) []error {
+	errs := []error{}
+	if container.Privileged() && !project.AllowPrivilegedJobs {
+		errs = append(
+			errs,
+			errors.Errorf(
+				"container %q requested to be privileged, but privileged jobs are "+
+					"not permitted by this project",
+				container.Name(),
+			),
+		)
+	}
+	if container.MountDockerSocket() && !project.AllowHostMounts {
+		errs = append(
+			errs,
+			errors.Errorf(
+				"container %q requested to mount the docker socket, but host "+
+					"mounts are not permitted by this project",
+				container.Name(),
+			),
+		)
+	}
+	return errs
+}
+
+// notifyValidationFailure reports the errors found while validating the
+// Drakefile to the event provider, if a ValidationFailureNotifier has been
+// registered for it. Failure to report is logged, but is otherwise not an
+// error, since the build fails regardless.
+func notifyValidationFailure(
+	project brigade.Project,
+	event brigade.Event,
+	errs []error,
+) {
+	builder, ok := drake.LookupValidationFailureNotifier(event.Provider)
+	if !ok {
+		return
+	}
+	notifier, err := builder(project, event)
+	if err != nil {
+		log.Printf("error reporting invalid Drakefile: %s", err)
+		return
+	}
+	if notifier == nil {
+		return
+	}
+	if err = notifier.SendValidationFailureNotification(errs); err != nil {
+		log.Printf("error reporting invalid Drakefile: %s", err)
+	}
+}
@@ -0,0 +1,170 @@
+package executor
+
+import (
+	"testing"
+
+	"github.com/lovethedrake/brigdrake/pkg/brigade"
+	"github.com/lovethedrake/drakecore/config"
+	"github.com/stretchr/testify/require"
+)
+
+func TestValidateDrakefile(t *testing.T) {
+	testCases := []struct {
+		name       string
+		project    brigade.Project
+		drakefile  string
+		assertions func(*testing.T, []error)
+	}{
+		{
+			name: "valid",
+			drakefile: `
+specUri: github.com/lovethedrake/drakespec
+specVersion: v0.6.0
+jobs:
+  test:
+    primaryContainer:
+      name: go
+      image: golang
+pipelines:
+  ci:
+    triggers:
+    - specUri: github.com/lovethedrake/drakespec-brig
+      specVersion: v1.0.0
+      config:
+        eventTypes:
+        - exec
+    - specUri: github.com/lovethedrake/drakespec-unsupported
+      specVersion: v1.0.0
+    jobs:
+    - name: test
+`,
+			assertions: func(t *testing.T, errs []error) {
+				require.Empty(t, errs)
+			},
+		},
+		{
+			name: "privileged container permitted by project",
+			project: brigade.Project{
+				AllowPrivilegedJobs: true,
+				AllowHostMounts:     true,
+			},
+			drakefile: `
+specUri: github.com/lovethedrake/drakespec
+specVersion: v0.6.0
+jobs:
+  test:
+    primaryContainer:
+      name: docker
+      image: docker
+      privileged: true
+      mountDockerSocket: true
+`,
+			assertions: func(t *testing.T, errs []error) {
+				require.Empty(t, errs)
+			},
+		},
+		{
+			name: "unsupported trigger spec version",
+			drakefile: `
+specUri: github.com/lovethedrake/drakespec
+specVersion: v0.6.0
+pipelines:
+  ci:
+    triggers:
+    - specUri: github.com/lovethedrake/drakespec-brig
+      specVersion: v2.0.0
+      config:
+        eventTypes:
+        - exec
+`,
+			assertions: func(t *testing.T, errs []error) {
+				require.Len(t, errs, 1)
+				require.Contains(
+					t,
+					errs[0].Error(),
+					`unsupported spec version "v2.0.0"; supported versions are `+
+						">=1.0.0 <2.0.0",
+				)
+			},
+		},
+		{
+			name: "unknown field in trigger config",
+			drakefile: `
+specUri: github.com/lovethedrake/drakespec
+specVersion: v0.6.0
+pipelines:
+  ci:
+    triggers:
+    - specUri: github.com/lovethedrake/drakespec-brig
+      specVersion: v1.0.0
+      config:
+        eventType:
+        - exec
+`,
+			assertions: func(t *testing.T, errs []error) {
+				require.Len(t, errs, 1)
+				require.Contains(t, errs[0].Error(), `unknown field "eventType"`)
+			},
+		},
+		{
+			name: "all errors reported together",
+			drakefile: `
+specUri: github.com/lovethedrake/drakespec
+specVersion: v0.6.0
+jobs:
+  test:
+    primaryContainer:
+      name: Go_1
+      image: golang
+      privileged: true
+      resources:
+        cpu:
+          requestedMillicores: 500
+    sidecarContainers:
+    - name: docker
+      image: docker
+      mountDockerSocket: true
+      resources:
+        memory:
+          requestedMegabytes: 512
+          maxMegabytes: 256
+pipelines:
+  ci:
+    triggers:
+    - specUri: github.com/lovethedrake/drakespec-brig
+      specVersion: v1.0.0
+      config:
+        eventTypes: exec
+    jobs:
+    - name: test
+`,
+			assertions: func(t *testing.T, errs []error) {
+				require.Len(t, errs, 6)
+				require.Contains(t, errs[0].Error(), "error parsing trigger 0")
+				require.Contains(t, errs[1].Error(), "requested to be privileged")
+				require.Contains(t, errs[2].Error(), `container name "Go_1"`)
+				require.Contains(
+					t,
+					errs[3].Error(),
+					"requests 500 CPU millicores, which exceeds its maximum of 200",
+				)
+				require.Contains(t, errs[4].Error(), "mount the docker socket")
+				require.Contains(
+					t,
+					errs[5].Error(),
+					"requests 512 megabytes of memory, which exceeds its maximum of 256",
+				)
+				for _, err := range errs[1:] {
+					require.Contains(t, err.Error(), `job "test"`)
+				}
+			},
+		},
+	}
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			cfg, err := config.NewConfigFromYAML([]byte(testCase.drakefile))
+			require.NoError(t, err)
+			testCase.assertions(t, validateDrakefile(testCase.project, cfg))
+		})
+	}
+}
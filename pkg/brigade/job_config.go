@@ -0,0 +1,238 @@
+package brigade
+
+import (
+	"encoding/json"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// RetryCondition represents a class of job failure that may be retried.
+type RetryCondition string
+
+const (
+	// RetryOnNonZeroExit represents a job's primary container exiting with a
+	// non-zero exit code for reasons other than having been OOMKilled
+	RetryOnNonZeroExit RetryCondition = "nonZeroExit"
+	// RetryOnEviction represents a job's pod having been evicted from its node
+	RetryOnEviction RetryCondition = "eviction"
+	// RetryOnOOMKilled represents a job's primary container having been killed
+	// for exceeding its memory limit
+	RetryOnOOMKilled RetryCondition = "oomKilled"
+	// RetryOnImagePullError represents failure to pull one of a job's images
+	RetryOnImagePullError RetryCondition = "imagePullError"
+)
+
+// JobConfig represents BrigDrake-specific job configuration that has no home
+// in the DrakeSpec. It is specified at the project level and applies to the
+// job of the same name in any pipeline.
+type JobConfig struct {
+	// Timeout is the maximum amount of time the job may run before it is
+	// considered timed out. A zero value defers to project or worker defaults.
+	Timeout time.Duration
+	// Retry specifies if and how a failed job should be re-attempted.
+	Retry RetryPolicy
+	// Secrets enumerates the project secrets that are exposed to each of the
+	// job's containers as environment variables. Project secrets that aren't
+	// enumerated here are not exposed.
+	Secrets []string
+	// ExternalSecrets maps the names of environment variables that are exposed
+	// to each of the job's containers to keys of existing Kubernetes secrets in
+	// the project's namespace.
+	ExternalSecrets map[string]SecretKeyRef
+	// SecretFiles enumerates secrets that are mounted into each of the job's
+	// containers as files.
+	SecretFiles []SecretFile
+	// Containers holds container-specific configuration, indexed by container
+	// name.
+	Containers map[string]ContainerConfig
+	// Caches enumerates caches that persist between builds and are mounted into
+	// each of the job's containers.
+	Caches []CacheConfig
+	// Artifacts enumerates paths, relative to the pipeline's shared storage, of
+	// files or directories that are copied to the artifact store after the job
+	// succeeds.
+	Artifacts []string
+}
+
+// ContainerConfig represents BrigDrake-specific configuration for a single
+// container of a job. Each field, if non-nil, overrides the corresponding
+// field of the job as a whole. e.g. An empty, non-nil list of secrets exposes
+// no project secrets to the container.
+type ContainerConfig struct {
+	// Secrets enumerates the project secrets that are exposed to the container
+	// as environment variables.
+	Secrets []string `json:"secrets"`
+	// ExternalSecrets maps the names of environment variables that are exposed
+	// to the container to keys of existing Kubernetes secrets in the project's
+	// namespace.
+	ExternalSecrets map[string]SecretKeyRef `json:"externalSecrets"`
+	// SecretFiles enumerates secrets that are mounted into the container as
+	// files.
+	SecretFiles []SecretFile `json:"secretFiles"`
+}
+
+// Container returns the configuration for the named container, with any
+// fields the container doesn't override taken from the job as a whole.
+func (j JobConfig) Container(containerName string) ContainerConfig {
+	containerCfg := j.Containers[containerName]
+	if containerCfg.Secrets == nil {
+		containerCfg.Secrets = j.Secrets
+	}
+	if containerCfg.ExternalSecrets == nil {
+		containerCfg.ExternalSecrets = j.ExternalSecrets
+	}
+	if containerCfg.SecretFiles == nil {
+		containerCfg.SecretFiles = j.SecretFiles
+	}
+	return containerCfg
+}
+
+// externalSecretRefs returns all references to keys of existing Kubernetes
+// secrets made by the job configuration, including those made by
+// container-specific configuration.
+func (j JobConfig) externalSecretRefs() []SecretKeyRef {
+	containerCfgs := []ContainerConfig{
+		{
+			ExternalSecrets: j.ExternalSecrets,
+			SecretFiles:     j.SecretFiles,
+		},
+	}
+	for _, containerCfg := range j.Containers {
+		containerCfgs = append(containerCfgs, containerCfg)
+	}
+	refs := []SecretKeyRef{}
+	for _, containerCfg := range containerCfgs {
+		for _, ref := range containerCfg.ExternalSecrets {
+			refs = append(refs, ref)
+		}
+		for _, secretFile := range containerCfg.SecretFiles {
+			if secretFile.ExternalSecret != nil {
+				refs = append(refs, *secretFile.ExternalSecret)
+			}
+		}
+	}
+	return refs
+}
+
+// WithoutSecrets returns a copy of the job configuration from which all
+// secrets have been removed.
+func (j JobConfig) WithoutSecrets() JobConfig {
+	j.Secrets = nil
+	j.ExternalSecrets = nil
+	j.SecretFiles = nil
+	j.Containers = nil
+	return j
+}
+
+// RetryPolicy represents the conditions under which a failed job should be
+// re-attempted.
+type RetryPolicy struct {
+	// MaxAttempts is the maximum number of times the job will be attempted,
+	// including the first attempt. Values less than 2 disable retries.
+	MaxAttempts int
+	// Backoff is how long to wait before the first retry. The wait doubles with
+	// each subsequent retry.
+	Backoff time.Duration
+	// MaxBackoff caps how long to wait between retries. A zero value means no
+	// cap.
+	MaxBackoff time.Duration
+	// On enumerates the failure conditions that will be retried. If empty, all
+	// conditions are retried.
+	On []RetryCondition
+}
+
+// UnmarshalJSON unmarshals JSON into a JobConfig. It is needed because
+// durations are expressed as strings such as "30m" in JSON.
+func (j *JobConfig) UnmarshalJSON(data []byte) error {
+	type flatJobConfig struct {
+		Timeout         string                     `json:"timeout"`
+		Retry           *RetryPolicy               `json:"retry"`
+		Secrets         []string                   `json:"secrets"`
+		ExternalSecrets map[string]SecretKeyRef    `json:"externalSecrets"`
+		SecretFiles     []SecretFile               `json:"secretFiles"`
+		Containers      map[string]ContainerConfig `json:"containers"`
+		Caches          []CacheConfig              `json:"caches"`
+		Artifacts       []string                   `json:"artifacts"`
+	}
+	flatCfg := flatJobConfig{}
+	if err := json.Unmarshal(data, &flatCfg); err != nil {
+		return err
+	}
+	var err error
+	if j.Timeout, err =
+		parseDurationField("timeout", flatCfg.Timeout); err != nil {
+		return err
+	}
+	if flatCfg.Retry != nil {
+		j.Retry = *flatCfg.Retry
+	}
+	j.Secrets = flatCfg.Secrets
+	j.ExternalSecrets = flatCfg.ExternalSecrets
+	j.SecretFiles = flatCfg.SecretFiles
+	j.Containers = flatCfg.Containers
+	j.Caches = flatCfg.Caches
+	for _, artifact := range flatCfg.Artifacts {
+		cleanArtifact := path.Clean(artifact)
+		if path.IsAbs(cleanArtifact) ||
+			cleanArtifact == "." ||
+			cleanArtifact == ".." ||
+			strings.HasPrefix(cleanArtifact, "../") {
+			return errors.Errorf(
+				"artifact path %q is not a relative path within shared storage",
+				artifact,
+			)
+		}
+		j.Artifacts = append(j.Artifacts, cleanArtifact)
+	}
+	return nil
+}
+
+// UnmarshalJSON unmarshals JSON into a RetryPolicy. It is needed because
+// durations are expressed as strings such as "30s" in JSON.
+func (r *RetryPolicy) UnmarshalJSON(data []byte) error {
+	type flatRetryPolicy struct {
+		MaxAttempts int              `json:"maxAttempts"`
+		Backoff     string           `json:"backoff"`
+		MaxBackoff  string           `json:"maxBackoff"`
+		On          []RetryCondition `json:"on"`
+	}
+	flatPolicy := flatRetryPolicy{}
+	if err := json.Unmarshal(data, &flatPolicy); err != nil {
+		return err
+	}
+	r.MaxAttempts = flatPolicy.MaxAttempts
+	r.On = flatPolicy.On
+	for _, condition := range r.On {
+		switch condition {
+		case RetryOnNonZeroExit,
+			RetryOnEviction,
+			RetryOnOOMKilled,
+			RetryOnImagePullError:
+		default:
+			return errors.Errorf("unrecognized retry condition %q", condition)
+		}
+	}
+	var err error
+	if r.Backoff, err =
+		parseDurationField("backoff", flatPolicy.Backoff); err != nil {
+		return err
+	}
+	r.MaxBackoff, err = parseDurationField("maxBackoff", flatPolicy.MaxBackoff)
+	return err
+}
+
+// parseDurationField parses a duration such as "10m" that was specified as a
+// string in JSON. An empty value yields a zero duration.
+func parseDurationField(fieldName, value string) (time.Duration, error) {
+	if value == "" {
+		return 0, nil
+	}
+	duration, err := time.ParseDuration(value)
+	if err != nil {
+		return 0, errors.Wrapf(err, "error parsing %s %q", fieldName, value)
+	}
+	return duration, nil
+}
@@ -0,0 +1,59 @@
+package brigade
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestJobConfigUnmarshalJSONArtifacts(t *testing.T) {
+	testCases := []struct {
+		name       string
+		json       string
+		assertions func(*testing.T, JobConfig, error)
+	}{
+		{
+			name: "valid artifacts",
+			json: `{"artifacts":["bin/","reports/coverage.html"]}`,
+			assertions: func(t *testing.T, jobCfg JobConfig, err error) {
+				require.NoError(t, err)
+				require.Equal(
+					t,
+					[]string{"bin", "reports/coverage.html"},
+					jobCfg.Artifacts,
+				)
+			},
+		},
+		{
+			name: "absolute artifact path",
+			json: `{"artifacts":["/etc/passwd"]}`,
+			assertions: func(t *testing.T, _ JobConfig, err error) {
+				require.Error(t, err)
+				require.Contains(t, err.Error(), "not a relative path")
+			},
+		},
+		{
+			name: "artifact path outside shared storage",
+			json: `{"artifacts":["bin/../../etc"]}`,
+			assertions: func(t *testing.T, _ JobConfig, err error) {
+				require.Error(t, err)
+				require.Contains(t, err.Error(), "not a relative path")
+			},
+		},
+		{
+			name: "entire shared storage",
+			json: `{"artifacts":["."]}`,
+			assertions: func(t *testing.T, _ JobConfig, err error) {
+				require.Error(t, err)
+			},
+		},
+	}
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			jobCfg := JobConfig{}
+			err := json.Unmarshal([]byte(testCase.json), &jobCfg)
+			testCase.assertions(t, jobCfg, err)
+		})
+	}
+}
@@ -2,9 +2,14 @@ package brigade
 
 import (
 	"encoding/json"
+	"sort"
 	"strings"
+	"time"
 
 	"github.com/kelseyhightower/envconfig"
+	"github.com/pkg/errors"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/client-go/kubernetes"
 )
@@ -29,6 +34,32 @@ type Project struct {
 	Secrets             map[string]string
 	AllowPrivilegedJobs bool
 	AllowHostMounts     bool
+	// AllowSecretsForForks indicates whether secrets may be exposed to jobs
+	// executed on behalf of events pertaining to changes from a fork of the
+	// project's repository, e.g. pull requests from forks.
+	AllowSecretsForForks bool
+	// AllowSecretsForExternalAuthors indicates whether secrets may be exposed
+	// to jobs executed on behalf of events authored by someone who is not a
+	// member of or collaborator on the project's repository.
+	AllowSecretsForExternalAuthors bool
+	// JobTimeout is the project's default for how long a job may run. A zero
+	// value defers to the worker's default.
+	JobTimeout time.Duration
+	// PipelineTimeout is the project's default for how long a pipeline may run,
+	// in total. A zero value defers to the worker's default.
+	PipelineTimeout time.Duration
+	// CacheMaxAge is how long a cache may go unused before it is evicted. A
+	// zero value means caches are never evicted on account of their age.
+	CacheMaxAge time.Duration
+	// CacheMaxSize caps the total storage requested by all of the project's
+	// caches. When exceeded, the least recently used caches are evicted. A nil
+	// value means no cap.
+	CacheMaxSize *resource.Quantity
+	// Jobs holds BrigDrake-specific job configuration, indexed by job name.
+	Jobs map[string]JobConfig
+	// KashtiURL is the base URL of the Kashti dashboard, if any, that build
+	// details can be linked to.
+	KashtiURL string
 }
 
 // Repository represents VCS-related projects configuration.
@@ -50,6 +81,7 @@ type KubernetesConfig struct {
 	VCSSidecarResourcesRequestsMemory string
 	BuildStorageSize                  string
 	BuildStorageClass                 string
+	CacheStorageClass                 string
 	ServiceAccount                    string
 	ImagePullSecrets                  []string
 }
@@ -71,6 +103,28 @@ func GetProjectFromEnvironmentAndSecret(
 	if err != nil {
 		return Project{}, err
 	}
+	p, err := NewProjectFromSecret(projectSecret, internalP.ServiceAccount)
+	if err != nil {
+		return p, err
+	}
+	if err = validateExternalSecretRefs(
+		kubeClient,
+		p.Kubernetes.Namespace,
+		p.ID,
+		p.Jobs,
+	); err != nil {
+		return p, errors.Wrap(err, "error validating project jobs")
+	}
+	return p, nil
+}
+
+// NewProjectFromSecret returns a Project object with values derived from the
+// given project-specific Kubernetes secret. References the project's jobs make
+// to other Kubernetes secrets are not validated.
+func NewProjectFromSecret(
+	projectSecret *v1.Secret,
+	serviceAccount string,
+) (Project, error) {
 	// nolint: lll
 	p := Project{
 		ID:   projectSecret.GetName(),
@@ -78,13 +132,14 @@ func GetProjectFromEnvironmentAndSecret(
 		Kubernetes: KubernetesConfig{
 			Namespace:                         projectSecret.GetNamespace(),
 			BuildStorageSize:                  string(projectSecret.Data["buildStorageSize"]),
-			ServiceAccount:                    internalP.ServiceAccount,
+			ServiceAccount:                    serviceAccount,
 			VCSSidecar:                        string(projectSecret.Data["vcsSidecar"]),
 			VCSSidecarResourcesLimitsCPU:      string(projectSecret.Data["vcsSidecarResources.limits.cpu"]),
 			VCSSidecarResourcesLimitsMemory:   string(projectSecret.Data["vcsSidecarResources.limits.memory"]),
 			VCSSidecarResourcesRequestsCPU:    string(projectSecret.Data["vcsSidecarResources.requests.cpu"]),
 			VCSSidecarResourcesRequestsMemory: string(projectSecret.Data["vcsSidecarResources.requests.memory"]),
 			BuildStorageClass:                 string(projectSecret.Data["kubernetes.buildStorageClass"]),
+			CacheStorageClass:                 string(projectSecret.Data["kubernetes.cacheStorageClass"]),
 			ImagePullSecrets:                  strings.Split(string(projectSecret.Data["imagePullSecrets"]), ","),
 		},
 		Repo: Repository{
@@ -94,18 +149,125 @@ func GetProjectFromEnvironmentAndSecret(
 			SSHKey:            string(projectSecret.Data["sshKey"]),
 			Token:             string(projectSecret.Data["github.token"]),
 		},
-		Secrets:             map[string]string{},
-		AllowPrivilegedJobs: string(projectSecret.Data["allowPrivilegedJobs"]) == "true",
-		AllowHostMounts:     string(projectSecret.Data["allowHostMounts"]) == "true",
+		Secrets:                        map[string]string{},
+		Jobs:                           map[string]JobConfig{},
+		AllowPrivilegedJobs:            string(projectSecret.Data["allowPrivilegedJobs"]) == "true",
+		AllowHostMounts:                string(projectSecret.Data["allowHostMounts"]) == "true",
+		KashtiURL:                      string(projectSecret.Data["kashtiURL"]),
+		AllowSecretsForForks:           string(projectSecret.Data["allowSecretsForForks"]) == "true",
+		AllowSecretsForExternalAuthors: string(projectSecret.Data["allowSecretsForExternalAuthors"]) == "true",
 	}
 	if p.Kubernetes.BuildStorageSize == "" {
 		p.Kubernetes.BuildStorageSize = "50Mi"
 	}
+	var err error
+	if p.JobTimeout, err = parseOptionalDuration(
+		projectSecret.Data["jobTimeout"],
+	); err != nil {
+		return p, errors.Wrap(err, "error parsing project jobTimeout")
+	}
+	if p.PipelineTimeout, err = parseOptionalDuration(
+		projectSecret.Data["pipelineTimeout"],
+	); err != nil {
+		return p, errors.Wrap(err, "error parsing project pipelineTimeout")
+	}
+	if p.CacheMaxAge, err = parseOptionalDuration(
+		projectSecret.Data["cacheMaxAge"],
+	); err != nil {
+		return p, errors.Wrap(err, "error parsing project cacheMaxAge")
+	}
+	if cacheMaxSize := projectSecret.Data["cacheMaxSize"]; len(cacheMaxSize) > 0 {
+		quantity, qerr := resource.ParseQuantity(string(cacheMaxSize))
+		if qerr != nil {
+			return p, errors.Wrap(qerr, "error parsing project cacheMaxSize")
+		}
+		p.CacheMaxSize = &quantity
+	}
 	secretsBytes, ok := projectSecret.Data["secrets"]
 	if ok {
 		if ierr := json.Unmarshal(secretsBytes, &p.Secrets); ierr != nil {
 			return p, ierr
 		}
 	}
+	jobsBytes, ok := projectSecret.Data["jobs"]
+	if ok {
+		if ierr := json.Unmarshal(jobsBytes, &p.Jobs); ierr != nil {
+			return p, errors.Wrap(ierr, "error parsing project jobs")
+		}
+	}
 	return p, nil
 }
+
+// validateExternalSecretRefs verifies that every key of an existing Kubernetes
+// secret that is referenced by the given job configuration exists. The
+// project's own secret may not be referenced, as it contains credentials that
+// are never meant to be exposed to jobs.
+func validateExternalSecretRefs(
+	kubeClient kubernetes.Interface,
+	namespace string,
+	projectSecretName string,
+	jobs map[string]JobConfig,
+) error {
+	jobNames := make([]string, 0, len(jobs))
+	for jobName := range jobs {
+		jobNames = append(jobNames, jobName)
+	}
+	sort.Strings(jobNames)
+	secrets := map[string]*v1.Secret{}
+	for _, jobName := range jobNames {
+		for _, ref := range jobs[jobName].externalSecretRefs() {
+			if ref.Name == "" || ref.Key == "" {
+				return errors.Errorf(
+					"job %q references an external secret without specifying a name "+
+						"and a key",
+					jobName,
+				)
+			}
+			if ref.Name == projectSecretName {
+				return errors.Errorf(
+					"job %q references the project's own secret %q",
+					jobName,
+					ref.Name,
+				)
+			}
+			secret, ok := secrets[ref.Name]
+			if !ok {
+				var err error
+				if secret, err = kubeClient.CoreV1().Secrets(namespace).Get(
+					ref.Name,
+					metav1.GetOptions{},
+				); err != nil {
+					return errors.Wrapf(
+						err,
+						"error getting secret %q referenced by job %q",
+						ref.Name,
+						jobName,
+					)
+				}
+				secrets[ref.Name] = secret
+			}
+			_, ok = secret.Data[ref.Key]
+			if !ok {
+				_, ok = secret.StringData[ref.Key]
+			}
+			if !ok {
+				return errors.Errorf(
+					"job %q references key %q of secret %q, but that key does not exist",
+					jobName,
+					ref.Key,
+					ref.Name,
+				)
+			}
+		}
+	}
+	return nil
+}
+
+// parseOptionalDuration parses a duration such as "10m" from the raw value of
+// a project secret field. An empty value yields a zero duration.
+func parseOptionalDuration(value []byte) (time.Duration, error) {
+	if len(value) == 0 {
+		return 0, nil
+	}
+	return time.ParseDuration(string(value))
+}
@@ -0,0 +1,197 @@
+package brigade
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func TestNewProjectFromSecret(t *testing.T) {
+	testCases := []struct {
+		name       string
+		data       map[string][]byte
+		assertions func(*testing.T, Project, error)
+	}{
+		{
+			name: "defaults",
+			data: map[string][]byte{},
+			assertions: func(t *testing.T, project Project, err error) {
+				require.NoError(t, err)
+				require.Equal(t, "brigade-foo", project.ID)
+				require.Equal(t, "bar", project.Kubernetes.Namespace)
+				require.Equal(t, "brigade-worker", project.Kubernetes.ServiceAccount)
+				require.Equal(t, "50Mi", project.Kubernetes.BuildStorageSize)
+				require.Empty(t, project.Secrets)
+				require.Empty(t, project.Jobs)
+			},
+		},
+		{
+			name: "jobs, secrets, and timeouts",
+			data: map[string][]byte{
+				"allowPrivilegedJobs": []byte("true"),
+				"jobTimeout":          []byte("5m"),
+				"secrets":             []byte(`{"FOO":"bar"}`),
+				"jobs":                []byte(`{"test":{"secrets":["FOO"]}}`),
+			},
+			assertions: func(t *testing.T, project Project, err error) {
+				require.NoError(t, err)
+				require.True(t, project.AllowPrivilegedJobs)
+				require.Equal(t, 5*time.Minute, project.JobTimeout)
+				require.Equal(t, map[string]string{"FOO": "bar"}, project.Secrets)
+				require.Equal(t, []string{"FOO"}, project.Jobs["test"].Secrets)
+			},
+		},
+		{
+			name: "invalid timeout",
+			data: map[string][]byte{
+				"pipelineTimeout": []byte("soon"),
+			},
+			assertions: func(t *testing.T, _ Project, err error) {
+				require.Error(t, err)
+				require.Contains(
+					t,
+					err.Error(),
+					"error parsing project pipelineTimeout",
+				)
+			},
+		},
+	}
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			project, err := NewProjectFromSecret(
+				&v1.Secret{
+					ObjectMeta: metav1.ObjectMeta{
+						Name:      "brigade-foo",
+						Namespace: "bar",
+					},
+					Data: testCase.data,
+				},
+				"brigade-worker",
+			)
+			testCase.assertions(t, project, err)
+		})
+	}
+}
+
+func TestValidateExternalSecretRefs(t *testing.T) {
+	const testNamespace = "foo"
+	testCases := []struct {
+		name       string
+		jobs       map[string]JobConfig
+		assertions func(*testing.T, error)
+	}{
+		{
+			name: "no references",
+			jobs: map[string]JobConfig{
+				"test": {},
+			},
+			assertions: func(t *testing.T, err error) {
+				require.NoError(t, err)
+			},
+		},
+		{
+			name: "valid references",
+			jobs: map[string]JobConfig{
+				"deploy": {
+					ExternalSecrets: map[string]SecretKeyRef{
+						"TOKEN": {Name: "deploy-credentials", Key: "token"},
+					},
+					Containers: map[string]ContainerConfig{
+						"helm": {
+							SecretFiles: []SecretFile{
+								{
+									ExternalSecret: &SecretKeyRef{
+										Name: "deploy-credentials",
+										Key:  "kubeconfig",
+									},
+									Path: "/root/.kube/config",
+								},
+							},
+						},
+					},
+				},
+			},
+			assertions: func(t *testing.T, err error) {
+				require.NoError(t, err)
+			},
+		},
+		{
+			name: "reference to non-existent secret",
+			jobs: map[string]JobConfig{
+				"deploy": {
+					ExternalSecrets: map[string]SecretKeyRef{
+						"TOKEN": {Name: "nope", Key: "token"},
+					},
+				},
+			},
+			assertions: func(t *testing.T, err error) {
+				require.Error(t, err)
+				require.Contains(t, err.Error(), `error getting secret "nope"`)
+			},
+		},
+		{
+			name: "reference to non-existent key",
+			jobs: map[string]JobConfig{
+				"deploy": {
+					ExternalSecrets: map[string]SecretKeyRef{
+						"TOKEN": {Name: "deploy-credentials", Key: "nope"},
+					},
+				},
+			},
+			assertions: func(t *testing.T, err error) {
+				require.Error(t, err)
+				require.Contains(t, err.Error(), "that key does not exist")
+			},
+		},
+		{
+			name: "reference to project secret",
+			jobs: map[string]JobConfig{
+				"deploy": {
+					ExternalSecrets: map[string]SecretKeyRef{
+						"SECRETS": {Name: "brigade-123", Key: "secrets"},
+					},
+				},
+			},
+			assertions: func(t *testing.T, err error) {
+				require.Error(t, err)
+				require.Contains(t, err.Error(), "project's own secret")
+			},
+		},
+	}
+	kubeClient := fake.NewSimpleClientset(
+		&v1.Secret{
+			ObjectMeta: metav1.ObjectMeta{
+				Namespace: testNamespace,
+				Name:      "deploy-credentials",
+			},
+			Data: map[string][]byte{
+				"token":      []byte("foo"),
+				"kubeconfig": []byte("bar"),
+			},
+		},
+		&v1.Secret{
+			ObjectMeta: metav1.ObjectMeta{
+				Namespace: testNamespace,
+				Name:      "brigade-123",
+			},
+			Data: map[string][]byte{
+				"secrets": []byte("{}"),
+			},
+		},
+	)
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			err := validateExternalSecretRefs(
+				kubeClient,
+				testNamespace,
+				"brigade-123",
+				testCase.jobs,
+			)
+			testCase.assertions(t, err)
+		})
+	}
+}
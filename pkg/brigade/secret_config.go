@@ -0,0 +1,85 @@
+package brigade
+
+import (
+	"encoding/json"
+	"os"
+	"path"
+	"strconv"
+
+	"github.com/pkg/errors"
+)
+
+// SecretKeyRef references a key of an existing Kubernetes secret in the
+// project's namespace.
+type SecretKeyRef struct {
+	// Name is the name of the Kubernetes secret.
+	Name string `json:"name"`
+	// Key is the key within the Kubernetes secret.
+	Key string `json:"key"`
+}
+
+// SecretFile represents a secret that is mounted into a job container as a
+// file. Exactly one of Secret or ExternalSecret must be specified.
+type SecretFile struct {
+	// Secret is the name of a project secret.
+	Secret string
+	// ExternalSecret references a key of an existing Kubernetes secret in the
+	// project's namespace.
+	ExternalSecret *SecretKeyRef
+	// Path is the absolute path at which the file is mounted.
+	Path string
+	// Mode is the file's permission bits. A nil value defers to the Kubernetes
+	// default of 0644.
+	Mode *os.FileMode
+}
+
+// UnmarshalJSON unmarshals JSON into a SecretFile. It is needed because file
+// modes are expressed as octal strings such as "0400" in JSON.
+func (s *SecretFile) UnmarshalJSON(data []byte) error {
+	type flatSecretFile struct {
+		Secret         string        `json:"secret"`
+		ExternalSecret *SecretKeyRef `json:"externalSecret"`
+		Path           string        `json:"path"`
+		Mode           string        `json:"mode"`
+	}
+	flatFile := flatSecretFile{}
+	if err := json.Unmarshal(data, &flatFile); err != nil {
+		return err
+	}
+	if (flatFile.Secret == "") == (flatFile.ExternalSecret == nil) {
+		return errors.Errorf(
+			"secret file %q must specify exactly one of secret or externalSecret",
+			flatFile.Path,
+		)
+	}
+	if flatFile.ExternalSecret != nil &&
+		(flatFile.ExternalSecret.Name == "" || flatFile.ExternalSecret.Key == "") {
+		return errors.Errorf(
+			"external secret for secret file %q must specify a name and a key",
+			flatFile.Path,
+		)
+	}
+	if !path.IsAbs(flatFile.Path) || path.Clean(flatFile.Path) == "/" {
+		return errors.Errorf(
+			"secret file path %q is not an absolute path to a file",
+			flatFile.Path,
+		)
+	}
+	s.Secret = flatFile.Secret
+	s.ExternalSecret = flatFile.ExternalSecret
+	s.Path = path.Clean(flatFile.Path)
+	if flatFile.Mode != "" {
+		mode, err := strconv.ParseUint(flatFile.Mode, 8, 32)
+		if err != nil || mode > 0777 {
+			return errors.Errorf(
+				"secret file %q has invalid mode %q; an octal value no greater "+
+					"than 0777 is required",
+				s.Path,
+				flatFile.Mode,
+			)
+		}
+		fileMode := os.FileMode(mode)
+		s.Mode = &fileMode
+	}
+	return nil
+}
@@ -0,0 +1,90 @@
+package brigade
+
+import (
+	"encoding/json"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestSecretFileUnmarshalJSON(t *testing.T) {
+	testCases := []struct {
+		name       string
+		json       string
+		assertions func(*testing.T, SecretFile, error)
+	}{
+		{
+			name: "project secret",
+			json: `{"secret":"GPG_KEY","path":"/root/.gnupg/key.asc","mode":"0400"}`,
+			assertions: func(t *testing.T, secretFile SecretFile, err error) {
+				require.NoError(t, err)
+				require.Equal(t, "GPG_KEY", secretFile.Secret)
+				require.Nil(t, secretFile.ExternalSecret)
+				require.Equal(t, "/root/.gnupg/key.asc", secretFile.Path)
+				require.NotNil(t, secretFile.Mode)
+				require.Equal(t, os.FileMode(0400), *secretFile.Mode)
+			},
+		},
+		{
+			name: "external secret",
+			json: `{"externalSecret":{"name":"foo","key":"bar"},"path":"/etc/bar"}`,
+			assertions: func(t *testing.T, secretFile SecretFile, err error) {
+				require.NoError(t, err)
+				require.Equal(
+					t,
+					&SecretKeyRef{Name: "foo", Key: "bar"},
+					secretFile.ExternalSecret,
+				)
+				require.Nil(t, secretFile.Mode)
+			},
+		},
+		{
+			name: "neither project secret nor external secret",
+			json: `{"path":"/etc/bar"}`,
+			assertions: func(t *testing.T, _ SecretFile, err error) {
+				require.Error(t, err)
+				require.Contains(t, err.Error(), "exactly one of")
+			},
+		},
+		{
+			name: "both project secret and external secret",
+			json: `{"secret":"FOO","externalSecret":{"name":"foo","key":"bar"},"path":"/etc/bar"}`, // nolint: lll
+			assertions: func(t *testing.T, _ SecretFile, err error) {
+				require.Error(t, err)
+				require.Contains(t, err.Error(), "exactly one of")
+			},
+		},
+		{
+			name: "external secret without key",
+			json: `{"externalSecret":{"name":"foo"},"path":"/etc/bar"}`,
+			assertions: func(t *testing.T, _ SecretFile, err error) {
+				require.Error(t, err)
+				require.Contains(t, err.Error(), "must specify a name and a key")
+			},
+		},
+		{
+			name: "relative path",
+			json: `{"secret":"FOO","path":"etc/bar"}`,
+			assertions: func(t *testing.T, _ SecretFile, err error) {
+				require.Error(t, err)
+				require.Contains(t, err.Error(), "not an absolute path")
+			},
+		},
+		{
+			name: "invalid mode",
+			json: `{"secret":"FOO","path":"/etc/bar","mode":"0999"}`,
+			assertions: func(t *testing.T, _ SecretFile, err error) {
+				require.Error(t, err)
+				require.Contains(t, err.Error(), "invalid mode")
+			},
+		},
+	}
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			secretFile := SecretFile{}
+			err := json.Unmarshal([]byte(testCase.json), &secretFile)
+			testCase.assertions(t, secretFile, err)
+		})
+	}
+}
@@ -1,6 +1,8 @@
 package brigade
 
 import (
+	"time"
+
 	"github.com/kelseyhightower/envconfig"
 )
 
@@ -8,13 +10,53 @@ import (
 // controller when it launches the worker.
 type WorkerConfig struct {
 	DefaultBuildStorageClass string `envconfig:"BRIGADE_DEFAULT_BUILD_STORAGE_CLASS"` // nolint: lll
+	DefaultCacheStorageClass string `envconfig:"BRIGADE_DEFAULT_CACHE_STORAGE_CLASS"` // nolint: lll
+	// DefaultJobTimeout is how long a job may run when neither the job nor the
+	// project specifies a timeout. A zero value means no timeout.
+	DefaultJobTimeout time.Duration `envconfig:"BRIGDRAKE_DEFAULT_JOB_TIMEOUT"` // nolint: lll
+	// DefaultPipelineTimeout is how long a pipeline may run, in total, when the
+	// project doesn't specify a timeout. A zero value means no deadline.
+	DefaultPipelineTimeout time.Duration `envconfig:"BRIGDRAKE_DEFAULT_PIPELINE_TIMEOUT"` // nolint: lll
+	// JobLogSinks enumerates where the complete logs of every job's containers
+	// should be streamed to. Supported values are "stdout" and "file". By
+	// default, there are none and job logs are not streamed.
+	JobLogSinks []string `envconfig:"BRIGDRAKE_JOB_LOG_SINKS"`
+	// JobLogsDir is the directory beneath which the "file" job log sink writes
+	// logs. It must be on a volume, e.g. a persistent volume, that is mounted
+	// into the worker's pod.
+	JobLogsDir string `envconfig:"BRIGDRAKE_JOB_LOGS_DIR"`
+	// ArtifactStore is where artifacts collected from jobs are stored. Supported
+	// values are "pvc" and "s3". An empty value means artifacts aren't
+	// collected.
+	ArtifactStore string `envconfig:"BRIGDRAKE_ARTIFACT_STORE"`
+	// ArtifactCollectorImage overrides the image used to collect artifacts. The
+	// default depends on the artifact store.
+	ArtifactCollectorImage string `envconfig:"BRIGDRAKE_ARTIFACT_COLLECTOR_IMAGE"` // nolint: lll
+	// ArtifactsURL is the base URL at which stored artifacts can be found, if
+	// any. For the "s3" artifact store, this defaults to the bucket's URL.
+	ArtifactsURL string `envconfig:"BRIGDRAKE_ARTIFACTS_URL"`
+	// ArtifactsPVC is the name of the PVC, in the project's namespace, in which
+	// the "pvc" artifact store stores artifacts.
+	ArtifactsPVC string `envconfig:"BRIGDRAKE_ARTIFACTS_PVC"`
+	// ArtifactsS3Endpoint is the URL of the S3-compatible endpoint to which the
+	// "s3" artifact store uploads artifacts.
+	ArtifactsS3Endpoint string `envconfig:"BRIGDRAKE_ARTIFACTS_S3_ENDPOINT"`
+	// ArtifactsS3Bucket is the bucket to which the "s3" artifact store uploads
+	// artifacts.
+	ArtifactsS3Bucket string `envconfig:"BRIGDRAKE_ARTIFACTS_S3_BUCKET"`
+	// ArtifactsS3CredentialsSecret is the name of a secret, in the project's
+	// namespace, having accessKeyID and secretAccessKey keys that the "s3"
+	// artifact store uses to authenticate to the endpoint.
+	ArtifactsS3CredentialsSecret string `envconfig:"BRIGDRAKE_ARTIFACTS_S3_CREDENTIALS_SECRET"` // nolint: lll
 }
 
 // NewWorkerConfigWithDefaults returns a WorkerConfig object with default values
 // already applied. Callers are then free to set custom values for the remaining
 // fields and/or override default values.
 func NewWorkerConfigWithDefaults() WorkerConfig {
-	return WorkerConfig{}
+	return WorkerConfig{
+		DefaultJobTimeout: 10 * time.Minute,
+	}
 }
 
 // GetWorkerConfigFromEnvironment returns a WorkerConfig object with values
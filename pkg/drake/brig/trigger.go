@@ -1,9 +1,6 @@
 package brig
 
 import (
-	"encoding/json"
-	"log"
-
 	"github.com/lovethedrake/brigdrake/pkg/brigade"
 	"github.com/lovethedrake/brigdrake/pkg/drake"
 )
@@ -12,33 +9,51 @@ type trigger struct {
 	EventTypes []string `json:"eventTypes"`
 }
 
+// specURI identifies the spec that this package's Trigger implements.
+const specURI = "github.com/lovethedrake/drakespec-brig"
+
+func init() {
+	drake.RegisterTrigger(specURI, ">=1.0.0 <2.0.0", NewTriggerFromJSON)
+}
+
 // NewTriggerFromJSON takes a slice of bytes containing JSON as an argument and
 // returns a Trigger that implements the
-// github.com/lovethedrake/drakespec-github spec.
+// github.com/lovethedrake/drakespec-brig spec.
 func NewTriggerFromJSON(jsonBytes []byte) (drake.Trigger, error) {
 	t := &trigger{}
-	err := json.Unmarshal(jsonBytes, t)
+	err := drake.DecodeTriggerConfig(jsonBytes, t)
 	return t, err
 }
 
-func (t *trigger) Matches(event brigade.Event) (bool, error) {
+func (t *trigger) Matches(
+	_ brigade.Project,
+	event brigade.Event,
+) (drake.MatchResult, error) {
 	if event.Provider != "brigade-cli" {
-		log.Printf(
-			"event from provider %q does not match brig trigger",
+		return drake.NoMatch(
+			"",
+			"event from provider %q is not handled by brig trigger",
 			event.Provider,
-		)
-		return false, nil
+		), nil
 	}
 
 	for _, eventType := range t.EventTypes {
 		if event.Type == eventType {
-			log.Printf("%q event matches trigger", event.Type)
-			return true, nil
+			return drake.Match(
+				"eventTypes",
+				"event type %q in %v",
+				event.Type,
+				t.EventTypes,
+			), nil
 		}
 	}
 
-	log.Printf("%q event does not match trigger", event.Type)
-	return false, nil
+	return drake.NoMatch(
+		"eventTypes",
+		"event type %q not in %v",
+		event.Type,
+		t.EventTypes,
+	), nil
 }
 
 func (t *trigger) JobStatusNotifier(
@@ -58,8 +58,11 @@ func TestMatches(t *testing.T) {
 
 	for _, testCase := range testCases {
 		t.Run(testCase.name, func(t *testing.T) {
-			matches, err := testCase.trigger.Matches(testCase.event)
-			testCase.assertions(t, matches, err)
+			result, err := testCase.trigger.Matches(
+				brigade.Project{},
+				testCase.event,
+			)
+			testCase.assertions(t, result.Matched, err)
 		})
 	}
 }
@@ -0,0 +1,172 @@
+package cron
+
+import (
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// macros maps the non-standard cron macros to equivalent, standard
+// expressions.
+var macros = map[string]string{
+	"@yearly":   "0 0 1 1 *",
+	"@annually": "0 0 1 1 *",
+	"@monthly":  "0 0 1 * *",
+	"@weekly":   "0 0 * * 0",
+	"@daily":    "0 0 * * *",
+	"@midnight": "0 0 * * *",
+	"@hourly":   "0 * * * *",
+}
+
+// cronField describes the permissible values for one of the five fields of a
+// cron expression.
+type cronField struct {
+	name  string
+	min   int
+	max   int
+	names map[string]int
+	// aliases maps values to the equivalent values they're normalized to when
+	// they stand alone. Values that bound a range are left as they are, since
+	// replacing them could invert the range.
+	aliases map[int]int
+}
+
+var cronFields = []cronField{
+	{name: "minute", min: 0, max: 59},
+	{name: "hour", min: 0, max: 23},
+	{name: "day of month", min: 1, max: 31},
+	{
+		name: "month",
+		min:  1,
+		max:  12,
+		names: map[string]int{
+			"JAN": 1, "FEB": 2, "MAR": 3, "APR": 4, "MAY": 5, "JUN": 6,
+			"JUL": 7, "AUG": 8, "SEP": 9, "OCT": 10, "NOV": 11, "DEC": 12,
+		},
+	},
+	{
+		// 7 is permitted as an alternative to 0 for Sunday
+		name: "day of week",
+		min:  0,
+		max:  7,
+		names: map[string]int{
+			"SUN": 0, "MON": 1, "TUE": 2, "WED": 3, "THU": 4, "FRI": 5, "SAT": 6,
+		},
+		aliases: map[int]int{7: 0},
+	},
+}
+
+type schedule struct {
+	Name       string `json:"name"`
+	Expression string `json:"expression"`
+	Timezone   string `json:"timezone"`
+}
+
+// validate returns an error if the schedule is missing both a name and
+// an expression, or if its expression or timezone are invalid.
+func (s *schedule) validate() error {
+	if s.Name == "" && s.Expression == "" {
+		return errors.New("schedule must specify a name, an expression, or both")
+	}
+	if s.Expression != "" {
+		if _, err := normalizeExpression(s.Expression); err != nil {
+			return err
+		}
+	}
+	if _, err := time.LoadLocation(s.Timezone); err != nil {
+		return errors.Wrapf(err, "error loading timezone %q", s.Timezone)
+	}
+	return nil
+}
+
+// normalizeExpression validates a cron expression and returns it in a
+// normalized form, suitable for comparison to other normalized expressions.
+// Macros are expanded to their equivalent expressions, names of months and
+// days are replaced with numbers, and fields are separated by single spaces.
+func normalizeExpression(expression string) (string, error) {
+	expression = strings.TrimSpace(expression)
+	if expandedExpression, ok := macros[strings.ToLower(expression)]; ok {
+		expression = expandedExpression
+	}
+	fields := strings.Fields(expression)
+	if len(fields) != len(cronFields) {
+		return "", errors.Errorf(
+			"cron expression %q does not have exactly %d fields",
+			expression,
+			len(cronFields),
+		)
+	}
+	for i, field := range fields {
+		normalizedField, err := cronFields[i].normalize(field)
+		if err != nil {
+			return "", errors.Wrapf(err, "invalid cron expression %q", expression)
+		}
+		fields[i] = normalizedField
+	}
+	return strings.Join(fields, " "), nil
+}
+
+// normalize validates a single field of a cron expression, which may be a
+// comma-delimited list of values, ranges, and steps, and returns it with any
+// names replaced by their numeric equivalents and any values that stand alone
+// replaced by the values they're aliases for.
+func (c cronField) normalize(field string) (string, error) {
+	items := strings.Split(field, ",")
+	for i, item := range items {
+		rangeAndStep := strings.SplitN(item, "/", 2)
+		if len(rangeAndStep) == 2 {
+			step, err := strconv.Atoi(rangeAndStep[1])
+			if err != nil || step < 1 {
+				return "", errors.Errorf("invalid step %q in %s field", item, c.name)
+			}
+		}
+		rng := rangeAndStep[0]
+		if rng != "*" {
+			bounds := strings.SplitN(rng, "-", 2)
+			for j, bound := range bounds {
+				value, err := c.parseValue(bound)
+				if err != nil {
+					return "", err
+				}
+				if alias, ok := c.aliases[value]; ok && len(bounds) == 1 {
+					value = alias
+				}
+				bounds[j] = strconv.Itoa(value)
+			}
+			if len(bounds) == 2 {
+				low, _ := strconv.Atoi(bounds[0])
+				high, _ := strconv.Atoi(bounds[1])
+				if low > high {
+					return "", errors.Errorf("invalid range %q in %s field", rng, c.name)
+				}
+			}
+			rangeAndStep[0] = strings.Join(bounds, "-")
+		}
+		items[i] = strings.Join(rangeAndStep, "/")
+	}
+	return strings.Join(items, ","), nil
+}
+
+// parseValue parses a single value, which may be a number or a name, and
+// verifies that it is within the field's permissible range.
+func (c cronField) parseValue(value string) (int, error) {
+	if number, ok := c.names[strings.ToUpper(value)]; ok {
+		return number, nil
+	}
+	number, err := strconv.Atoi(value)
+	if err != nil {
+		return 0, errors.Errorf("invalid value %q in %s field", value, c.name)
+	}
+	if number < c.min || number > c.max {
+		return 0, errors.Errorf(
+			"value %d in %s field is outside the range %d-%d",
+			number,
+			c.name,
+			c.min,
+			c.max,
+		)
+	}
+	return number, nil
+}
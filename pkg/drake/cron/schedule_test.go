@@ -0,0 +1,42 @@
+package cron
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestNormalizeExpression(t *testing.T) {
+	testCases := []struct {
+		expression string
+		expected   string
+		errMsg     string
+	}{
+		{expression: "* * * * *", expected: "* * * * *"},
+		{expression: " @hourly ", expected: "0 * * * *"},
+		{expression: "*/15 0-6/2 1,15 * *", expected: "*/15 0-6/2 1,15 * *"},
+		{expression: "0 0 * dec sun", expected: "0 0 * 12 0"},
+		{expression: "0 0 * * 7", expected: "0 0 * * 0"},
+		{expression: "0 0 * * 1,7", expected: "0 0 * * 1,0"},
+		{expression: "0 0 * * 5-7", expected: "0 0 * * 5-7"},
+		{expression: "0 0 * * 8", errMsg: "outside the range"},
+		{expression: "0 0 * *", errMsg: "exactly 5 fields"},
+		{expression: "60 * * * *", errMsg: "outside the range"},
+		{expression: "0 0 0 * *", errMsg: "outside the range"},
+		{expression: "*/0 * * * *", errMsg: "invalid step"},
+		{expression: "0 5-1 * * *", errMsg: "invalid range"},
+		{expression: "0 0 * FOO *", errMsg: "invalid value"},
+	}
+	for _, testCase := range testCases {
+		t.Run(testCase.expression, func(t *testing.T) {
+			normalized, err := normalizeExpression(testCase.expression)
+			if testCase.errMsg != "" {
+				require.Error(t, err)
+				require.Contains(t, err.Error(), testCase.errMsg)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, testCase.expected, normalized)
+		})
+	}
+}
@@ -0,0 +1,133 @@
+package cron
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/lovethedrake/brigdrake/pkg/brigade"
+	"github.com/lovethedrake/brigdrake/pkg/drake"
+	"github.com/pkg/errors"
+)
+
+// provider is the name of the provider of events emitted by a cron gateway
+const provider = "cron"
+
+type trigger struct {
+	Schedules []*schedule `json:"schedules"`
+}
+
+// cronEvent represents the payload of an event emitted by a cron gateway
+type cronEvent struct {
+	Name       string `json:"name"`
+	Expression string `json:"expression"`
+	Timezone   string `json:"timezone"`
+}
+
+// specURI identifies the spec that this package's Trigger implements.
+const specURI = "github.com/lovethedrake/drakespec-cron"
+
+func init() {
+	drake.RegisterTrigger(specURI, ">=1.0.0 <2.0.0", NewTriggerFromJSON)
+}
+
+// NewTriggerFromJSON takes a slice of bytes containing JSON as an argument and
+// returns a Trigger that implements the
+// github.com/lovethedrake/drakespec-cron spec.
+func NewTriggerFromJSON(jsonBytes []byte) (drake.Trigger, error) {
+	t := &trigger{}
+	if err := drake.DecodeTriggerConfig(jsonBytes, t); err != nil {
+		return t, err
+	}
+	for i, schedule := range t.Schedules {
+		if err := schedule.validate(); err != nil {
+			return t, errors.Wrapf(err, "error validating schedule %d", i)
+		}
+	}
+	return t, nil
+}
+
+func (t *trigger) Matches(
+	_ brigade.Project,
+	event brigade.Event,
+) (drake.MatchResult, error) {
+	if event.Provider != provider {
+		return drake.NoMatch(
+			"",
+			"event from provider %q is not handled by cron trigger",
+			event.Provider,
+		), nil
+	}
+	ce := cronEvent{}
+	if len(event.Payload) > 0 {
+		if err := json.Unmarshal(event.Payload, &ce); err != nil {
+			return drake.MatchResult{},
+				errors.Wrap(err, "error unmarshaling event payload")
+		}
+	}
+	// If the payload doesn't name the schedule, fall back to the event type.
+	if ce.Name == "" {
+		ce.Name = event.Type
+	}
+	for i, schedule := range t.Schedules {
+		matches, err := schedule.matches(ce)
+		if err != nil {
+			return drake.MatchResult{}, err
+		}
+		if matches {
+			return drake.Match(
+				fmt.Sprintf("schedules[%d]", i),
+				"cron event for schedule %q matches schedule",
+				ce.Name,
+			), nil
+		}
+	}
+	return drake.NoMatch(
+		"schedules",
+		"cron event for schedule %q matches none of %d schedules",
+		ce.Name,
+		len(t.Schedules),
+	), nil
+}
+
+// matches returns true if the cron event was emitted for this schedule. This
+// is the case if the event's schedule name matches this schedule's name or if
+// the event's cron expression and timezone are equivalent to this schedule's.
+func (s *schedule) matches(ce cronEvent) (bool, error) {
+	if s.Name != "" && s.Name == ce.Name {
+		return true, nil
+	}
+	if s.Expression == "" || ce.Expression == "" {
+		return false, nil
+	}
+	expression, err := normalizeExpression(s.Expression)
+	if err != nil {
+		return false, err
+	}
+	eventExpression, err := normalizeExpression(ce.Expression)
+	if err != nil {
+		return false, errors.Wrap(err, "error parsing event's cron expression")
+	}
+	if expression != eventExpression {
+		return false, nil
+	}
+	location, err := time.LoadLocation(s.Timezone)
+	if err != nil {
+		return false, errors.Wrapf(err, "error loading timezone %q", s.Timezone)
+	}
+	eventLocation, err := time.LoadLocation(ce.Timezone)
+	if err != nil {
+		return false, errors.Wrapf(
+			err,
+			"error loading event's timezone %q",
+			ce.Timezone,
+		)
+	}
+	return location.String() == eventLocation.String(), nil
+}
+
+func (t *trigger) JobStatusNotifier(
+	project brigade.Project, event brigade.Event,
+) (drake.JobStatusNotifier, error) {
+	return nil, nil
+}
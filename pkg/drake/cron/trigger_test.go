@@ -0,0 +1,215 @@
+package cron
+
+import (
+	"testing"
+
+	"github.com/lovethedrake/brigdrake/pkg/brigade"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewTriggerFromJSON(t *testing.T) {
+	testCases := []struct {
+		name       string
+		json       string
+		assertions func(*testing.T, error)
+	}{
+		{
+			name: "valid schedules",
+			json: `{"schedules":[{"name":"nightly"},{"expression":"0 2 * * MON-FRI","timezone":"America/New_York"}]}`, // nolint: lll
+			assertions: func(t *testing.T, err error) {
+				require.NoError(t, err)
+			},
+		},
+		{
+			name: "schedule with neither name nor expression",
+			json: `{"schedules":[{"timezone":"UTC"}]}`,
+			assertions: func(t *testing.T, err error) {
+				require.Error(t, err)
+				require.Contains(t, err.Error(), "must specify a name")
+			},
+		},
+		{
+			name: "invalid cron expression",
+			json: `{"schedules":[{"expression":"0 25 * * *"}]}`,
+			assertions: func(t *testing.T, err error) {
+				require.Error(t, err)
+				require.Contains(t, err.Error(), "hour field")
+			},
+		},
+		{
+			name: "invalid timezone",
+			json: `{"schedules":[{"name":"nightly","timezone":"Mars/Olympus_Mons"}]}`, // nolint: lll
+			assertions: func(t *testing.T, err error) {
+				require.Error(t, err)
+				require.Contains(t, err.Error(), "error loading timezone")
+			},
+		},
+	}
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			_, err := NewTriggerFromJSON([]byte(testCase.json))
+			testCase.assertions(t, err)
+		})
+	}
+}
+
+func TestMatches(t *testing.T) {
+	testCases := []struct {
+		name       string
+		trigger    *trigger
+		event      brigade.Event
+		assertions func(*testing.T, bool, error)
+	}{
+		{
+			name:    "unsupported provider",
+			trigger: &trigger{},
+			event: brigade.Event{
+				Provider: "github",
+				Type:     "push",
+			},
+			assertions: func(t *testing.T, matches bool, err error) {
+				require.NoError(t, err)
+				require.False(t, matches)
+			},
+		},
+		{
+			name: "schedule name match",
+			trigger: &trigger{
+				Schedules: []*schedule{{Name: "nightly"}},
+			},
+			event: brigade.Event{
+				Provider: "cron",
+				Payload:  []byte(`{"name":"nightly","expression":"0 0 * * *"}`),
+			},
+			assertions: func(t *testing.T, matches bool, err error) {
+				require.NoError(t, err)
+				require.True(t, matches)
+			},
+		},
+		{
+			name: "schedule name match via event type",
+			trigger: &trigger{
+				Schedules: []*schedule{{Name: "nightly"}},
+			},
+			event: brigade.Event{
+				Provider: "cron",
+				Type:     "nightly",
+			},
+			assertions: func(t *testing.T, matches bool, err error) {
+				require.NoError(t, err)
+				require.True(t, matches)
+			},
+		},
+		{
+			name: "schedule name non-match",
+			trigger: &trigger{
+				Schedules: []*schedule{{Name: "nightly"}},
+			},
+			event: brigade.Event{
+				Provider: "cron",
+				Payload:  []byte(`{"name":"weekly"}`),
+			},
+			assertions: func(t *testing.T, matches bool, err error) {
+				require.NoError(t, err)
+				require.False(t, matches)
+			},
+		},
+		{
+			name: "equivalent expression match",
+			trigger: &trigger{
+				Schedules: []*schedule{{Expression: "@daily"}},
+			},
+			event: brigade.Event{
+				Provider: "cron",
+				Payload:  []byte(`{"expression":"0  0 * * *","timezone":"UTC"}`),
+			},
+			assertions: func(t *testing.T, matches bool, err error) {
+				require.NoError(t, err)
+				require.True(t, matches)
+			},
+		},
+		{
+			name: "expression match with names",
+			trigger: &trigger{
+				Schedules: []*schedule{{Expression: "30 2 * JAN MON-FRI"}},
+			},
+			event: brigade.Event{
+				Provider: "cron",
+				Payload:  []byte(`{"expression":"30 2 * 1 1-5"}`),
+			},
+			assertions: func(t *testing.T, matches bool, err error) {
+				require.NoError(t, err)
+				require.True(t, matches)
+			},
+		},
+		{
+			name: "expression match with 7 for Sunday",
+			trigger: &trigger{
+				Schedules: []*schedule{{Expression: "0 4 * * 7"}},
+			},
+			event: brigade.Event{
+				Provider: "cron",
+				Payload:  []byte(`{"expression":"0 4 * * SUN"}`),
+			},
+			assertions: func(t *testing.T, matches bool, err error) {
+				require.NoError(t, err)
+				require.True(t, matches)
+			},
+		},
+		{
+			name: "expression match with different timezone",
+			trigger: &trigger{
+				Schedules: []*schedule{
+					{
+						Expression: "0 2 * * *",
+						Timezone:   "America/New_York",
+					},
+				},
+			},
+			event: brigade.Event{
+				Provider: "cron",
+				Payload:  []byte(`{"expression":"0 2 * * *","timezone":"Europe/Berlin"}`), // nolint: lll
+			},
+			assertions: func(t *testing.T, matches bool, err error) {
+				require.NoError(t, err)
+				require.False(t, matches)
+			},
+		},
+		{
+			name: "expression non-match",
+			trigger: &trigger{
+				Schedules: []*schedule{{Expression: "0 2 * * *"}},
+			},
+			event: brigade.Event{
+				Provider: "cron",
+				Payload:  []byte(`{"expression":"0 3 * * *"}`),
+			},
+			assertions: func(t *testing.T, matches bool, err error) {
+				require.NoError(t, err)
+				require.False(t, matches)
+			},
+		},
+		{
+			name: "invalid event expression",
+			trigger: &trigger{
+				Schedules: []*schedule{{Expression: "0 2 * * *"}},
+			},
+			event: brigade.Event{
+				Provider: "cron",
+				Payload:  []byte(`{"expression":"bogus"}`),
+			},
+			assertions: func(t *testing.T, _ bool, err error) {
+				require.Error(t, err)
+			},
+		},
+	}
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			result, err := testCase.trigger.Matches(
+				brigade.Project{},
+				testCase.event,
+			)
+			testCase.assertions(t, result.Matched, err)
+		})
+	}
+}
@@ -0,0 +1,24 @@
+package drake
+
+import "github.com/lovethedrake/brigdrake/pkg/brigade"
+
+// EventOrigin describes where an event came from, to the extent that a
+// Trigger is able to determine it. The zero value describes an event from a
+// trusted origin.
+type EventOrigin struct {
+	// Fork indicates that the event pertains to changes from a fork of the
+	// project's repository.
+	Fork bool
+	// ExternalAuthor indicates that the event was authored by someone who is
+	// not a member of or collaborator on the project's repository.
+	ExternalAuthor bool
+}
+
+// EventOriginEvaluator is an optional interface that a Trigger may also
+// implement if it can determine whether the events it matches originated from
+// a fork or from an external author. This is used to withhold secrets from
+// jobs executed on behalf of untrusted events.
+type EventOriginEvaluator interface {
+	// EventOrigin returns the origin of the event.
+	EventOrigin(brigade.Event) (EventOrigin, error)
+}
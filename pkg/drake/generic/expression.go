@@ -0,0 +1,463 @@
+package generic
+
+import (
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// expression is a compiled condition that can be evaluated against an event
+// payload that was unmarshaled from JSON.
+//
+// The expression language is deliberately small. Operands are paths into the
+// payload (e.g. .deployment.environment), string literals in double quotes,
+// numbers, true, false, and null. Operators, from lowest to highest
+// precedence, are ||, &&, !, and the comparisons ==, !=, =~ (matches regular
+// expression), and !~ (does not match regular expression). Parentheses may be
+// used for grouping. A path that does not exist within the payload evaluates
+// to null.
+type expression interface {
+	evaluate(doc interface{}) (interface{}, error)
+}
+
+type literalExpression struct {
+	value interface{}
+}
+
+func (l *literalExpression) evaluate(interface{}) (interface{}, error) {
+	return l.value, nil
+}
+
+type pathExpression struct {
+	path *jsonPath
+}
+
+func (p *pathExpression) evaluate(doc interface{}) (interface{}, error) {
+	value, _ := p.path.lookup(doc)
+	return value, nil
+}
+
+type notExpression struct {
+	operand expression
+}
+
+func (n *notExpression) evaluate(doc interface{}) (interface{}, error) {
+	value, err := evaluateBool(n.operand, doc, "!")
+	if err != nil {
+		return nil, err
+	}
+	return !value, nil
+}
+
+type logicalExpression struct {
+	operator string
+	left     expression
+	right    expression
+}
+
+func (l *logicalExpression) evaluate(doc interface{}) (interface{}, error) {
+	left, err := evaluateBool(l.left, doc, l.operator)
+	if err != nil {
+		return nil, err
+	}
+	// Short-circuit
+	if (l.operator == "&&" && !left) || (l.operator == "||" && left) {
+		return left, nil
+	}
+	return evaluateBool(l.right, doc, l.operator)
+}
+
+type equalityExpression struct {
+	negate bool
+	left   expression
+	right  expression
+}
+
+func (e *equalityExpression) evaluate(doc interface{}) (interface{}, error) {
+	left, err := e.left.evaluate(doc)
+	if err != nil {
+		return nil, err
+	}
+	right, err := e.right.evaluate(doc)
+	if err != nil {
+		return nil, err
+	}
+	return reflect.DeepEqual(left, right) != e.negate, nil
+}
+
+type regexExpression struct {
+	negate  bool
+	operand expression
+	regex   *regexp.Regexp
+}
+
+func (r *regexExpression) evaluate(doc interface{}) (interface{}, error) {
+	value, err := r.operand.evaluate(doc)
+	if err != nil {
+		return nil, err
+	}
+	// Only strings can match a regular expression
+	str, ok := value.(string)
+	if !ok {
+		return r.negate, nil
+	}
+	return r.regex.MatchString(str) != r.negate, nil
+}
+
+// evaluateBool evaluates the given expression and returns an error if it does
+// not evaluate to a boolean.
+func evaluateBool(
+	expr expression,
+	doc interface{},
+	operator string,
+) (bool, error) {
+	value, err := expr.evaluate(doc)
+	if err != nil {
+		return false, err
+	}
+	b, ok := value.(bool)
+	if !ok {
+		return false, errors.Errorf(
+			"operand of %s evaluated to non-boolean value %v",
+			operator,
+			value,
+		)
+	}
+	return b, nil
+}
+
+type tokenKind int
+
+const (
+	tokenEOF tokenKind = iota
+	tokenPath
+	tokenString
+	tokenNumber
+	tokenIdent
+	tokenOperator
+	tokenLeftParen
+	tokenRightParen
+)
+
+type token struct {
+	kind tokenKind
+	text string
+	pos  int
+}
+
+var operators = []string{"==", "!=", "=~", "!~", "&&", "||", "!"}
+
+// tokenize splits an expression into tokens.
+func tokenize(input string) ([]token, error) {
+	tokens := []token{}
+	i := 0
+	for i < len(input) {
+		c := input[i]
+		start := i
+		switch {
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r':
+			i++
+			continue
+		case c == '(':
+			tokens = append(tokens, token{kind: tokenLeftParen, text: "(", pos: i})
+			i++
+			continue
+		case c == ')':
+			tokens = append(tokens, token{kind: tokenRightParen, text: ")", pos: i})
+			i++
+			continue
+		case c == '"':
+			i++
+			for i < len(input) && input[i] != '"' {
+				if input[i] == '\\' {
+					i++
+				}
+				i++
+			}
+			if i >= len(input) {
+				return nil, errors.Errorf("unterminated string at position %d", start)
+			}
+			i++
+			tokens = append(
+				tokens,
+				token{kind: tokenString, text: input[start:i], pos: start},
+			)
+			continue
+		case c == '.' || c == '$':
+			for i < len(input) {
+				if input[i] == '[' {
+					end, err := closingBracket(input[i:])
+					if err != nil {
+						return nil, errors.Wrapf(err, "invalid path at position %d", start)
+					}
+					i += end + 1
+					continue
+				}
+				if input[i] != '.' && input[i] != '$' && !isIdentChar(input[i]) {
+					break
+				}
+				i++
+			}
+			tokens = append(
+				tokens,
+				token{kind: tokenPath, text: input[start:i], pos: start},
+			)
+			continue
+		case c == '-' || (c >= '0' && c <= '9'):
+			i++
+			for i < len(input) &&
+				(input[i] == '.' || (input[i] >= '0' && input[i] <= '9')) {
+				i++
+			}
+			tokens = append(
+				tokens,
+				token{kind: tokenNumber, text: input[start:i], pos: start},
+			)
+			continue
+		case (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z'):
+			for i < len(input) && isIdentChar(input[i]) {
+				i++
+			}
+			tokens = append(
+				tokens,
+				token{kind: tokenIdent, text: input[start:i], pos: start},
+			)
+			continue
+		}
+		var matched bool
+		for _, operator := range operators {
+			if strings.HasPrefix(input[i:], operator) {
+				tokens = append(
+					tokens,
+					token{kind: tokenOperator, text: operator, pos: i},
+				)
+				i += len(operator)
+				matched = true
+				break
+			}
+		}
+		if !matched {
+			return nil, errors.Errorf(
+				"unexpected character %q at position %d",
+				c,
+				i,
+			)
+		}
+	}
+	return append(tokens, token{kind: tokenEOF, pos: len(input)}), nil
+}
+
+// parser is a recursive descent parser for the expression language.
+type parser struct {
+	tokens []token
+	pos    int
+}
+
+// parseExpression compiles the given input into an expression.
+func parseExpression(input string) (expression, error) {
+	tokens, err := tokenize(input)
+	if err != nil {
+		return nil, err
+	}
+	p := &parser{tokens: tokens}
+	expr, err := p.parseOr()
+	if err != nil {
+		return nil, err
+	}
+	if next := p.peek(); next.kind != tokenEOF {
+		return nil, errors.Errorf(
+			"unexpected %q at position %d",
+			next.text,
+			next.pos,
+		)
+	}
+	return expr, nil
+}
+
+func (p *parser) peek() token {
+	return p.tokens[p.pos]
+}
+
+func (p *parser) next() token {
+	t := p.tokens[p.pos]
+	if t.kind != tokenEOF {
+		p.pos++
+	}
+	return t
+}
+
+func (p *parser) acceptOperator(operators ...string) (string, bool) {
+	t := p.peek()
+	if t.kind != tokenOperator {
+		return "", false
+	}
+	for _, operator := range operators {
+		if t.text == operator {
+			p.pos++
+			return operator, true
+		}
+	}
+	return "", false
+}
+
+func (p *parser) parseOr() (expression, error) {
+	left, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		if _, ok := p.acceptOperator("||"); !ok {
+			return left, nil
+		}
+		right, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		left = &logicalExpression{operator: "||", left: left, right: right}
+	}
+}
+
+func (p *parser) parseAnd() (expression, error) {
+	left, err := p.parseUnary()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		if _, ok := p.acceptOperator("&&"); !ok {
+			return left, nil
+		}
+		right, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		left = &logicalExpression{operator: "&&", left: left, right: right}
+	}
+}
+
+func (p *parser) parseUnary() (expression, error) {
+	if _, ok := p.acceptOperator("!"); ok {
+		operand, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		return &notExpression{operand: operand}, nil
+	}
+	return p.parseComparison()
+}
+
+func (p *parser) parseComparison() (expression, error) {
+	left, err := p.parseOperand()
+	if err != nil {
+		return nil, err
+	}
+	operator, ok := p.acceptOperator("==", "!=", "=~", "!~")
+	if !ok {
+		return left, nil
+	}
+	switch operator {
+	case "=~", "!~":
+		t := p.next()
+		if t.kind != tokenString {
+			return nil, errors.Errorf(
+				"expected string literal regular expression after %s at position %d",
+				operator,
+				t.pos,
+			)
+		}
+		pattern, err := strconv.Unquote(t.text)
+		if err != nil {
+			return nil, errors.Errorf(
+				"invalid string %s at position %d",
+				t.text,
+				t.pos,
+			)
+		}
+		regex, err := regexp.Compile(pattern)
+		if err != nil {
+			return nil, errors.Wrapf(
+				err,
+				"invalid regular expression at position %d",
+				t.pos,
+			)
+		}
+		return &regexExpression{
+			negate:  operator == "!~",
+			operand: left,
+			regex:   regex,
+		}, nil
+	default:
+		right, err := p.parseOperand()
+		if err != nil {
+			return nil, err
+		}
+		return &equalityExpression{
+			negate: operator == "!=",
+			left:   left,
+			right:  right,
+		}, nil
+	}
+}
+
+func (p *parser) parseOperand() (expression, error) {
+	t := p.next()
+	switch t.kind {
+	case tokenPath:
+		path, err := parsePath(t.text)
+		if err != nil {
+			return nil, err
+		}
+		return &pathExpression{path: path}, nil
+	case tokenString:
+		str, err := strconv.Unquote(t.text)
+		if err != nil {
+			return nil, errors.Errorf(
+				"invalid string %s at position %d",
+				t.text,
+				t.pos,
+			)
+		}
+		return &literalExpression{value: str}, nil
+	case tokenNumber:
+		// Numbers unmarshaled from JSON are float64s, so number literals must
+		// also be float64s for equality comparisons to work.
+		num, err := strconv.ParseFloat(t.text, 64)
+		if err != nil {
+			return nil, errors.Errorf(
+				"invalid number %s at position %d",
+				t.text,
+				t.pos,
+			)
+		}
+		return &literalExpression{value: num}, nil
+	case tokenIdent:
+		switch t.text {
+		case "true":
+			return &literalExpression{value: true}, nil
+		case "false":
+			return &literalExpression{value: false}, nil
+		case "null":
+			return &literalExpression{value: nil}, nil
+		}
+		return nil, errors.Errorf(
+			"unrecognized identifier %q at position %d; paths must begin with "+
+				"\".\" or \"$\"",
+			t.text,
+			t.pos,
+		)
+	case tokenLeftParen:
+		expr, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		if closing := p.next(); closing.kind != tokenRightParen {
+			return nil, errors.Errorf("expected \")\" at position %d", closing.pos)
+		}
+		return expr, nil
+	case tokenEOF:
+		return nil, errors.New("unexpected end of expression")
+	}
+	return nil, errors.Errorf("unexpected %q at position %d", t.text, t.pos)
+}
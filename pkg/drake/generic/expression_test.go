@@ -0,0 +1,165 @@
+package generic
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+const testPayload = `{
+	"deployment": {
+		"environment": "staging",
+		"replicas": 3,
+		"canary": false,
+		"labels": {"app.kubernetes.io/name": "drake"}
+	},
+	"ref": "refs/tags/v1.2.3",
+	"commits": [{"id": "abc123"}, {"id": "def456"}]
+}`
+
+func TestParsePath(t *testing.T) {
+	testCases := []struct {
+		path     string
+		expected interface{}
+		found    bool
+		errMsg   string
+	}{
+		{path: ".deployment.environment", expected: "staging", found: true},
+		{path: "$.deployment.replicas", expected: float64(3), found: true},
+		{path: ".commits[1].id", expected: "def456", found: true},
+		{
+			path:     `.deployment.labels["app.kubernetes.io/name"]`,
+			expected: "drake",
+			found:    true,
+		},
+		{path: ".deployment.region", found: false},
+		{path: ".commits[2].id", found: false},
+		{path: ".ref.foo", found: false},
+		{path: "", errMsg: "must not be empty"},
+		{path: "deployment", errMsg: "unexpected character"},
+		{path: ".deployment.", errMsg: "expected key"},
+		{path: ".commits[-1]", errMsg: "invalid array index"},
+		{path: ".commits[0", errMsg: "unterminated subscript"},
+	}
+	var doc interface{}
+	require.NoError(t, json.Unmarshal([]byte(testPayload), &doc))
+	for _, testCase := range testCases {
+		t.Run(testCase.path, func(t *testing.T) {
+			path, err := parsePath(testCase.path)
+			if testCase.errMsg != "" {
+				require.Error(t, err)
+				require.Contains(t, err.Error(), testCase.errMsg)
+				return
+			}
+			require.NoError(t, err)
+			value, found := path.lookup(doc)
+			require.Equal(t, testCase.found, found)
+			require.Equal(t, testCase.expected, value)
+		})
+	}
+}
+
+func TestParseAndEvaluateExpression(t *testing.T) {
+	testCases := []struct {
+		expression string
+		expected   interface{}
+		parseErr   string
+		evalErr    string
+	}{
+		{
+			expression: `.deployment.environment == "staging"`,
+			expected:   true,
+		},
+		{
+			expression: `.deployment.environment != "staging"`,
+			expected:   false,
+		},
+		{
+			expression: `.deployment.replicas == 3 && !.deployment.canary`,
+			expected:   true,
+		},
+		{
+			expression: `.ref =~ "^refs/tags/v[0-9]+" || .deployment.canary`,
+			expected:   true,
+		},
+		{
+			expression: `.ref !~ "^refs/heads/"`,
+			expected:   true,
+		},
+		{
+			expression: `.deployment.replicas =~ "3"`,
+			expected:   false,
+		},
+		{
+			expression: `.deployment.region == null`,
+			expected:   true,
+		},
+		{
+			expression: `(.deployment.environment == "prod" || ` +
+				`.deployment.environment == "staging") && .commits[0].id == "abc123"`,
+			expected: true,
+		},
+		{
+			expression: `.deployment.canary && .deployment.region`,
+			expected:   false,
+		},
+		{
+			expression: `.deployment.environment && true`,
+			evalErr:    "non-boolean",
+		},
+		{
+			expression: `.deployment.environment == `,
+			parseErr:   "unexpected end",
+		},
+		{
+			expression: `deployment == "staging"`,
+			parseErr:   "unrecognized identifier",
+		},
+		{
+			expression: `.ref =~ .deployment.environment`,
+			parseErr:   "expected string literal",
+		},
+		{
+			expression: `.ref =~ "["`,
+			parseErr:   "invalid regular expression",
+		},
+		{
+			expression: `(.deployment.canary`,
+			parseErr:   `expected ")"`,
+		},
+		{
+			expression: `.deployment.canary true`,
+			parseErr:   `unexpected "true"`,
+		},
+		{
+			expression: `.ref == "unterminated`,
+			parseErr:   "unterminated string",
+		},
+		{
+			expression: `.ref < "z"`,
+			parseErr:   "unexpected character",
+		},
+	}
+	var doc interface{}
+	require.NoError(t, json.Unmarshal([]byte(testPayload), &doc))
+	for _, testCase := range testCases {
+		t.Run(testCase.expression, func(t *testing.T) {
+			expr, err := parseExpression(testCase.expression)
+			if testCase.parseErr != "" {
+				require.Error(t, err)
+				require.Contains(t, err.Error(), testCase.parseErr)
+				return
+			}
+			require.NoError(t, err)
+			value, err := expr.evaluate(doc)
+			if testCase.evalErr != "" {
+				require.Error(t, err)
+				require.Contains(t, err.Error(), testCase.evalErr)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, testCase.expected, value)
+		})
+	}
+}
@@ -0,0 +1,146 @@
+package generic
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// pathSegment represents a single step in a jsonPath-- either a key into a
+// JSON object or an index into a JSON array.
+type pathSegment struct {
+	key     string
+	index   int
+	isIndex bool
+}
+
+// jsonPath is a compiled, simplified JSONPath expression such as
+// .deployment.environment, $.commits[0].id, or
+// .labels["app.kubernetes.io/name"]. Wildcards, slices, filters, and recursive
+// descent are not supported.
+type jsonPath struct {
+	raw      string
+	segments []pathSegment
+}
+
+// parsePath compiles a simplified JSONPath expression. A leading $ denoting
+// the root of the document is optional.
+func parsePath(raw string) (*jsonPath, error) {
+	p := &jsonPath{raw: raw}
+	if raw == "" {
+		return nil, errors.New("path must not be empty")
+	}
+	s := strings.TrimPrefix(raw, "$")
+	if s == "." {
+		return p, nil
+	}
+	for len(s) > 0 {
+		switch s[0] {
+		case '.':
+			s = s[1:]
+			i := 0
+			for i < len(s) && isIdentChar(s[i]) {
+				i++
+			}
+			if i == 0 {
+				return nil, errors.Errorf(
+					"invalid path %q: expected key after \".\"",
+					raw,
+				)
+			}
+			p.segments = append(p.segments, pathSegment{key: s[:i]})
+			s = s[i:]
+		case '[':
+			end, err := closingBracket(s)
+			if err != nil {
+				return nil, errors.Wrapf(err, "invalid path %q", raw)
+			}
+			subscript := strings.TrimSpace(s[1:end])
+			s = s[end+1:]
+			if strings.HasPrefix(subscript, `"`) {
+				key, err := strconv.Unquote(subscript)
+				if err != nil {
+					return nil, errors.Errorf(
+						"invalid path %q: invalid quoted key %s",
+						raw,
+						subscript,
+					)
+				}
+				p.segments = append(p.segments, pathSegment{key: key})
+				continue
+			}
+			index, err := strconv.Atoi(subscript)
+			if err != nil || index < 0 {
+				return nil, errors.Errorf(
+					"invalid path %q: invalid array index %q",
+					raw,
+					subscript,
+				)
+			}
+			p.segments = append(
+				p.segments,
+				pathSegment{index: index, isIndex: true},
+			)
+		default:
+			return nil, errors.Errorf(
+				"invalid path %q: unexpected character %q",
+				raw,
+				s[0],
+			)
+		}
+	}
+	return p, nil
+}
+
+// closingBracket returns the index of the "]" that closes the subscript
+// opened at the start of s, skipping over any quoted key.
+func closingBracket(s string) (int, error) {
+	inQuotes := false
+	for i := 1; i < len(s); i++ {
+		switch {
+		case inQuotes && s[i] == '\\':
+			i++
+		case s[i] == '"':
+			inQuotes = !inQuotes
+		case !inQuotes && s[i] == ']':
+			return i, nil
+		}
+	}
+	return 0, errors.New("unterminated subscript")
+}
+
+func isIdentChar(c byte) bool {
+	return c == '_' || c == '-' ||
+		(c >= 'a' && c <= 'z') ||
+		(c >= 'A' && c <= 'Z') ||
+		(c >= '0' && c <= '9')
+}
+
+// lookup returns the value found at the path within a document that was
+// unmarshaled from JSON, and whether any such value exists.
+func (p *jsonPath) lookup(doc interface{}) (interface{}, bool) {
+	value := doc
+	for _, segment := range p.segments {
+		if segment.isIndex {
+			array, ok := value.([]interface{})
+			if !ok || segment.index >= len(array) {
+				return nil, false
+			}
+			value = array[segment.index]
+			continue
+		}
+		object, ok := value.(map[string]interface{})
+		if !ok {
+			return nil, false
+		}
+		if value, ok = object[segment.key]; !ok {
+			return nil, false
+		}
+	}
+	return value, true
+}
+
+func (p *jsonPath) String() string {
+	return p.raw
+}
@@ -0,0 +1,192 @@
+package generic
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+
+	"github.com/lovethedrake/brigdrake/pkg/brigade"
+	"github.com/lovethedrake/brigdrake/pkg/drake"
+	"github.com/pkg/errors"
+)
+
+type trigger struct {
+	Providers  []string     `json:"providers"`
+	EventTypes []string     `json:"eventTypes"`
+	Conditions []*condition `json:"conditions"`
+}
+
+// condition represents a criterion that an event's payload must satisfy. It
+// is expressed either as a path into the payload together with a value that
+// the value at that path must equal or a regular expression that it must
+// match, or as an expression. All conditions compile to an expression.
+type condition struct {
+	Path       string          `json:"path"`
+	Equals     json.RawMessage `json:"equals"`
+	Matches    *string         `json:"matches"`
+	Expression string          `json:"expression"`
+	expr       expression
+}
+
+// specURI identifies the spec that this package's Trigger implements.
+const specURI = "github.com/lovethedrake/drakespec-generic"
+
+func init() {
+	drake.RegisterTrigger(specURI, ">=1.0.0 <2.0.0", NewTriggerFromJSON)
+}
+
+// NewTriggerFromJSON takes a slice of bytes containing JSON as an argument and
+// returns a Trigger that implements the
+// github.com/lovethedrake/drakespec-generic spec.
+func NewTriggerFromJSON(jsonBytes []byte) (drake.Trigger, error) {
+	t := &trigger{}
+	if err := drake.DecodeTriggerConfig(jsonBytes, t); err != nil {
+		return t, err
+	}
+	if len(t.Providers) == 0 {
+		return t, errors.New("trigger must specify at least one provider")
+	}
+	for i, condition := range t.Conditions {
+		if err := condition.compile(); err != nil {
+			return t, errors.Wrapf(err, "error compiling condition %d", i)
+		}
+	}
+	return t, nil
+}
+
+func (c *condition) compile() error {
+	if c.Expression != "" {
+		if c.Path != "" || c.Equals != nil || c.Matches != nil {
+			return errors.New(
+				"condition must not specify both an expression and a path",
+			)
+		}
+		var err error
+		c.expr, err = parseExpression(c.Expression)
+		return errors.Wrapf(err, "error parsing expression %q", c.Expression)
+	}
+	if c.Path == "" {
+		return errors.New("condition must specify either an expression or a path")
+	}
+	path, err := parsePath(c.Path)
+	if err != nil {
+		return err
+	}
+	if (c.Equals == nil) == (c.Matches == nil) {
+		return errors.Errorf(
+			"condition on path %q must specify exactly one of equals or matches",
+			c.Path,
+		)
+	}
+	if c.Matches != nil {
+		regex, err := regexp.Compile(*c.Matches)
+		if err != nil {
+			return errors.Wrapf(
+				err,
+				"error compiling regular expression %q",
+				*c.Matches,
+			)
+		}
+		c.expr = &regexExpression{
+			operand: &pathExpression{path: path},
+			regex:   regex,
+		}
+		return nil
+	}
+	var value interface{}
+	if err := json.Unmarshal(c.Equals, &value); err != nil {
+		return errors.Wrapf(err, "error parsing value of equals for %q", c.Path)
+	}
+	c.expr = &equalityExpression{
+		left:  &pathExpression{path: path},
+		right: &literalExpression{value: value},
+	}
+	return nil
+}
+
+func (c *condition) String() string {
+	if c.Expression != "" {
+		return c.Expression
+	}
+	if c.Matches != nil {
+		return c.Path + " =~ " + *c.Matches
+	}
+	return c.Path + " == " + string(c.Equals)
+}
+
+func (t *trigger) Matches(
+	_ brigade.Project,
+	event brigade.Event,
+) (drake.MatchResult, error) {
+	if !contains(t.Providers, event.Provider) {
+		return drake.NoMatch(
+			"providers",
+			"provider %q not in %v",
+			event.Provider,
+			t.Providers,
+		), nil
+	}
+	if len(t.EventTypes) > 0 && !contains(t.EventTypes, event.Type) {
+		return drake.NoMatch(
+			"eventTypes",
+			"event type %q not in %v",
+			event.Type,
+			t.EventTypes,
+		), nil
+	}
+	if len(t.Conditions) == 0 {
+		return drake.Match(
+			"eventTypes",
+			"%q event from provider %q",
+			event.Type,
+			event.Provider,
+		), nil
+	}
+	var payload interface{}
+	if len(event.Payload) > 0 {
+		if err := json.Unmarshal(event.Payload, &payload); err != nil {
+			return drake.NoMatch(
+				"conditions",
+				"event payload is not valid JSON and cannot satisfy conditions: %s",
+				err,
+			), nil
+		}
+	}
+	for i, condition := range t.Conditions {
+		value, err := condition.expr.evaluate(payload)
+		if err != nil {
+			return drake.MatchResult{}, errors.Wrapf(
+				err,
+				"error evaluating condition %q",
+				condition,
+			)
+		}
+		if matches, ok := value.(bool); !ok || !matches {
+			return drake.NoMatch(
+				fmt.Sprintf("conditions[%d]", i),
+				"event does not satisfy condition %q",
+				condition,
+			), nil
+		}
+	}
+	return drake.Match(
+		"conditions",
+		"event satisfies all %d conditions",
+		len(t.Conditions),
+	), nil
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+func (t *trigger) JobStatusNotifier(
+	project brigade.Project, event brigade.Event,
+) (drake.JobStatusNotifier, error) {
+	return nil, nil
+}
@@ -0,0 +1,211 @@
+package generic
+
+import (
+	"testing"
+
+	"github.com/lovethedrake/brigdrake/pkg/brigade"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewTriggerFromJSON(t *testing.T) {
+	testCases := []struct {
+		name       string
+		json       string
+		assertions func(*testing.T, error)
+	}{
+		{
+			name: "valid trigger",
+			json: `{
+				"providers": ["generic-gateway"],
+				"eventTypes": ["deploy"],
+				"conditions": [
+					{"path": ".deployment.environment", "equals": "staging"},
+					{"path": ".ref", "matches": "^refs/tags/"},
+					{"expression": ".deployment.replicas == 3"}
+				]
+			}`,
+			assertions: func(t *testing.T, err error) {
+				require.NoError(t, err)
+			},
+		},
+		{
+			name: "no providers",
+			json: `{"eventTypes": ["deploy"]}`,
+			assertions: func(t *testing.T, err error) {
+				require.Error(t, err)
+				require.Contains(t, err.Error(), "at least one provider")
+			},
+		},
+		{
+			name: "condition with neither path nor expression",
+			json: `{"providers": ["generic-gateway"], "conditions": [{}]}`,
+			assertions: func(t *testing.T, err error) {
+				require.Error(t, err)
+				require.Contains(t, err.Error(), "either an expression or a path")
+			},
+		},
+		{
+			name: "condition with both path and expression",
+			json: `{
+				"providers": ["generic-gateway"],
+				"conditions": [{"path": ".a", "expression": ".a == 1"}]
+			}`,
+			assertions: func(t *testing.T, err error) {
+				require.Error(t, err)
+				require.Contains(t, err.Error(), "must not specify both")
+			},
+		},
+		{
+			name: "condition with both equals and matches",
+			json: `{
+				"providers": ["generic-gateway"],
+				"conditions": [{"path": ".a", "equals": "b", "matches": "b"}]
+			}`,
+			assertions: func(t *testing.T, err error) {
+				require.Error(t, err)
+				require.Contains(t, err.Error(), "exactly one of equals or matches")
+			},
+		},
+		{
+			name: "condition with invalid expression",
+			json: `{
+				"providers": ["generic-gateway"],
+				"conditions": [{"expression": ".a =="}]
+			}`,
+			assertions: func(t *testing.T, err error) {
+				require.Error(t, err)
+				require.Contains(t, err.Error(), "error compiling condition 0")
+			},
+		},
+	}
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			_, err := NewTriggerFromJSON([]byte(testCase.json))
+			testCase.assertions(t, err)
+		})
+	}
+}
+
+func TestMatches(t *testing.T) {
+	const triggerJSON = `{
+		"providers": ["generic-gateway"],
+		"eventTypes": ["deploy"],
+		"conditions": [
+			{"path": ".deployment.environment", "equals": "staging"},
+			{"path": ".ref", "matches": "^refs/tags/"}
+		]
+	}`
+	testCases := []struct {
+		name       string
+		json       string
+		event      brigade.Event
+		assertions func(*testing.T, bool, error)
+	}{
+		{
+			name: "unsupported provider",
+			json: triggerJSON,
+			event: brigade.Event{
+				Provider: "github",
+				Type:     "deploy",
+				Payload:  []byte(testPayload),
+			},
+			assertions: func(t *testing.T, matches bool, err error) {
+				require.NoError(t, err)
+				require.False(t, matches)
+			},
+		},
+		{
+			name: "unsupported event type",
+			json: triggerJSON,
+			event: brigade.Event{
+				Provider: "generic-gateway",
+				Type:     "rollback",
+				Payload:  []byte(testPayload),
+			},
+			assertions: func(t *testing.T, matches bool, err error) {
+				require.NoError(t, err)
+				require.False(t, matches)
+			},
+		},
+		{
+			name: "all conditions satisfied",
+			json: triggerJSON,
+			event: brigade.Event{
+				Provider: "generic-gateway",
+				Type:     "deploy",
+				Payload:  []byte(testPayload),
+			},
+			assertions: func(t *testing.T, matches bool, err error) {
+				require.NoError(t, err)
+				require.True(t, matches)
+			},
+		},
+		{
+			name: "condition not satisfied",
+			json: triggerJSON,
+			event: brigade.Event{
+				Provider: "generic-gateway",
+				Type:     "deploy",
+				Payload: []byte(
+					`{"deployment":{"environment":"prod"},"ref":"refs/tags/v1"}`,
+				),
+			},
+			assertions: func(t *testing.T, matches bool, err error) {
+				require.NoError(t, err)
+				require.False(t, matches)
+			},
+		},
+		{
+			name: "payload is not JSON",
+			json: triggerJSON,
+			event: brigade.Event{
+				Provider: "generic-gateway",
+				Type:     "deploy",
+				Payload:  []byte("staging"),
+			},
+			assertions: func(t *testing.T, matches bool, err error) {
+				require.NoError(t, err)
+				require.False(t, matches)
+			},
+		},
+		{
+			name: "no conditions and any event type",
+			json: `{"providers": ["generic-gateway"]}`,
+			event: brigade.Event{
+				Provider: "generic-gateway",
+				Type:     "anything",
+			},
+			assertions: func(t *testing.T, matches bool, err error) {
+				require.NoError(t, err)
+				require.True(t, matches)
+			},
+		},
+		{
+			name: "expression evaluation error",
+			json: `{
+				"providers": ["generic-gateway"],
+				"conditions": [{"expression": "!.deployment.environment"}]
+			}`,
+			event: brigade.Event{
+				Provider: "generic-gateway",
+				Type:     "deploy",
+				Payload:  []byte(testPayload),
+			},
+			assertions: func(t *testing.T, _ bool, err error) {
+				require.Error(t, err)
+				require.Contains(t, err.Error(), "non-boolean")
+			},
+		},
+	}
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			trigger, err := NewTriggerFromJSON([]byte(testCase.json))
+			require.NoError(t, err)
+			result, err := trigger.Matches(
+				brigade.Project{},
+				testCase.event,
+			)
+			testCase.assertions(t, result.Matched, err)
+		})
+	}
+}
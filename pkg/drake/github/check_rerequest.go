@@ -0,0 +1,133 @@
+package github
+
+import (
+	"encoding/json"
+
+	"github.com/google/go-github/github"
+	"github.com/lovethedrake/brigdrake/pkg/brigade"
+	"github.com/pkg/errors"
+)
+
+const (
+	checkSuiteRerequestedEventType = "check_suite:rerequested"
+	checkRunRerequestedEventType   = "check_run:rerequested"
+)
+
+// checkRerequest captures the details of a check_suite:rerequested or
+// check_run:rerequested event that are needed to map it back onto the pull
+// request or push that originally caused the checks to run.
+type checkRerequest struct {
+	installationID int64
+	repoOwner      string
+	repoName       string
+	// repoID is the ID of the repository the checks belong to.
+	repoID     int64
+	headSHA    string
+	headBranch string
+	// beforeSHA is the SHA of the commit the head branch pointed to before the
+	// push that caused the checks to run, if any.
+	beforeSHA string
+	// headRepoID is the ID of the repository that headBranch belongs to. This
+	// is zero if the event did not say.
+	headRepoID   int64
+	pullRequests []*github.PullRequest
+	// checkRunName is the name of the check run that was re-requested. This is
+	// empty when an entire check suite was re-requested.
+	checkRunName string
+}
+
+// checkSuiteHeadRepository is used for unmarshaling the head repository of a
+// check suite, which the github.CheckSuite type does not (yet) define.
+type checkSuiteHeadRepository struct {
+	HeadRepository struct {
+		ID int64 `json:"id"`
+	} `json:"head_repository"`
+}
+
+// checkRerequestHeadRepository is used for unmarshaling the head repository of
+// the check suite in either a check_suite or a check_run event.
+type checkRerequestHeadRepository struct {
+	CheckSuite checkSuiteHeadRepository `json:"check_suite"`
+	CheckRun   struct {
+		CheckSuite checkSuiteHeadRepository `json:"check_suite"`
+	} `json:"check_run"`
+}
+
+func newCheckRerequest(event brigade.Event) (checkRerequest, error) {
+	c := checkRerequest{}
+	var suite *github.CheckSuite
+	var repo *github.Repository
+	switch event.Type {
+	case checkSuiteRerequestedEventType:
+		cse := github.CheckSuiteEvent{}
+		if err := json.Unmarshal(event.Payload, &cse); err != nil {
+			return c, errors.Wrap(err, "error unmarshaling event payload")
+		}
+		suite = cse.GetCheckSuite()
+		repo = cse.GetRepo()
+		c.installationID = cse.GetInstallation().GetID()
+		c.headSHA = suite.GetHeadSHA()
+	case checkRunRerequestedEventType:
+		cre := github.CheckRunEvent{}
+		if err := json.Unmarshal(event.Payload, &cre); err != nil {
+			return c, errors.Wrap(err, "error unmarshaling event payload")
+		}
+		run := cre.GetCheckRun()
+		suite = run.GetCheckSuite()
+		repo = cre.GetRepo()
+		c.installationID = cre.GetInstallation().GetID()
+		c.headSHA = run.GetHeadSHA()
+		c.checkRunName = run.GetName()
+		c.pullRequests = run.PullRequests
+	default:
+		return c, errors.Errorf(
+			"event type %q is not a check rerequest",
+			event.Type,
+		)
+	}
+	// A check run's own list of pull requests is preferred, but otherwise, the
+	// check suite's is used.
+	if len(c.pullRequests) == 0 && suite != nil {
+		c.pullRequests = suite.PullRequests
+	}
+	c.headBranch = suite.GetHeadBranch()
+	c.beforeSHA = suite.GetBeforeSHA()
+	headRepo := checkRerequestHeadRepository{}
+	if err := json.Unmarshal(event.Payload, &headRepo); err != nil {
+		return c, errors.Wrap(err, "error unmarshaling event payload")
+	}
+	c.headRepoID = headRepo.CheckSuite.HeadRepository.ID
+	if c.headRepoID == 0 {
+		c.headRepoID = headRepo.CheckRun.CheckSuite.HeadRepository.ID
+	}
+	c.repoID = repo.GetID()
+	c.repoOwner = repo.GetOwner().GetLogin()
+	c.repoName = repo.GetName()
+	return c, nil
+}
+
+// fromFork returns true if the checks being re-requested may have run against
+// changes from a fork. GitHub doesn't list pull requests from forks in check
+// suites, so when none are listed, the check suite's head repository is
+// compared to the repository instead. If either is unknown, the changes are
+// assumed to be from a fork, since where they came from can't be established.
+func (c checkRerequest) fromFork() bool {
+	if len(c.pullRequests) == 0 {
+		return c.headRepoID == 0 || c.headRepoID != c.repoID
+	}
+	for _, pr := range c.pullRequests {
+		if isFork(pr) {
+			return true
+		}
+	}
+	return false
+}
+
+// fromOtherRepo returns true if the check suite's head branch is known to
+// belong to a repository other than the one the checks belong to, i.e. the
+// checks ran against a pull request from a fork that GitHub didn't list.
+func (c checkRerequest) fromOtherRepo() bool {
+	return len(c.pullRequests) == 0 &&
+		c.headRepoID != 0 &&
+		c.headRepoID != c.repoID
+}
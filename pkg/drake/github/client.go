@@ -2,12 +2,56 @@ package github
 
 import (
 	"context"
+	"encoding/base64"
 	"fmt"
+	"strconv"
 
 	"github.com/google/go-github/github"
+	"github.com/lovethedrake/brigdrake/pkg/brigade"
+	"github.com/pkg/errors"
 	"golang.org/x/oauth2"
 )
 
+// githubClientFn is the signature of functions that return a client for
+// interacting with the GitHub API on behalf of the specified installation of
+// the GitHub App whose credentials are stored in the project's secrets.
+type githubClientFn func(
+	project brigade.Project,
+	installationID int64,
+) (simpleGithubClient, error)
+
+// newInstallationClient is a githubClientFn that returns a real GitHub client.
+func newInstallationClient(
+	project brigade.Project,
+	installationID int64,
+) (simpleGithubClient, error) {
+	appIDStr, ok := project.Secrets["BRIGDRAKE_GITHUB_APP_ID"]
+	if !ok {
+		return nil, errors.New(
+			"project secrets do not specify BRIGDRAKE_GITHUB_APP_ID",
+		)
+	}
+	appID, err := strconv.ParseInt(appIDStr, 10, 64)
+	if err != nil {
+		return nil, errors.Wrapf(
+			err,
+			"error parsing BRIGDRAKE_GITHUB_APP_ID %q",
+			appIDStr,
+		)
+	}
+	base64EncodedGithubKey, ok := project.Secrets["BRIGDRAKE_GITHUB_KEY"]
+	if !ok {
+		return nil, errors.New(
+			"project secrets do not specify BRIGDRAKE_GITHUB_KEY",
+		)
+	}
+	githubKey, err := base64.StdEncoding.DecodeString(base64EncodedGithubKey)
+	if err != nil {
+		return nil, errors.Wrap(err, "error base64 decoding github key")
+	}
+	return newClientFromKeyPEM(appID, installationID, githubKey)
+}
+
 // newClientFromBearerToken returns a new github.Client for the given bearer
 // token.
 func newClientFromBearerToken(token string) *github.Client {
@@ -0,0 +1,84 @@
+package github
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+
+	"github.com/google/go-github/github"
+	"github.com/pkg/errors"
+)
+
+// getCommitSHA returns the SHA of the commit that the given ref (e.g. a branch
+// or tag name) refers to.
+func getCommitSHA(
+	githubClient simpleGithubClient,
+	repoOwner string,
+	repoName string,
+	ref string,
+) (string, error) {
+	req, err := githubClient.NewRequest(
+		"GET",
+		fmt.Sprintf(
+			"repos/%s/%s/commits/%s",
+			repoOwner,
+			repoName,
+			url.PathEscape(ref),
+		),
+		nil,
+	)
+	if err != nil {
+		return "", errors.Wrap(err, "error creating request for commit")
+	}
+	commit := github.RepositoryCommit{}
+	if _, err = githubClient.Do(context.TODO(), req, &commit); err != nil {
+		return "", errors.Wrapf(err, "error getting commit for ref %q", ref)
+	}
+	if commit.GetSHA() == "" {
+		return "", errors.Errorf("could not determine commit for ref %q", ref)
+	}
+	return commit.GetSHA(), nil
+}
+
+// commitsComparison represents the subset of the fields of a comparison
+// between two commits that we use.
+type commitsComparison struct {
+	Files []pullRequestFile `json:"files"`
+}
+
+// listChangedFiles returns the names of all files changed between the two
+// given commits. Files that were renamed are listed under both their old and
+// new names. GitHub lists at most 300 files.
+func listChangedFiles(
+	githubClient simpleGithubClient,
+	repoOwner string,
+	repoName string,
+	base string,
+	head string,
+) ([]string, error) {
+	req, err := githubClient.NewRequest(
+		"GET",
+		fmt.Sprintf("repos/%s/%s/compare/%s...%s", repoOwner, repoName, base, head),
+		nil,
+	)
+	if err != nil {
+		return nil, errors.Wrap(err, "error creating request for comparison")
+	}
+	comparison := commitsComparison{}
+	if _, err = githubClient.Do(context.TODO(), req, &comparison); err != nil {
+		return nil, errors.Wrapf(
+			err,
+			"error comparing commit %s to commit %s",
+			head,
+			base,
+		)
+	}
+	fileNames := []string{}
+	for _, file := range comparison.Files {
+		fileNames = append(fileNames, file.Filename)
+		if file.PreviousFilename != "" {
+			fileNames = append(fileNames, file.PreviousFilename)
+		}
+	}
+	return fileNames, nil
+}
@@ -0,0 +1,82 @@
+package github
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/google/go-github/github"
+	"github.com/lovethedrake/brigdrake/pkg/brigade"
+	"github.com/lovethedrake/brigdrake/pkg/drake"
+	"github.com/pkg/errors"
+)
+
+const createEventType = "create"
+
+type createEventSelector struct {
+	BranchSelector *refSelector `json:"branches,omitempty"`
+	TagSelector    *tagSelector `json:"tags,omitempty"`
+}
+
+// validate returns an error if the selector's tag selector is invalid.
+func (c *createEventSelector) validate() error {
+	if c.TagSelector == nil {
+		return nil
+	}
+	return errors.Wrap(
+		c.TagSelector.validate(),
+		"error validating tag selector",
+	)
+}
+
+func (c *createEventSelector) matches(
+	event brigade.Event,
+) (drake.MatchResult, error) {
+	ce := github.CreateEvent{}
+	if err := json.Unmarshal(event.Payload, &ce); err != nil {
+		return drake.MatchResult{},
+			errors.Wrap(err, "error unmarshaling event payload")
+	}
+	ref := ce.GetRef()
+	var selectorName string
+	var match bool
+	var reason string
+	var err error
+	switch ce.GetRefType() {
+	case "branch":
+		selectorName = "create.branches"
+		if c.BranchSelector == nil {
+			return drake.NoMatch(
+				selectorName,
+				"no applicable selector configured for branch %s",
+				ref,
+			), nil
+		}
+		match, reason, err = c.BranchSelector.matches(ref)
+	case "tag":
+		selectorName = "create.tags"
+		if c.TagSelector == nil {
+			return drake.NoMatch(
+				selectorName,
+				"no applicable selector configured for tag %s",
+				ref,
+			), nil
+		}
+		match, reason, err = c.TagSelector.matches(ref)
+	default:
+		return drake.NoMatch(
+			"create",
+			"creation of %s %s is not selected",
+			ce.GetRefType(),
+			ref,
+		), nil
+	}
+	if err != nil {
+		return drake.MatchResult{},
+			errors.Wrapf(err, "error matching ref %q to selector", ref)
+	}
+	return drake.MatchResult{
+		Matched:  match,
+		Selector: selectorName,
+		Reason:   fmt.Sprintf("%s %s %s", ce.GetRefType(), ref, reason),
+	}, nil
+}
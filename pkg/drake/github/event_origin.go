@@ -0,0 +1,77 @@
+package github
+
+import (
+	"encoding/json"
+
+	"github.com/google/go-github/github"
+	"github.com/lovethedrake/brigdrake/pkg/brigade"
+	"github.com/lovethedrake/brigdrake/pkg/drake"
+	"github.com/pkg/errors"
+)
+
+// memberAuthorAssociations are the relationships to a repository that
+// distinguish members of and collaborators on the repository from external
+// authors.
+var memberAuthorAssociations = []string{
+	"OWNER",
+	"MEMBER",
+	"COLLABORATOR",
+}
+
+// EventOrigin implements drake.EventOriginEvaluator. Pull requests are from a
+// fork if their head and base repositories differ and are from an external
+// author if the pull request's author is not a member of or collaborator on
+// the base repository. Comments are from an external author if the comment's
+// author is not a member or collaborator. A command issued by a member or
+// collaborator in a comment is regarded as their approval to run the pull
+// request's changes, wherever those came from. Re-requested checks are from a
+// fork if any pull request they ran against is, or, since GitHub doesn't list
+// pull requests from forks, if they name no pull requests and their head
+// repository is not the repository itself or is unknown. Other events, e.g.
+// pushes, can only originate from someone with write access to the repository
+// and are therefore trusted.
+func (t *trigger) EventOrigin(event brigade.Event) (drake.EventOrigin, error) {
+	origin := drake.EventOrigin{}
+	if event.Provider != "github" {
+		return origin, nil
+	}
+	switch event.Type {
+	case "pull_request:opened",
+		"pull_request:synchronize",
+		"pull_request:reopened",
+		"pull_request:labeled":
+		pre := github.PullRequestEvent{}
+		if err := json.Unmarshal(event.Payload, &pre); err != nil {
+			return origin, errors.Wrap(err, "error unmarshaling event payload")
+		}
+		origin.Fork = isFork(pre.GetPullRequest())
+		origin.ExternalAuthor = !authorAssociationAllowed(
+			memberAuthorAssociations,
+			pre.GetPullRequest().GetAuthorAssociation(),
+		)
+	case checkSuiteRerequestedEventType, checkRunRerequestedEventType:
+		cr, err := newCheckRerequest(event)
+		if err != nil {
+			return origin, err
+		}
+		origin.Fork = cr.fromFork()
+	case issueCommentCreatedEventType:
+		ice := github.IssueCommentEvent{}
+		if err := json.Unmarshal(event.Payload, &ice); err != nil {
+			return origin, errors.Wrap(err, "error unmarshaling event payload")
+		}
+		origin.ExternalAuthor = !authorAssociationAllowed(
+			memberAuthorAssociations,
+			ice.GetComment().GetAuthorAssociation(),
+		)
+	}
+	return origin, nil
+}
+
+// isFork returns true if the given pull request's head repository differs
+// from its base repository. A pull request whose head repository is unknown,
+// e.g. because the fork was deleted, is also regarded as being from a fork.
+func isFork(pr *github.PullRequest) bool {
+	headRepoID := pr.GetHead().GetRepo().GetID()
+	return headRepoID == 0 || headRepoID != pr.GetBase().GetRepo().GetID()
+}
@@ -0,0 +1,140 @@
+package github
+
+import (
+	"testing"
+
+	"github.com/lovethedrake/brigdrake/pkg/brigade"
+	"github.com/lovethedrake/brigdrake/pkg/drake"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEventOrigin(t *testing.T) {
+	testCases := []struct {
+		name       string
+		event      brigade.Event
+		assertions func(*testing.T, drake.EventOrigin, error)
+	}{
+		{
+			name: "push event",
+			event: brigade.Event{
+				Provider: "github",
+				Type:     "push",
+				Payload:  []byte(`{"ref":"refs/heads/master"}`),
+			},
+			assertions: func(t *testing.T, origin drake.EventOrigin, err error) {
+				require.NoError(t, err)
+				require.Equal(t, drake.EventOrigin{}, origin)
+			},
+		},
+		{
+			name: "pull request from member's branch",
+			event: brigade.Event{
+				Provider: "github",
+				Type:     "pull_request:opened",
+				Payload:  []byte(`{"pull_request":{"author_association":"MEMBER","head":{"repo":{"id":1}},"base":{"repo":{"id":1}}}}`), // nolint: lll
+			},
+			assertions: func(t *testing.T, origin drake.EventOrigin, err error) {
+				require.NoError(t, err)
+				require.Equal(t, drake.EventOrigin{}, origin)
+			},
+		},
+		{
+			name: "pull request from external author's fork",
+			event: brigade.Event{
+				Provider: "github",
+				Type:     "pull_request:opened",
+				Payload:  []byte(`{"pull_request":{"author_association":"CONTRIBUTOR","head":{"repo":{"id":2}},"base":{"repo":{"id":1}}}}`), // nolint: lll
+			},
+			assertions: func(t *testing.T, origin drake.EventOrigin, err error) {
+				require.NoError(t, err)
+				require.Equal(
+					t,
+					drake.EventOrigin{
+						Fork:           true,
+						ExternalAuthor: true,
+					},
+					origin,
+				)
+			},
+		},
+		{
+			name: "pull request from deleted fork",
+			event: brigade.Event{
+				Provider: "github",
+				Type:     "pull_request:synchronize",
+				Payload:  []byte(`{"pull_request":{"author_association":"COLLABORATOR","head":{},"base":{"repo":{"id":1}}}}`), // nolint: lll
+			},
+			assertions: func(t *testing.T, origin drake.EventOrigin, err error) {
+				require.NoError(t, err)
+				require.True(t, origin.Fork)
+				require.False(t, origin.ExternalAuthor)
+			},
+		},
+		{
+			name: "check suite rerequested for pull request from fork",
+			event: brigade.Event{
+				Provider: "github",
+				Type:     "check_suite:rerequested",
+				Payload:  []byte(`{"check_suite":{"pull_requests":[{"head":{"repo":{"id":2}},"base":{"repo":{"id":1}}}]}}`), // nolint: lll
+			},
+			assertions: func(t *testing.T, origin drake.EventOrigin, err error) {
+				require.NoError(t, err)
+				require.True(t, origin.Fork)
+			},
+		},
+		{
+			name: "check suite rerequested for branch of fork with no pull requests",
+			event: brigade.Event{
+				Provider: "github",
+				Type:     "check_suite:rerequested",
+				Payload:  []byte(`{"check_suite":{"head_branch":"master","head_repository":{"id":2}},"repository":{"id":1}}`), // nolint: lll
+			},
+			assertions: func(t *testing.T, origin drake.EventOrigin, err error) {
+				require.NoError(t, err)
+				require.True(t, origin.Fork)
+			},
+		},
+		{
+			name: "check run rerequested for push",
+			event: brigade.Event{
+				Provider: "github",
+				Type:     "check_run:rerequested",
+				Payload:  []byte(`{"check_run":{"check_suite":{"head_branch":"master","head_repository":{"id":1}}},"repository":{"id":1}}`), // nolint: lll
+			},
+			assertions: func(t *testing.T, origin drake.EventOrigin, err error) {
+				require.NoError(t, err)
+				require.Equal(t, drake.EventOrigin{}, origin)
+			},
+		},
+		{
+			name: "check suite rerequested with unknown head repository",
+			event: brigade.Event{
+				Provider: "github",
+				Type:     "check_suite:rerequested",
+				Payload:  []byte(`{"check_suite":{"head_branch":"master"},"repository":{"id":1}}`), // nolint: lll
+			},
+			assertions: func(t *testing.T, origin drake.EventOrigin, err error) {
+				require.NoError(t, err)
+				require.True(t, origin.Fork)
+			},
+		},
+		{
+			name: "comment by external author",
+			event: brigade.Event{
+				Provider: "github",
+				Type:     "issue_comment:created",
+				Payload:  []byte(`{"comment":{"author_association":"NONE"}}`),
+			},
+			assertions: func(t *testing.T, origin drake.EventOrigin, err error) {
+				require.NoError(t, err)
+				require.Equal(t, drake.EventOrigin{ExternalAuthor: true}, origin)
+			},
+		},
+	}
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			origin, err := (&trigger{}).EventOrigin(testCase.event)
+			testCase.assertions(t, origin, err)
+		})
+	}
+}
@@ -0,0 +1,154 @@
+package github
+
+import (
+	"encoding/json"
+	"regexp"
+	"strings"
+
+	"github.com/google/go-github/github"
+	"github.com/lovethedrake/brigdrake/pkg/brigade"
+	"github.com/lovethedrake/brigdrake/pkg/drake"
+	"github.com/pkg/errors"
+)
+
+const issueCommentCreatedEventType = "issue_comment:created"
+
+// defaultCommand is the command that comments must begin with if an
+// issueCommentEventSelector doesn't specify one.
+const defaultCommand = "/drake run"
+
+// defaultCommentAuthorAssociations are the relationships to the repository
+// that comment authors must have if an issueCommentEventSelector doesn't
+// specify any. Without this default, anyone able to comment on a pull request
+// would be able to execute pipelines.
+var defaultCommentAuthorAssociations = memberAuthorAssociations
+
+// issueCommentEventSelector selects comments on pull requests that issue a
+// command, e.g. "/drake run" or "/drake run e2e". The optional argument
+// following the command names a single pipeline or job to be executed.
+type issueCommentEventSelector struct {
+	// Command is the command that a comment must begin with. It may be
+	// specified literally or as a /regular expression/.
+	Command string `json:"command,omitempty"`
+	// AuthorAssociations enumerates the relationships to the repository (e.g.
+	// OWNER, MEMBER, or COLLABORATOR) that a comment's author must have for the
+	// comment to be selected.
+	AuthorAssociations []string `json:"authorAssociations,omitempty"`
+}
+
+func (i *issueCommentEventSelector) matches(
+	event brigade.Event,
+) (drake.MatchResult, error) {
+	ice := github.IssueCommentEvent{}
+	if err := json.Unmarshal(event.Payload, &ice); err != nil {
+		return drake.MatchResult{},
+			errors.Wrap(err, "error unmarshaling event payload")
+	}
+	if !ice.GetIssue().IsPullRequest() {
+		return drake.NoMatch(
+			"issueComment",
+			"comment is on an issue that is not a pull request",
+		), nil
+	}
+	allowedAuthorAssociations := i.AuthorAssociations
+	if len(allowedAuthorAssociations) == 0 {
+		allowedAuthorAssociations = defaultCommentAuthorAssociations
+	}
+	authorAssociation := ice.GetComment().GetAuthorAssociation()
+	if !authorAssociationAllowed(allowedAuthorAssociations, authorAssociation) {
+		return drake.NoMatch(
+			"issueComment.authorAssociations",
+			"author association %q not in %v",
+			authorAssociation,
+			allowedAuthorAssociations,
+		), nil
+	}
+	match, _, err := i.parseCommand(ice.GetComment().GetBody())
+	if err != nil {
+		return drake.MatchResult{}, err
+	}
+	command := i.Command
+	if command == "" {
+		command = defaultCommand
+	}
+	if !match {
+		return drake.NoMatch(
+			"issueComment.command",
+			"comment does not issue command %s",
+			command,
+		), nil
+	}
+	return drake.Match(
+		"issueComment.command",
+		"comment issues command %s",
+		command,
+	), nil
+}
+
+// argument returns the argument, if any, that follows the command in the
+// comment.
+func (i *issueCommentEventSelector) argument(
+	event brigade.Event,
+) (string, error) {
+	ice := github.IssueCommentEvent{}
+	if err := json.Unmarshal(event.Payload, &ice); err != nil {
+		return "", errors.Wrap(err, "error unmarshaling event payload")
+	}
+	_, arg, err := i.parseCommand(ice.GetComment().GetBody())
+	return arg, err
+}
+
+// parseCommand determines whether the first line of the comment body begins
+// with the command and, if so, returns the first word that follows it, if
+// any.
+func (i *issueCommentEventSelector) parseCommand(
+	body string,
+) (bool, string, error) {
+	line := strings.TrimSpace(strings.SplitN(body, "\n", 2)[0])
+	command := i.Command
+	if command == "" {
+		command = defaultCommand
+	}
+	var rest string
+	if strings.HasPrefix(command, "/") && strings.HasSuffix(command, "/") &&
+		len(command) > 1 {
+		regex, err := regexp.Compile(command[1 : len(command)-1])
+		if err != nil {
+			return false, "", errors.Wrapf(
+				err,
+				"error compiling regular expression %s",
+				command,
+			)
+		}
+		loc := regex.FindStringIndex(line)
+		if loc == nil || loc[0] != 0 {
+			return false, "", nil
+		}
+		rest = line[loc[1]:]
+	} else {
+		if !strings.HasPrefix(line, command) {
+			return false, "", nil
+		}
+		rest = line[len(command):]
+	}
+	// The command must be followed by whitespace or nothing at all; otherwise
+	// "/drake runaway" would be mistaken for "/drake run".
+	if rest != "" && rest == strings.TrimLeft(rest, " \t") {
+		return false, "", nil
+	}
+	if fields := strings.Fields(rest); len(fields) > 0 {
+		return true, fields[0], nil
+	}
+	return true, "", nil
+}
+
+// authorAssociationAllowed returns true if the given author association is
+// among those that are allowed. Comparisons are case-insensitive.
+func authorAssociationAllowed(allowed []string, authorAssociation string) bool {
+	for _, allowedAuthorAssociation := range allowed {
+		if strings.EqualFold(authorAssociation, allowedAuthorAssociation) {
+			return true
+		}
+	}
+	return false
+}
@@ -1,17 +1,16 @@
 package github
 
 import (
-	"bytes"
 	"context"
-	"encoding/base64"
 	"fmt"
 	"net/http"
+	"strings"
+	"sync"
 	"time"
 
 	"github.com/google/go-github/github"
 	"github.com/lovethedrake/brigdrake/pkg/drake"
 	"github.com/lovethedrake/drakecore/config"
-	"github.com/pkg/errors"
 )
 
 // simpleGithubClient is an interface for a github client that contains
@@ -29,50 +28,85 @@ type simpleGithubClient interface {
 
 // jobStatusNotifier is an implementation of the drake.JobStatusNotifier
 // interface that can report Brigade / Drake job statuses to GitHub as check
-// runs.
+// runs. Each check run is created only once and thereafter updated using the
+// ID GitHub assigned it.
 type jobStatusNotifier struct {
-	checkRunsURL string
-	commit       string
-	githubClient simpleGithubClient
+	checkRunsURL     string
+	commit           string
+	detailsURL       string
+	summaryCheckName string
+	githubClient     simpleGithubClient
+	// checkRunIDs maps the names of check runs that have already been created
+	// to their IDs.
+	checkRunIDs   map[string]int64
+	checkRunIDsMu sync.Mutex
 }
 
+// checkRun is a github.CheckRun with the addition of fields that the
+// github.CheckRun type does not (yet) define.
+type checkRun struct {
+	github.CheckRun
+	DetailsURL *string `json:"details_url,omitempty"`
+}
+
+// maxCheckRunOutputTextLength is the maximum length GitHub permits for the text
+// of a check run's output.
+const maxCheckRunOutputTextLength = 65535
+
 // newJobStatusNotifier returns an implementation of the drake.JobStatusNotifier
 // interface that can report Brigade / Drake job statuses to GitHub as check
-// runs.
+// runs. The status of each pipeline as a whole is reported as a summary check
+// run having the given name or, if no name is given, a name derived from the
+// pipeline's.
 func newJobStatusNotifier(
-	appID int64,
-	installationID int64,
-	base64EncodedGithubKey string,
+	githubClient simpleGithubClient,
 	repoOwner string,
 	repoName string,
 	commit string,
-) (drake.JobStatusNotifier, error) {
-	githubKey, err := base64.StdEncoding.DecodeString(base64EncodedGithubKey)
-	if err != nil {
-		return nil, errors.Wrap(err, "error base64 decoding github key")
+	detailsURL string,
+	summaryCheckName string,
+) drake.JobStatusNotifier {
+	return &jobStatusNotifier{
+		checkRunsURL: fmt.Sprintf(
+			"repos/%s/%s/check-runs",
+			repoOwner,
+			repoName,
+		),
+		commit:           commit,
+		detailsURL:       detailsURL,
+		summaryCheckName: summaryCheckName,
+		githubClient:     githubClient,
+		checkRunIDs:      map[string]int64{},
 	}
-	githubClient, err := newClientFromKeyPEM(
-		appID,
-		installationID,
-		[]byte(githubKey),
+}
+
+func (j *jobStatusNotifier) SendQueuedNotification(job config.Job) error {
+	jobName := job.Name()
+	status := "queued"
+	blankSummary := ""
+	return j.notifyGithub(
+		github.CheckRun{
+			Name:    &jobName,
+			HeadSHA: &j.commit,
+			Output: &github.CheckRunOutput{
+				Title:   &jobName,
+				Summary: &blankSummary,
+			},
+			Status: &status,
+		},
 	)
-	if err != nil {
-		return nil, errors.Wrap(
-			err,
-			"error creating github client for job status notifier",
-		)
-	}
-	return &jobStatusNotifier{
-		checkRunsURL: fmt.Sprintf("repos/%s/%s/check-runs", repoOwner, repoName),
-		commit:       commit,
-		githubClient: githubClient,
-	}, nil
 }
 
-func (j *jobStatusNotifier) SendInProgressNotification(job config.Job) error {
+func (j *jobStatusNotifier) SendInProgressNotification(
+	job config.Job,
+	attempt int,
+) error {
 	jobName := job.Name()
 	status := "in_progress"
-	blankSummary := ""
+	summary := ""
+	if attempt > 1 {
+		summary = fmt.Sprintf("Attempt %d", attempt)
+	}
 	return j.notifyGithub(
 		github.CheckRun{
 			Name:      &jobName,
@@ -80,43 +114,153 @@ func (j *jobStatusNotifier) SendInProgressNotification(job config.Job) error {
 			StartedAt: &github.Timestamp{Time: time.Now()},
 			Output: &github.CheckRunOutput{
 				Title:   &jobName,
-				Summary: &blankSummary,
+				Summary: &summary,
 			},
 			Status: &status,
 		},
 	)
 }
 
-func (j *jobStatusNotifier) SendSuccessNotification(job config.Job) error {
-	return j.sendCompletedNotification(job, "success")
+func (j *jobStatusNotifier) SendSuccessNotification(
+	job config.Job,
+	output drake.JobOutput,
+) error {
+	return j.sendCompletedNotification(job, "success", "", output)
 }
 
-func (j *jobStatusNotifier) SendCancelledNotification(job config.Job) error {
-	return j.sendCompletedNotification(job, "cancelled")
+func (j *jobStatusNotifier) SendCancelledNotification(
+	job config.Job,
+	output drake.JobOutput,
+) error {
+	return j.sendCompletedNotification(job, "cancelled", "", output)
+}
+
+func (j *jobStatusNotifier) SendTimedOutNotification(
+	job config.Job,
+	output drake.JobOutput,
+) error {
+	return j.sendCompletedNotification(job, "timed_out", "", output)
 }
 
-func (j *jobStatusNotifier) SendTimedOutNotification(job config.Job) error {
-	return j.sendCompletedNotification(job, "timed_out")
+func (j *jobStatusNotifier) SendFailureNotification(
+	job config.Job,
+	failure drake.JobFailure,
+	output drake.JobOutput,
+) error {
+	summary := failure.String()
+	if failure.Message != "" {
+		summary = fmt.Sprintf("%s\n\n%s", summary, failure.Message)
+	}
+	return j.sendCompletedNotification(job, "failure", summary, output)
 }
 
-func (j *jobStatusNotifier) SendFailureNotification(job config.Job) error {
-	return j.sendCompletedNotification(job, "failure")
+func (j *jobStatusNotifier) SendSkippedNotification(job config.Job) error {
+	return j.sendCompletedNotification(job, "skipped", "", drake.JobOutput{})
 }
 
 func (j *jobStatusNotifier) sendCompletedNotification(
 	job config.Job,
 	conclusion string,
+	summary string,
+	output drake.JobOutput,
 ) error {
 	jobName := job.Name()
 	status := "completed"
-	blankSummary := ""
+	if output.ArtifactsURL != "" {
+		artifactsLink := fmt.Sprintf("[Artifacts](%s)", output.ArtifactsURL)
+		if summary == "" {
+			summary = artifactsLink
+		} else {
+			summary = fmt.Sprintf("%s\n\n%s", summary, artifactsLink)
+		}
+	}
+	run := github.CheckRun{
+		Name:    &jobName,
+		HeadSHA: &j.commit,
+		Output: &github.CheckRunOutput{
+			Title:   &jobName,
+			Summary: &summary,
+		},
+		Status:      &status,
+		CompletedAt: &github.Timestamp{Time: time.Now()},
+		Conclusion:  &conclusion,
+	}
+	if output.Logs != "" {
+		text := formatLogs(output.Logs)
+		run.Output.Text = &text
+	}
+	return j.notifyGithub(run)
+}
+
+func (j *jobStatusNotifier) SendPipelineInProgressNotification(
+	pipeline config.Pipeline,
+	matchResult drake.MatchResult,
+) error {
+	name := j.pipelineCheckName(pipeline)
+	status := "in_progress"
+	title := pipeline.Name()
+	summary := formatMatchResult(matchResult)
 	return j.notifyGithub(
 		github.CheckRun{
-			Name:    &jobName,
+			Name:      &name,
+			HeadSHA:   &j.commit,
+			StartedAt: &github.Timestamp{Time: time.Now()},
+			Output: &github.CheckRunOutput{
+				Title:   &title,
+				Summary: &summary,
+			},
+			Status: &status,
+		},
+	)
+}
+
+func (j *jobStatusNotifier) SendPipelineSuccessNotification(
+	pipeline config.Pipeline,
+	matchResult drake.MatchResult,
+	results []drake.JobResult,
+) error {
+	return j.sendPipelineCompletedNotification(
+		pipeline,
+		"success",
+		matchResult,
+		results,
+	)
+}
+
+func (j *jobStatusNotifier) SendPipelineFailureNotification(
+	pipeline config.Pipeline,
+	matchResult drake.MatchResult,
+	results []drake.JobResult,
+) error {
+	return j.sendPipelineCompletedNotification(
+		pipeline,
+		"failure",
+		matchResult,
+		results,
+	)
+}
+
+func (j *jobStatusNotifier) sendPipelineCompletedNotification(
+	pipeline config.Pipeline,
+	conclusion string,
+	matchResult drake.MatchResult,
+	results []drake.JobResult,
+) error {
+	name := j.pipelineCheckName(pipeline)
+	status := "completed"
+	title := pipeline.Name()
+	summary := fmt.Sprintf(
+		"%s\n\n%s",
+		formatMatchResult(matchResult),
+		formatJobResults(results),
+	)
+	return j.notifyGithub(
+		github.CheckRun{
+			Name:    &name,
 			HeadSHA: &j.commit,
 			Output: &github.CheckRunOutput{
-				Title:   &jobName,
-				Summary: &blankSummary,
+				Title:   &title,
+				Summary: &summary,
 			},
 			Status:      &status,
 			CompletedAt: &github.Timestamp{Time: time.Now()},
@@ -125,13 +269,151 @@ func (j *jobStatusNotifier) sendCompletedNotification(
 	)
 }
 
+const (
+	// pipelineCheckNamePrefix prefixes the pipeline's name in the default name
+	// of a pipeline's summary check run.
+	pipelineCheckNamePrefix = "drake/"
+	// validationCheckName is the name of the check run that reports an invalid
+	// Drakefile. Pipeline names can't contain a ".", so this can't be the
+	// default name of any pipeline's summary check run.
+	validationCheckName = pipelineCheckNamePrefix + "Drakefile.yaml"
+)
+
+// SendValidationFailureNotification implements
+// drake.ValidationFailureNotifier.
+func (j *jobStatusNotifier) SendValidationFailureNotification(
+	errs []error,
+) error {
+	name := validationCheckName
+	status := "completed"
+	conclusion := "failure"
+	title := "Drakefile is invalid"
+	var sb strings.Builder
+	sb.WriteString("No pipelines were executed because of the following ")
+	sb.WriteString("errors:\n\n")
+	for i, err := range errs {
+		fmt.Fprintf(&sb, "%d. %s\n", i+1, err)
+	}
+	summary := sb.String()
+	return j.notifyGithub(
+		github.CheckRun{
+			Name:    &name,
+			HeadSHA: &j.commit,
+			Output: &github.CheckRunOutput{
+				Title:   &title,
+				Summary: &summary,
+			},
+			Status:      &status,
+			CompletedAt: &github.Timestamp{Time: time.Now()},
+			Conclusion:  &conclusion,
+		},
+	)
+}
+
+// pipelineCheckName returns the name of the summary check run for the given
+// pipeline.
+func (j *jobStatusNotifier) pipelineCheckName(pipeline config.Pipeline) string {
+	if j.summaryCheckName != "" {
+		return j.summaryCheckName
+	}
+	return pipelineCheckNamePrefix + pipeline.Name()
+}
+
+// notifyGithub creates the given check run or, if a check run by the same name
+// was already created, updates that one.
 func (j *jobStatusNotifier) notifyGithub(run github.CheckRun) error {
-	req, err := j.githubClient.NewRequest("POST", j.checkRunsURL, run)
+	j.checkRunIDsMu.Lock()
+	id, exists := j.checkRunIDs[run.GetName()]
+	j.checkRunIDsMu.Unlock()
+	method := "POST"
+	url := j.checkRunsURL
+	if exists {
+		method = "PATCH"
+		url = fmt.Sprintf("%s/%d", j.checkRunsURL, id)
+		// A check run's head SHA cannot be updated
+		run.HeadSHA = nil
+	}
+	body := checkRun{CheckRun: run}
+	if j.detailsURL != "" {
+		body.DetailsURL = &j.detailsURL
+	}
+	req, err := j.githubClient.NewRequest(method, url, body)
 	if err != nil {
 		return err
 	}
 	// Turn on beta feature.
 	req.Header.Set("Accept", "application/vnd.github.antiope-preview+json")
-	_, err = j.githubClient.Do(context.TODO(), req, bytes.NewBuffer(nil))
-	return err
+	result := &github.CheckRun{}
+	if _, err = j.githubClient.Do(context.TODO(), req, result); err != nil {
+		return err
+	}
+	if !exists && result.ID != nil {
+		j.checkRunIDsMu.Lock()
+		defer j.checkRunIDsMu.Unlock()
+		if j.checkRunIDs == nil {
+			j.checkRunIDs = map[string]int64{}
+		}
+		j.checkRunIDs[run.GetName()] = *result.ID
+	}
+	return nil
+}
+
+// formatMatchResult explains, in markdown, why a pipeline was executed.
+func formatMatchResult(matchResult drake.MatchResult) string {
+	if matchResult.Selector == "" {
+		return fmt.Sprintf("Triggered because %s.", matchResult.Reason)
+	}
+	return fmt.Sprintf(
+		"Triggered by `%s` because %s.",
+		matchResult.Selector,
+		matchResult.Reason,
+	)
+}
+
+// formatJobResults formats the results of a pipeline's jobs as a markdown
+// table.
+func formatJobResults(results []drake.JobResult) string {
+	var sb strings.Builder
+	sb.WriteString("| Job | Result | Duration |\n")
+	sb.WriteString("|-----|--------|----------|\n")
+	for _, result := range results {
+		duration := "-"
+		if result.Conclusion != drake.JobConclusionSkipped {
+			duration = result.Duration.Round(time.Second).String()
+		}
+		fmt.Fprintf(
+			&sb,
+			"| %s | %s | %s |\n",
+			result.Job,
+			strings.Replace(string(result.Conclusion), "_", " ", -1),
+			duration,
+		)
+	}
+	return sb.String()
+}
+
+// formatLogs formats job logs as a markdown code block that fits within the
+// length GitHub permits for a check run's output text. If the logs are too
+// long, the beginning of the logs is truncated since the end of the logs is
+// most likely to explain a job's outcome.
+func formatLogs(logs string) string {
+	logs = strings.TrimRight(logs, "\n")
+	// The fence must be longer than any run of backticks within the logs
+	// themselves or else the logs could prematurely close the code block.
+	fence := "```"
+	for strings.Contains(logs, fence) {
+		fence += "`"
+	}
+	const truncationNotice = "_(Earlier output truncated)_\n\n"
+	maxLogsLength := maxCheckRunOutputTextLength - 2*len(fence) - 2
+	if len(logs) > maxLogsLength {
+		maxLogsLength -= len(truncationNotice)
+		logs = logs[len(logs)-maxLogsLength:]
+		// Don't begin with a partial line
+		if i := strings.Index(logs, "\n"); i >= 0 {
+			logs = logs[i+1:]
+		}
+		return fmt.Sprintf("%s%s\n%s\n%s", truncationNotice, fence, logs, fence)
+	}
+	return fmt.Sprintf("%s\n%s\n%s", fence, logs, fence)
 }
@@ -1,150 +1,474 @@
 package github
 
-// import (
-// 	"context"
-// 	"fmt"
-// 	"net/http"
-// 	"testing"
-
-// 	"github.com/google/go-github/github"
-// 	"github.com/lovethedrake/drakecore/config"
-// 	"github.com/stretchr/testify/require"
-// )
-
-// var (
-// 	repoName          = "foobar"
-// 	repoOwner         = "krancour"
-// 	headSHA           = "1234567"
-// 	installationToken = "fakeToken"
-// )
-
-// type fakeGithubClient struct {
-// 	t                  *testing.T
-// 	expectedConclusion string
-// }
-
-// func (f *fakeGithubClient) NewRequest(
-// 	_ string,
-// 	_ string,
-// 	body interface{},
-// ) (*http.Request, error) {
-// 	require.IsType(f.t, github.CheckRun{}, body)
-// 	run := body.(github.CheckRun)
-// 	require.NotNil(f.t, run.Status)
-// 	switch *run.Status {
-// 	case "in_progress":
-// 		require.Nil(f.t, run.Conclusion)
-// 	default:
-// 		require.NotNil(f.t, run.Conclusion)
-// 		require.Equal(f.t, f.expectedConclusion, *run.Conclusion)
-// 	}
-// 	return &http.Request{
-// 		Header: http.Header{},
-// 	}, nil
-// }
-
-// func (f *fakeGithubClient) Do(
-// 	context.Context,
-// 	*http.Request,
-// 	interface{},
-// ) (*github.Response, error) {
-// 	return nil, nil
-// }
-
-// type fakeJob struct {
-// 	name            string
-// 	containers      []config.Container
-// 	sourceMountMode config.SourceMountMode
-// }
-
-// func (f *fakeJob) Name() string {
-// 	return f.name
-// }
-
-// func (f *fakeJob) Containers() []config.Container {
-// 	return f.containers
-// }
-
-// func (f *fakeJob) SourceMountMode() config.SourceMountMode {
-// 	return f.sourceMountMode
-// }
-
-// func TestNewJobStatusNotifier(t *testing.T) {
-// 	jsnIFace, err := newJobStatusNotifier(
-// 		repoOwner,
-// 		repoName,
-// 		headSHA,
-// 		installationToken,
-// 	)
-// 	require.NoError(t, err)
-// 	require.IsType(t, &jobStatusNotifier{}, jsnIFace)
-// 	jsn := jsnIFace.(*jobStatusNotifier)
-// 	require.Equal(
-// 		t,
-// 		fmt.Sprintf("repos/%s/%s/check-runs", repoOwner, repoName),
-// 		jsn.checkRunsURL,
-// 	)
-// 	require.Equal(t, headSHA, jsn.commit)
-// 	require.NotNil(t, jsn.githubClient)
-// }
-
-// func TestSendNotifications(t *testing.T) {
-// 	jsnIFace, err := newJobStatusNotifier(
-// 		repoOwner,
-// 		repoName,
-// 		headSHA,
-// 		installationToken,
-// 	)
-// 	require.NoError(t, err)
-// 	require.IsType(t, &jobStatusNotifier{}, jsnIFace)
-// 	jsn := jsnIFace.(*jobStatusNotifier)
-
-// nolint: misspell
-// 	testCases := []struct {
-// 		name               string
-// 		notificationFn     func(job config.Job) error
-// 		expectedConclusion string
-// 	}{
-// 		{
-// 			name:               "in progress",
-// 			notificationFn:     jsn.SendInProgressNotification,
-// 			expectedConclusion: "in_progress",
-// 		},
-// 		{
-// 			name:               "success",
-// 			notificationFn:     jsn.SendSuccessNotification,
-// 			expectedConclusion: "success",
-// 		},
-// 		{
-// 			name:               "cancelled",
-// 			notificationFn:     jsn.SendCancelledNotification,
-// 			expectedConclusion: "cancelled",
-// 		},
-// 		{
-// 			name:               "timed out",
-// 			notificationFn:     jsn.SendTimedOutNotification,
-// 			expectedConclusion: "timed_out",
-// 		},
-// 		{
-// 			name:               "failure",
-// 			notificationFn:     jsn.SendFailureNotification,
-// 			expectedConclusion: "failure",
-// 		},
-// 	}
-
-// 	job := &fakeJob{
-// 		name: "fakeJobName",
-// 	}
-
-// 	for _, testCase := range testCases {
-// 		t.Run(testCase.name, func(t *testing.T) {
-// 			// Swap real github client for a fake one
-// 			jsn.githubClient = &fakeGithubClient{
-// 				t:                  t,
-// 				expectedConclusion: testCase.expectedConclusion,
-// 			}
-// 			err := testCase.notificationFn(job)
-// 			require.NoError(t, err)
-// 		})
-// 	}
-// }
+import (
+	"context"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/go-github/github"
+	"github.com/lovethedrake/brigdrake/pkg/drake"
+	"github.com/lovethedrake/drakecore/config"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/require"
+)
+
+const headSHA = "1234567"
+
+type fakeGithubClient struct {
+	runs    []checkRun
+	methods []string
+	urls    []string
+	nextID  int64
+}
+
+func (f *fakeGithubClient) NewRequest(
+	method string,
+	url string,
+	body interface{},
+) (*http.Request, error) {
+	f.runs = append(f.runs, body.(checkRun))
+	f.methods = append(f.methods, method)
+	f.urls = append(f.urls, url)
+	return &http.Request{
+		Method: method,
+		Header: http.Header{},
+	}, nil
+}
+
+func (f *fakeGithubClient) Do(
+	_ context.Context,
+	req *http.Request,
+	v interface{},
+) (*github.Response, error) {
+	// Like GitHub, assign an ID to each newly created check run
+	if run, ok := v.(*github.CheckRun); ok && req.Method == "POST" {
+		f.nextID++
+		run.ID = &f.nextID
+	}
+	return nil, nil
+}
+
+type fakeJob struct {
+	name string
+}
+
+func (f *fakeJob) Name() string {
+	return f.name
+}
+
+func (f *fakeJob) PrimaryContainer() config.Container {
+	return nil
+}
+
+func (f *fakeJob) SidecarContainers() []config.Container {
+	return nil
+}
+
+func (f *fakeJob) SourceMountMode() config.SourceMountMode {
+	return config.SourceMountModeReadOnly
+}
+
+func (f *fakeJob) OSFamily() config.OSFamily {
+	return config.OSFamilyLinux
+}
+
+func (f *fakeJob) CPUArch() config.CPUArch {
+	return config.CPUArchAMD64
+}
+
+type fakePipeline struct {
+	name string
+}
+
+func (f *fakePipeline) Name() string {
+	return f.name
+}
+
+func (f *fakePipeline) Triggers() []config.PipelineTrigger {
+	return nil
+}
+
+func (f *fakePipeline) Jobs() []config.PipelineJob {
+	return nil
+}
+
+func TestSendNotifications(t *testing.T) {
+	job := &fakeJob{
+		name: "foo",
+	}
+	output := drake.JobOutput{
+		Logs: "bar\n",
+	}
+	testCases := []struct {
+		name           string
+		notificationFn func(*jobStatusNotifier) error
+		assertions     func(*testing.T, checkRun)
+	}{
+		{
+			name: "queued",
+			notificationFn: func(jsn *jobStatusNotifier) error {
+				return jsn.SendQueuedNotification(job)
+			},
+			assertions: func(t *testing.T, run checkRun) {
+				require.Equal(t, "queued", run.GetStatus())
+				require.Nil(t, run.StartedAt)
+				require.Nil(t, run.Conclusion)
+			},
+		},
+		{
+			name: "in progress",
+			notificationFn: func(jsn *jobStatusNotifier) error {
+				return jsn.SendInProgressNotification(job, 1)
+			},
+			assertions: func(t *testing.T, run checkRun) {
+				require.Equal(t, "in_progress", run.GetStatus())
+				require.Nil(t, run.Conclusion)
+				require.Empty(t, run.Output.GetSummary())
+			},
+		},
+		{
+			name: "in progress on a later attempt",
+			notificationFn: func(jsn *jobStatusNotifier) error {
+				return jsn.SendInProgressNotification(job, 2)
+			},
+			assertions: func(t *testing.T, run checkRun) {
+				require.Equal(t, "in_progress", run.GetStatus())
+				require.Equal(t, "Attempt 2", run.Output.GetSummary())
+			},
+		},
+		{
+			name: "success",
+			notificationFn: func(jsn *jobStatusNotifier) error {
+				return jsn.SendSuccessNotification(job, output)
+			},
+			assertions: func(t *testing.T, run checkRun) {
+				require.Equal(t, "completed", run.GetStatus())
+				require.Equal(t, "success", run.GetConclusion())
+				require.Equal(t, "```\nbar\n```", run.Output.GetText())
+			},
+		},
+		{
+			name: "success with artifacts",
+			notificationFn: func(jsn *jobStatusNotifier) error {
+				return jsn.SendSuccessNotification(
+					job,
+					drake.JobOutput{
+						ArtifactsURL: "https://artifacts.example.com/foo/",
+					},
+				)
+			},
+			assertions: func(t *testing.T, run checkRun) {
+				require.Equal(t, "success", run.GetConclusion())
+				require.Equal(
+					t,
+					"[Artifacts](https://artifacts.example.com/foo/)",
+					run.Output.GetSummary(),
+				)
+			},
+		},
+		{
+			name: "cancelled",
+			notificationFn: func(jsn *jobStatusNotifier) error {
+				return jsn.SendCancelledNotification(job, output)
+			},
+			assertions: func(t *testing.T, run checkRun) {
+				require.Equal(t, "cancelled", run.GetConclusion())
+			},
+		},
+		{
+			name: "timed out",
+			notificationFn: func(jsn *jobStatusNotifier) error {
+				return jsn.SendTimedOutNotification(job, output)
+			},
+			assertions: func(t *testing.T, run checkRun) {
+				require.Equal(t, "timed_out", run.GetConclusion())
+			},
+		},
+		{
+			name: "failure",
+			notificationFn: func(jsn *jobStatusNotifier) error {
+				return jsn.SendFailureNotification(
+					job,
+					drake.JobFailure{
+						Reason:   "OOMKilled",
+						ExitCode: 137,
+					},
+					drake.JobOutput{},
+				)
+			},
+			assertions: func(t *testing.T, run checkRun) {
+				require.Equal(t, "failure", run.GetConclusion())
+				require.Equal(t, "OOMKilled (exit 137)", run.Output.GetSummary())
+				require.Nil(t, run.Output.Text)
+			},
+		},
+		{
+			name: "skipped",
+			notificationFn: func(jsn *jobStatusNotifier) error {
+				return jsn.SendSkippedNotification(job)
+			},
+			assertions: func(t *testing.T, run checkRun) {
+				require.Equal(t, "completed", run.GetStatus())
+				require.Equal(t, "skipped", run.GetConclusion())
+			},
+		},
+	}
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			githubClient := &fakeGithubClient{}
+			jsn := &jobStatusNotifier{
+				commit:       headSHA,
+				detailsURL:   "https://kashti.example.com/builds/foo",
+				githubClient: githubClient,
+			}
+			err := testCase.notificationFn(jsn)
+			require.NoError(t, err)
+			require.Len(t, githubClient.runs, 1)
+			run := githubClient.runs[0]
+			require.Equal(t, job.Name(), run.GetName())
+			require.Equal(t, headSHA, run.GetHeadSHA())
+			require.Equal(t, jsn.detailsURL, *run.DetailsURL)
+			testCase.assertions(t, run)
+		})
+	}
+}
+
+func TestCheckRunsAreUpdatedByID(t *testing.T) {
+	githubClient := &fakeGithubClient{}
+	jsn := newJobStatusNotifier(
+		githubClient,
+		"lovethedrake",
+		"brigdrake",
+		headSHA,
+		"",
+		"",
+	)
+	foo := &fakeJob{name: "foo"}
+	bar := &fakeJob{name: "bar"}
+	require.NoError(t, jsn.SendQueuedNotification(foo))
+	require.NoError(t, jsn.SendQueuedNotification(bar))
+	require.NoError(t, jsn.SendInProgressNotification(foo, 1))
+	require.NoError(t, jsn.SendSkippedNotification(bar))
+	require.NoError(t, jsn.SendSuccessNotification(foo, drake.JobOutput{}))
+	require.Equal(
+		t,
+		[]string{"POST", "POST", "PATCH", "PATCH", "PATCH"},
+		githubClient.methods,
+	)
+	require.Equal(
+		t,
+		[]string{
+			"repos/lovethedrake/brigdrake/check-runs",
+			"repos/lovethedrake/brigdrake/check-runs",
+			"repos/lovethedrake/brigdrake/check-runs/1",
+			"repos/lovethedrake/brigdrake/check-runs/2",
+			"repos/lovethedrake/brigdrake/check-runs/1",
+		},
+		githubClient.urls,
+	)
+	require.Equal(t, headSHA, githubClient.runs[0].GetHeadSHA())
+	// The head SHA of an existing check run cannot be updated
+	require.Nil(t, githubClient.runs[2].HeadSHA)
+}
+
+func TestSendPipelineNotifications(t *testing.T) {
+	pipeline := &fakePipeline{
+		name: "ci",
+	}
+	matchResult := drake.Match(
+		"push.branches",
+		"branch master matches only-list entry master",
+	)
+	results := []drake.JobResult{
+		{
+			Job:        "foo",
+			Conclusion: drake.JobConclusionSuccess,
+			Duration:   90 * time.Second,
+		},
+	}
+	testCases := []struct {
+		name             string
+		summaryCheckName string
+		notificationFn   func(*jobStatusNotifier) error
+		assertions       func(*testing.T, checkRun)
+	}{
+		{
+			name: "in progress",
+			notificationFn: func(jsn *jobStatusNotifier) error {
+				return jsn.SendPipelineInProgressNotification(
+					pipeline,
+					matchResult,
+				)
+			},
+			assertions: func(t *testing.T, run checkRun) {
+				require.Equal(t, "drake/ci", run.GetName())
+				require.Equal(t, "in_progress", run.GetStatus())
+				require.Nil(t, run.Conclusion)
+				require.Equal(
+					t,
+					"Triggered by `push.branches` because branch master matches "+
+						"only-list entry master.",
+					run.Output.GetSummary(),
+				)
+			},
+		},
+		{
+			name:             "in progress with summary check name",
+			summaryCheckName: "required",
+			notificationFn: func(jsn *jobStatusNotifier) error {
+				return jsn.SendPipelineInProgressNotification(
+					pipeline,
+					matchResult,
+				)
+			},
+			assertions: func(t *testing.T, run checkRun) {
+				require.Equal(t, "required", run.GetName())
+			},
+		},
+		{
+			name: "success",
+			notificationFn: func(jsn *jobStatusNotifier) error {
+				return jsn.SendPipelineSuccessNotification(
+					pipeline,
+					matchResult,
+					results,
+				)
+			},
+			assertions: func(t *testing.T, run checkRun) {
+				require.Equal(t, "drake/ci", run.GetName())
+				require.Equal(t, "completed", run.GetStatus())
+				require.Equal(t, "success", run.GetConclusion())
+				require.Contains(
+					t,
+					run.Output.GetSummary(),
+					"because branch master matches only-list entry master",
+				)
+				require.Contains(
+					t,
+					run.Output.GetSummary(),
+					"| foo | success | 1m30s |",
+				)
+			},
+		},
+		{
+			name:             "failure with summary check name",
+			summaryCheckName: "required",
+			notificationFn: func(jsn *jobStatusNotifier) error {
+				return jsn.SendPipelineFailureNotification(
+					pipeline,
+					matchResult,
+					results,
+				)
+			},
+			assertions: func(t *testing.T, run checkRun) {
+				require.Equal(t, "required", run.GetName())
+				require.Equal(t, "failure", run.GetConclusion())
+			},
+		},
+	}
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			githubClient := &fakeGithubClient{}
+			jsn := &jobStatusNotifier{
+				commit:           headSHA,
+				summaryCheckName: testCase.summaryCheckName,
+				githubClient:     githubClient,
+			}
+			err := testCase.notificationFn(jsn)
+			require.NoError(t, err)
+			require.Len(t, githubClient.runs, 1)
+			run := githubClient.runs[0]
+			require.Equal(t, headSHA, run.GetHeadSHA())
+			require.Equal(t, pipeline.Name(), run.Output.GetTitle())
+			testCase.assertions(t, run)
+		})
+	}
+}
+
+func TestSendValidationFailureNotification(t *testing.T) {
+	githubClient := &fakeGithubClient{}
+	jsn := &jobStatusNotifier{
+		commit:       headSHA,
+		githubClient: githubClient,
+	}
+	err := jsn.SendValidationFailureNotification(
+		[]error{errors.New("foo"), errors.New("bar")},
+	)
+	require.NoError(t, err)
+	require.Len(t, githubClient.runs, 1)
+	run := githubClient.runs[0]
+	require.Equal(t, "drake/Drakefile.yaml", run.GetName())
+	require.Equal(t, headSHA, run.GetHeadSHA())
+	require.Equal(t, "completed", run.GetStatus())
+	require.Equal(t, "failure", run.GetConclusion())
+	require.Contains(t, run.Output.GetSummary(), "1. foo\n2. bar\n")
+}
+
+func TestFormatJobResults(t *testing.T) {
+	require.Equal(
+		t,
+		"| Job | Result | Duration |\n"+
+			"|-----|--------|----------|\n"+
+			"| lint | success | 12s |\n"+
+			"| test | timed out | 10m0s |\n"+
+			"| publish | skipped | - |\n",
+		formatJobResults(
+			[]drake.JobResult{
+				{
+					Job:        "lint",
+					Conclusion: drake.JobConclusionSuccess,
+					Duration:   12400 * time.Millisecond,
+				},
+				{
+					Job:        "test",
+					Conclusion: drake.JobConclusionTimedOut,
+					Duration:   10 * time.Minute,
+				},
+				{
+					Job:        "publish",
+					Conclusion: drake.JobConclusionSkipped,
+				},
+			},
+		),
+	)
+}
+
+func TestFormatLogs(t *testing.T) {
+	testCases := []struct {
+		name       string
+		logs       string
+		assertions func(*testing.T, string)
+	}{
+		{
+			name: "short logs",
+			logs: "foo\nbar\n",
+			assertions: func(t *testing.T, text string) {
+				require.Equal(t, "```\nfoo\nbar\n```", text)
+			},
+		},
+		{
+			name: "logs containing a fence",
+			logs: "foo\n```\nbar",
+			assertions: func(t *testing.T, text string) {
+				require.Equal(t, "````\nfoo\n```\nbar\n````", text)
+			},
+		},
+		{
+			name: "long logs",
+			logs: "first\n" + strings.Repeat("foo\n", 20000) + "last",
+			assertions: func(t *testing.T, text string) {
+				require.True(t, len(text) <= maxCheckRunOutputTextLength)
+				require.True(t, strings.HasPrefix(text, "_(Earlier output"))
+				require.NotContains(t, text, "first")
+				require.True(t, strings.HasSuffix(text, "foo\nlast\n```"))
+			},
+		},
+	}
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			testCase.assertions(t, formatLogs(testCase.logs))
+		})
+	}
+}
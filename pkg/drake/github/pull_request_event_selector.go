@@ -2,37 +2,285 @@ package github
 
 import (
 	"encoding/json"
-	"log"
+	"fmt"
 
 	"github.com/google/go-github/github"
 	"github.com/lovethedrake/brigdrake/pkg/brigade"
+	"github.com/lovethedrake/brigdrake/pkg/drake"
 	"github.com/pkg/errors"
 )
 
+// nolint: lll
 type pullRequestEventSelector struct {
-	TargetBranchSelector *refSelector `json:"targetBranches,omitempty"`
+	TargetBranchSelector *refSelector   `json:"targetBranches,omitempty"`
+	SourceBranchSelector *refSelector   `json:"sourceBranches,omitempty"`
+	PathSelector         *refSelector   `json:"paths,omitempty"`
+	LabelSelector        *labelSelector `json:"labels,omitempty"`
+	// AuthorAssociations, if non-empty, enumerates the relationships to the
+	// repository (e.g. OWNER, MEMBER, COLLABORATOR, or CONTRIBUTOR) that a pull
+	// request's author must have for the pull request to be selected.
+	AuthorAssociations []string `json:"authorAssociations,omitempty"`
+	IgnoreDrafts       bool     `json:"ignoreDrafts,omitempty"`
+}
+
+// labelSelector selects pull requests based on their labels. Labels may be
+// specified literally or as /regular expressions/.
+type labelSelector struct {
+	// RequiredLabels must ALL be applied to a pull request for it to be
+	// selected. When any of these is newly applied to a pull request, that
+	// alone is cause for the pull request to be (re-)evaluated.
+	RequiredLabels []string `json:"required,omitempty"`
+	// ForbiddenLabels must NOT be applied to a pull request for it to be
+	// selected.
+	ForbiddenLabels []string `json:"forbidden,omitempty"`
+}
+
+// pullRequestDraftStatus is used for unmarshaling the draft status of a pull
+// request, which the github.PullRequest type does not (yet) define.
+type pullRequestDraftStatus struct {
+	PullRequest struct {
+		Draft bool `json:"draft"`
+	} `json:"pull_request"`
 }
 
 func (p *pullRequestEventSelector) matches(
+	project brigade.Project,
 	event brigade.Event,
-) (bool, error) {
+	githubClientFn githubClientFn,
+) (drake.MatchResult, error) {
 	if p.TargetBranchSelector == nil {
-		log.Printf(
-			"check suite request event does not match nil target branch selector",
-		)
-		return false, nil
+		return drake.NoMatch(
+			"pullRequest.targetBranches",
+			"no target branch selector configured",
+		), nil
 	}
 	pre := github.PullRequestEvent{}
 	if err := json.Unmarshal(event.Payload, &pre); err != nil {
-		return false, errors.Wrap(err, "error unmarshaling event payload")
+		return drake.MatchResult{},
+			errors.Wrap(err, "error unmarshaling event payload")
+	}
+	draftStatus := pullRequestDraftStatus{}
+	if err := json.Unmarshal(event.Payload, &draftStatus); err != nil {
+		return drake.MatchResult{},
+			errors.Wrap(err, "error unmarshaling event payload")
+	}
+	var newLabel string
+	if event.Type == "pull_request:labeled" {
+		newLabel = pre.GetLabel().GetName()
+	}
+	pr := &pullRequest{Draft: draftStatus.PullRequest.Draft}
+	if pre.PullRequest != nil {
+		pr.PullRequest = *pre.PullRequest
+	}
+	return p.matchesPullRequest(
+		project,
+		pre.GetInstallation().GetID(),
+		pr,
+		newLabel,
+		githubClientFn,
+	)
+}
+
+// matchesPullRequest determines whether the given pull request is selected. If
+// a label was just applied to the pull request, it must also be given, since
+// not every new label is cause for the pull request to be evaluated.
+func (p *pullRequestEventSelector) matchesPullRequest(
+	project brigade.Project,
+	installationID int64,
+	pr *pullRequest,
+	newLabel string,
+	githubClientFn githubClientFn,
+) (drake.MatchResult, error) {
+	if p.IgnoreDrafts && pr.Draft {
+		return drake.NoMatch(
+			"pullRequest.ignoreDrafts",
+			"pull request is a draft",
+		), nil
 	}
-	branch := *pre.PullRequest.Base.Ref
-	match, err := p.TargetBranchSelector.matches(branch)
+	if newLabel != "" {
+		newLabelMatches, reason, err := p.LabelSelector.matchesNewLabel(newLabel)
+		if err != nil {
+			return drake.MatchResult{}, err
+		}
+		if !newLabelMatches {
+			return drake.NoMatch("pullRequest.labels", "%s", reason), nil
+		}
+	}
+	labelsMatch, reason, err := p.LabelSelector.matches(pr.Labels)
+	if err != nil {
+		return drake.MatchResult{}, err
+	}
+	if !labelsMatch {
+		return drake.NoMatch("pullRequest.labels", "%s", reason), nil
+	}
+	authorAssociation := pr.GetAuthorAssociation()
+	if !p.matchesAuthorAssociation(authorAssociation) {
+		return drake.NoMatch(
+			"pullRequest.authorAssociations",
+			"author association %q not in %v",
+			authorAssociation,
+			p.AuthorAssociations,
+		), nil
+	}
+	if p.SourceBranchSelector != nil {
+		sourceBranch := pr.GetHead().GetRef()
+		match, reason, err := p.SourceBranchSelector.matches(sourceBranch)
+		if err != nil {
+			return drake.MatchResult{}, errors.Wrapf(
+				err,
+				"error matching branch %q to source branch selector",
+				sourceBranch,
+			)
+		}
+		if !match {
+			return drake.NoMatch(
+				"pullRequest.sourceBranches",
+				"source branch %s %s",
+				sourceBranch,
+				reason,
+			), nil
+		}
+	}
+	result, err := p.matchesTargetBranch(pr.GetBase().GetRef())
+	if err != nil || !result.Matched || p.PathSelector == nil {
+		return result, err
+	}
+	// The pull request doesn't include the files that were changed, so we have
+	// to ask GitHub for them.
+	githubClient, err := githubClientFn(project, installationID)
 	if err != nil {
-		return false, errors.Wrap(
+		return drake.MatchResult{}, errors.Wrap(
+			err,
+			"error creating github client for listing pull request files",
+		)
+	}
+	repo := pr.GetBase().GetRepo()
+	files, err := listPullRequestFiles(
+		githubClient,
+		repo.GetOwner().GetLogin(),
+		repo.GetName(),
+		pr.GetNumber(),
+	)
+	if err != nil {
+		return drake.MatchResult{}, err
+	}
+	match, reason, err := p.PathSelector.matchesAny(files)
+	if err != nil {
+		return drake.MatchResult{},
+			errors.Wrap(err, "error matching files to path selector")
+	}
+	if !match {
+		return drake.NoMatch(
+			"pullRequest.paths",
+			"none of the %d files changed by the pull request are selected",
+			len(files),
+		), nil
+	}
+	return drake.Match("pullRequest.paths", "%s", reason), nil
+}
+
+// matchesTargetBranch determines whether a pull request targeting the given
+// branch is selected.
+func (p *pullRequestEventSelector) matchesTargetBranch(
+	branch string,
+) (drake.MatchResult, error) {
+	if p.TargetBranchSelector == nil {
+		return drake.NoMatch(
+			"pullRequest.targetBranches",
+			"no target branch selector configured",
+		), nil
+	}
+	match, reason, err := p.TargetBranchSelector.matches(branch)
+	if err != nil {
+		return drake.MatchResult{}, errors.Wrapf(
 			err,
 			"error matching branch %q to target branch selector",
+			branch,
 		)
 	}
-	return match, nil
+	return drake.MatchResult{
+		Matched:  match,
+		Selector: "pullRequest.targetBranches",
+		Reason:   fmt.Sprintf("target branch %s %s", branch, reason),
+	}, nil
+}
+
+// matchesAuthorAssociation returns true if a pull request whose author has the
+// given relationship to the repository is selected.
+func (p *pullRequestEventSelector) matchesAuthorAssociation(
+	authorAssociation string,
+) bool {
+	return len(p.AuthorAssociations) == 0 ||
+		authorAssociationAllowed(p.AuthorAssociations, authorAssociation)
+}
+
+// matches returns true if a pull request with the given labels is selected. If
+// it is not, it also returns the reason why. A nil labelSelector selects all
+// pull requests.
+func (l *labelSelector) matches(
+	labels []*github.Label,
+) (bool, string, error) {
+	if l == nil {
+		return true, "", nil
+	}
+	for _, requiredLabel := range l.RequiredLabels {
+		match, err := anyLabelMatches(labels, requiredLabel)
+		if err != nil {
+			return false, "", err
+		}
+		if !match {
+			return false,
+				fmt.Sprintf("missing required label %s", requiredLabel),
+				nil
+		}
+	}
+	for _, forbiddenLabel := range l.ForbiddenLabels {
+		match, err := anyLabelMatches(labels, forbiddenLabel)
+		if err != nil {
+			return false, "", err
+		}
+		if match {
+			return false, fmt.Sprintf("has forbidden label %s", forbiddenLabel), nil
+		}
+	}
+	return true, "", nil
+}
+
+// matchesNewLabel returns true if the given label, having just been applied to
+// a pull request, is cause for the pull request to be evaluated. This is only
+// the case if it is one of the required labels. Otherwise, applying any label
+// at all to a pull request would cause every pipeline to be re-run. If the
+// label is not cause for evaluation, the reason why is also returned.
+func (l *labelSelector) matchesNewLabel(label string) (bool, string, error) {
+	if l == nil {
+		return false,
+			fmt.Sprintf("new label %s with no label selector configured", label),
+			nil
+	}
+	for _, requiredLabel := range l.RequiredLabels {
+		match, err := refMatch(label, requiredLabel)
+		if err != nil {
+			return false, "", err
+		}
+		if match {
+			return true, "", nil
+		}
+	}
+	return false, fmt.Sprintf("new label %s is not a required label", label), nil
+}
+
+func anyLabelMatches(
+	labels []*github.Label,
+	valueOrPattern string,
+) (bool, error) {
+	for _, label := range labels {
+		match, err := refMatch(label.GetName(), valueOrPattern)
+		if err != nil {
+			return false, err
+		}
+		if match {
+			return true, nil
+		}
+	}
+	return false, nil
 }
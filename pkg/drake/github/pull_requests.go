@@ -0,0 +1,124 @@
+package github
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/google/go-github/github"
+	"github.com/pkg/errors"
+)
+
+// pullRequestFilesPerPage is the maximum page size the GitHub API permits
+// when listing a pull request's files.
+const pullRequestFilesPerPage = 100
+
+// pullRequestFile represents the subset of the fields of a file changed by a
+// pull request that we use. (The github.CommitFile type does not yet define
+// previous_filename.)
+type pullRequestFile struct {
+	Filename         string `json:"filename"`
+	PreviousFilename string `json:"previous_filename,omitempty"`
+}
+
+// listPullRequestFiles returns the names of all files changed by the specified
+// pull request. Files that were renamed are listed under both their old and
+// new names.
+func listPullRequestFiles(
+	githubClient simpleGithubClient,
+	repoOwner string,
+	repoName string,
+	number int,
+) ([]string, error) {
+	fileNames := []string{}
+	for page := 1; page != 0; {
+		req, err := githubClient.NewRequest(
+			"GET",
+			fmt.Sprintf(
+				"repos/%s/%s/pulls/%d/files?per_page=%d&page=%d",
+				repoOwner,
+				repoName,
+				number,
+				pullRequestFilesPerPage,
+				page,
+			),
+			nil,
+		)
+		if err != nil {
+			return nil, errors.Wrap(
+				err,
+				"error creating request for pull request files",
+			)
+		}
+		files := []pullRequestFile{}
+		resp, err := githubClient.Do(context.TODO(), req, &files)
+		if err != nil {
+			return nil, errors.Wrapf(
+				err,
+				"error listing files for pull request %d",
+				number,
+			)
+		}
+		for _, file := range files {
+			fileNames = append(fileNames, file.Filename)
+			if file.PreviousFilename != "" {
+				fileNames = append(fileNames, file.PreviousFilename)
+			}
+		}
+		page = 0
+		if resp != nil {
+			page = resp.NextPage
+		}
+	}
+	return fileNames, nil
+}
+
+// pullRequest is used for unmarshaling a pull request together with its draft
+// status, which the github.PullRequest type does not (yet) define.
+type pullRequest struct {
+	github.PullRequest
+	Draft bool `json:"draft"`
+}
+
+// getPullRequest returns the specified pull request.
+func getPullRequest(
+	githubClient simpleGithubClient,
+	repoOwner string,
+	repoName string,
+	number int,
+) (*pullRequest, error) {
+	req, err := githubClient.NewRequest(
+		"GET",
+		fmt.Sprintf("repos/%s/%s/pulls/%d", repoOwner, repoName, number),
+		nil,
+	)
+	if err != nil {
+		return nil, errors.Wrap(err, "error creating request for pull request")
+	}
+	pr := &pullRequest{}
+	if _, err = githubClient.Do(context.TODO(), req, pr); err != nil {
+		return nil, errors.Wrapf(err, "error getting pull request %d", number)
+	}
+	return pr, nil
+}
+
+// getPullRequestHeadSHA returns the SHA of the commit currently at the head of
+// the specified pull request.
+func getPullRequestHeadSHA(
+	githubClient simpleGithubClient,
+	repoOwner string,
+	repoName string,
+	number int,
+) (string, error) {
+	pr, err := getPullRequest(githubClient, repoOwner, repoName, number)
+	if err != nil {
+		return "", err
+	}
+	sha := pr.GetHead().GetSHA()
+	if sha == "" {
+		return "", errors.Errorf(
+			"could not determine head SHA of pull request %d",
+			number,
+		)
+	}
+	return sha, nil
+}
@@ -2,11 +2,12 @@ package github
 
 import (
 	"encoding/json"
-	"log"
+	"fmt"
 	"regexp"
 
 	"github.com/google/go-github/github"
 	"github.com/lovethedrake/brigdrake/pkg/brigade"
+	"github.com/lovethedrake/brigdrake/pkg/drake"
 	"github.com/pkg/errors"
 )
 
@@ -17,43 +18,128 @@ var (
 
 type pushEventSelector struct {
 	BranchSelector *refSelector `json:"branches,omitempty"`
-	TagSelector    *refSelector `json:"tags,omitempty"`
+	TagSelector    *tagSelector `json:"tags,omitempty"`
+	PathSelector   *refSelector `json:"paths,omitempty"`
 }
 
-func (p *pushEventSelector) matches(event brigade.Event) (bool, error) {
+// validate returns an error if the selector's tag selector is invalid.
+func (p *pushEventSelector) validate() error {
+	if p.TagSelector == nil {
+		return nil
+	}
+	return errors.Wrap(
+		p.TagSelector.validate(),
+		"error validating tag selector",
+	)
+}
+
+func (p *pushEventSelector) matches(
+	event brigade.Event,
+) (drake.MatchResult, error) {
 	pe := github.PushEvent{}
 	if err := json.Unmarshal(event.Payload, &pe); err != nil {
-		return false, errors.Wrap(err, "error unmarshaling event payload")
+		return drake.MatchResult{},
+			errors.Wrap(err, "error unmarshaling event payload")
+	}
+	// Pushes that delete a ref have no commits to execute pipelines against
+	if pe.GetDeleted() {
+		return drake.NoMatch("push", "ref %s was deleted", pe.GetRef()), nil
+	}
+	result, err := p.matchesRef(pe.GetRef())
+	if err != nil || !result.Matched || p.PathSelector == nil {
+		return result, err
+	}
+	return p.matchesFiles(pushedFiles(pe))
+}
+
+// matchesFiles determines whether a push that changed the given files is
+// selected by the path selector, which must not be nil.
+func (p *pushEventSelector) matchesFiles(
+	files []string,
+) (drake.MatchResult, error) {
+	match, reason, err := p.PathSelector.matchesAny(files)
+	if err != nil {
+		return drake.MatchResult{},
+			errors.Wrap(err, "error matching files to path selector")
+	}
+	if !match {
+		return drake.NoMatch(
+			"push.paths",
+			"none of the %d files changed by the push are selected",
+			len(files),
+		), nil
 	}
-	var fullRef string
-	if pe.Ref != nil {
-		fullRef = *pe.Ref
+	return drake.Match("push.paths", "%s", reason), nil
+}
+
+// pushedFiles returns the distinct names of all files added, modified, or
+// removed by the commits included in a push.
+func pushedFiles(pe github.PushEvent) []string {
+	files := []string{}
+	seen := map[string]struct{}{}
+	for _, commit := range pe.Commits {
+		for _, fileNames := range [][]string{
+			commit.Added,
+			commit.Modified,
+			commit.Removed,
+		} {
+			for _, fileName := range fileNames {
+				if _, ok := seen[fileName]; !ok {
+					seen[fileName] = struct{}{}
+					files = append(files, fileName)
+				}
+			}
+		}
+	}
+	return files
+}
+
+// matchesRef determines whether a push to the given fully-qualified ref (e.g.
+// refs/heads/master) is selected.
+func (p *pushEventSelector) matchesRef(
+	fullRef string,
+) (drake.MatchResult, error) {
+	// Note that a nil *refSelector or *tagSelector must not be assigned to this,
+	// lest it become a non-nil interface wrapping a nil pointer.
+	var selector interface {
+		matches(string) (bool, string, error)
 	}
-	var refSelector *refSelector
-	var ref string
+	var selectorName, kind, ref string
 	if refSubmatches :=
 		branchRefRegex.FindStringSubmatch(fullRef); len(refSubmatches) == 2 {
-		refSelector = p.BranchSelector
+		if p.BranchSelector != nil {
+			selector = p.BranchSelector
+		}
+		selectorName = "push.branches"
+		kind = "branch"
 		ref = refSubmatches[1]
-	}
-	if refSelector == nil {
-		if refSubmatches :=
-			tagRefRegex.FindStringSubmatch(fullRef); len(refSubmatches) == 2 {
-			refSelector = p.TagSelector
-			ref = refSubmatches[1]
+	} else if refSubmatches :=
+		tagRefRegex.FindStringSubmatch(fullRef); len(refSubmatches) == 2 {
+		if p.TagSelector != nil {
+			selector = p.TagSelector
 		}
+		selectorName = "push.tags"
+		kind = "tag"
+		ref = refSubmatches[1]
 	}
-	if refSelector == nil {
-		log.Printf("no applicable selector found for ref %q", fullRef)
-		return false, nil
+	if selector == nil {
+		return drake.NoMatch(
+			selectorName,
+			"no applicable selector configured for ref %s",
+			fullRef,
+		), nil
 	}
-	match, err := refSelector.matches(ref)
+	match, reason, err := selector.matches(ref)
 	if err != nil {
-		return false, errors.Wrapf(
+		return drake.MatchResult{}, errors.Wrapf(
 			err,
 			"error matching ref %q to selector",
 			fullRef,
 		)
 	}
-	return match, nil
+	return drake.MatchResult{
+		Matched:  match,
+		Selector: selectorName,
+		Reason:   fmt.Sprintf("%s %s %s", kind, ref, reason),
+	}, nil
 }
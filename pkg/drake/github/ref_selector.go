@@ -1,6 +1,7 @@
 package github
 
 import (
+	"fmt"
 	"regexp"
 	"strings"
 
@@ -12,16 +13,20 @@ type refSelector struct {
 	BlacklistedRefs []string `json:"ignore,omitempty"`
 }
 
-func (r *refSelector) matches(ref string) (bool, error) {
+// matches returns true if the given ref is selected. In either case, it also
+// returns the reason, e.g. "not in only-list [master]". Callers prefix the
+// reason with a description of the ref, e.g. "branch foo".
+func (r *refSelector) matches(ref string) (bool, string, error) {
 	var matchesWhitelist bool
+	var whitelistedRef string
 	if len(r.WhitelistedRefs) == 0 {
 		matchesWhitelist = true
 	} else {
-		for _, whitelistedRef := range r.WhitelistedRefs {
+		for _, whitelistedRef = range r.WhitelistedRefs {
 			var err error
 			matchesWhitelist, err = refMatch(ref, whitelistedRef)
 			if err != nil {
-				return false, err
+				return false, "", err
 			}
 			if matchesWhitelist {
 				break
@@ -29,17 +34,32 @@ func (r *refSelector) matches(ref string) (bool, error) {
 		}
 	}
 	var matchesBlacklist bool
-	for _, blacklistedRef := range r.BlacklistedRefs {
+	var blacklistedRef string
+	for _, blacklistedRef = range r.BlacklistedRefs {
 		var err error
 		matchesBlacklist, err = refMatch(ref, blacklistedRef)
 		if err != nil {
-			return false, err
+			return false, "", err
 		}
 		if matchesBlacklist {
 			break
 		}
 	}
-	return matchesWhitelist && !matchesBlacklist, nil
+	switch {
+	case !matchesWhitelist:
+		return false, fmt.Sprintf("not in only-list %v", r.WhitelistedRefs), nil
+	case matchesBlacklist:
+		return false, fmt.Sprintf("matches ignore-list entry %s", blacklistedRef),
+			nil
+	case len(r.WhitelistedRefs) > 0:
+		return true, fmt.Sprintf("matches only-list entry %s", whitelistedRef),
+			nil
+	case len(r.BlacklistedRefs) > 0:
+		return true, fmt.Sprintf("not in ignore-list %v", r.BlacklistedRefs), nil
+	default:
+		return true, "selected by selector with no only-list or ignore-list",
+			nil
+	}
 }
 
 func refMatch(ref, valueOrPattern string) (bool, error) {
@@ -58,3 +78,28 @@ func refMatch(ref, valueOrPattern string) (bool, error) {
 	}
 	return ref == valueOrPattern, nil
 }
+
+// matchesAny returns true if any one of the given values is selected. This is
+// used for matching paths, where a single selected path among all of those
+// that were changed is sufficient for a match. If no values are given, none
+// can be in the only-list, so the result is false if there is one. Otherwise,
+// there is nothing to ignore, so the result is true. When a value is selected,
+// the reason is also returned, prefixed with the value.
+func (r *refSelector) matchesAny(values []string) (bool, string, error) {
+	if len(values) == 0 {
+		if len(r.WhitelistedRefs) > 0 {
+			return false, "", nil
+		}
+		return true, "no values to filter", nil
+	}
+	for _, value := range values {
+		match, reason, err := r.matches(value)
+		if err != nil {
+			return false, "", err
+		}
+		if match {
+			return true, fmt.Sprintf("%s %s", value, reason), nil
+		}
+	}
+	return false, "", nil
+}
@@ -0,0 +1,52 @@
+package github
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/google/go-github/github"
+	"github.com/lovethedrake/brigdrake/pkg/brigade"
+	"github.com/lovethedrake/brigdrake/pkg/drake"
+	"github.com/pkg/errors"
+)
+
+const releasePublishedEventType = "release:published"
+
+type releaseEventSelector struct {
+	TagSelector *tagSelector `json:"tags,omitempty"`
+}
+
+// validate returns an error if the selector's tag selector is invalid.
+func (r *releaseEventSelector) validate() error {
+	if r.TagSelector == nil {
+		return nil
+	}
+	return errors.Wrap(
+		r.TagSelector.validate(),
+		"error validating tag selector",
+	)
+}
+
+func (r *releaseEventSelector) matches(
+	event brigade.Event,
+) (drake.MatchResult, error) {
+	if r.TagSelector == nil {
+		return drake.NoMatch("release.tags", "no tag selector configured"), nil
+	}
+	re := github.ReleaseEvent{}
+	if err := json.Unmarshal(event.Payload, &re); err != nil {
+		return drake.MatchResult{},
+			errors.Wrap(err, "error unmarshaling event payload")
+	}
+	tag := re.GetRelease().GetTagName()
+	match, reason, err := r.TagSelector.matches(tag)
+	if err != nil {
+		return drake.MatchResult{},
+			errors.Wrapf(err, "error matching tag %q to selector", tag)
+	}
+	return drake.MatchResult{
+		Matched:  match,
+		Selector: "release.tags",
+		Reason:   fmt.Sprintf("tag %s %s", tag, reason),
+	}, nil
+}
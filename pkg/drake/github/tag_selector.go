@@ -0,0 +1,100 @@
+package github
+
+import (
+	"fmt"
+
+	"github.com/lovethedrake/brigdrake/pkg/semver"
+	"github.com/pkg/errors"
+)
+
+const (
+	// prereleasesInclude indicates that tags that are semantic versions with a
+	// pre-release component are selected just like any other tags
+	prereleasesInclude = "include"
+	// prereleasesExclude indicates that tags that are semantic versions with a
+	// pre-release component are never selected
+	prereleasesExclude = "exclude"
+	// prereleasesOnly indicates that ONLY tags that are semantic versions with
+	// a pre-release component may be selected
+	prereleasesOnly = "only"
+)
+
+// tagSelector is a refSelector that additionally understands tags that are
+// semantic versions.
+type tagSelector struct {
+	refSelector
+	// SemverConstraint, if specified, is a constraint such as ">=1.0.0 <2.0.0"
+	// that a tag, parsed as a semantic version, must satisfy. Tags that are not
+	// semantic versions do not satisfy any constraint.
+	SemverConstraint string `json:"semver,omitempty"`
+	// Prereleases specifies how tags that are semantic versions with a
+	// pre-release component are treated. Valid values are "include" (the
+	// default), "exclude", and "only".
+	Prereleases string `json:"prereleases,omitempty"`
+}
+
+// validate returns an error if the selector's pre-releases value is not
+// recognized or its semantic version constraint is invalid.
+func (t *tagSelector) validate() error {
+	switch t.Prereleases {
+	case "", prereleasesInclude, prereleasesExclude, prereleasesOnly:
+	default:
+		return errors.Errorf(
+			"unrecognized prereleases value %q; valid values are %q, %q, and %q",
+			t.Prereleases,
+			prereleasesInclude,
+			prereleasesExclude,
+			prereleasesOnly,
+		)
+	}
+	if t.SemverConstraint != "" {
+		if _, err := semver.ParseConstraint(t.SemverConstraint); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// matches returns true if the given tag is selected. In either case, it also
+// returns the reason. Callers prefix the reason with a description of the tag.
+func (t *tagSelector) matches(tag string) (bool, string, error) {
+	match, reason, err := t.refSelector.matches(tag)
+	if err != nil || !match {
+		return false, reason, err
+	}
+	if err = t.validate(); err != nil {
+		return false, "", err
+	}
+	if t.SemverConstraint == "" &&
+		(t.Prereleases == "" || t.Prereleases == prereleasesInclude) {
+		return true, reason, nil
+	}
+	version, err := semver.ParseVersion(tag)
+	if err != nil {
+		return false, "is not a semantic version", nil
+	}
+	if t.Prereleases == prereleasesExclude && version.IsPrerelease() {
+		return false, "is a pre-release and pre-releases are excluded", nil
+	}
+	if t.Prereleases == prereleasesOnly && !version.IsPrerelease() {
+		return false, "is not a pre-release and only pre-releases are selected",
+			nil
+	}
+	if t.SemverConstraint == "" {
+		return true, reason, nil
+	}
+	constraint, err := semver.ParseConstraint(t.SemverConstraint)
+	if err != nil {
+		return false, "", err
+	}
+	if !constraint.Check(version) {
+		return false, fmt.Sprintf(
+			"does not satisfy semantic version constraint %q",
+			t.SemverConstraint,
+		), nil
+	}
+	return true, fmt.Sprintf(
+		"satisfies semantic version constraint %q",
+		t.SemverConstraint,
+	), nil
+}
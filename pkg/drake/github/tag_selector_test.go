@@ -0,0 +1,107 @@
+package github
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestTagSelectorMatches(t *testing.T) {
+	testCases := []struct {
+		name     string
+		selector tagSelector
+		tag      string
+		expected bool
+		errMsg   string
+	}{
+		{
+			name:     "no constraints",
+			selector: tagSelector{},
+			tag:      "latest",
+			expected: true,
+		},
+		{
+			name: "regex match",
+			selector: tagSelector{
+				refSelector: refSelector{WhitelistedRefs: []string{"/^v1\\./"}},
+			},
+			tag:      "v1.2.3",
+			expected: true,
+		},
+		{
+			name:     "semver constraint satisfied",
+			selector: tagSelector{SemverConstraint: ">=1.0.0 <2.0.0"},
+			tag:      "v1.2.3",
+			expected: true,
+		},
+		{
+			name:     "semver constraint not satisfied",
+			selector: tagSelector{SemverConstraint: ">=1.0.0 <2.0.0"},
+			tag:      "v2.0.0",
+			expected: false,
+		},
+		{
+			name:     "semver constraint with non-semver tag",
+			selector: tagSelector{SemverConstraint: ">=1.0.0"},
+			tag:      "nightly",
+			expected: false,
+		},
+		{
+			name: "semver constraint satisfied but tag ignored",
+			selector: tagSelector{
+				refSelector:      refSelector{BlacklistedRefs: []string{"v1.2.3"}},
+				SemverConstraint: ">=1.0.0",
+			},
+			tag:      "v1.2.3",
+			expected: false,
+		},
+		{
+			name:     "pre-releases only with pre-release",
+			selector: tagSelector{Prereleases: prereleasesOnly},
+			tag:      "v1.2.3-rc.1",
+			expected: true,
+		},
+		{
+			name:     "pre-releases only with release",
+			selector: tagSelector{Prereleases: prereleasesOnly},
+			tag:      "v1.2.3",
+			expected: false,
+		},
+		{
+			name:     "pre-releases excluded with pre-release",
+			selector: tagSelector{Prereleases: prereleasesExclude},
+			tag:      "v1.2.3-rc.1",
+			expected: false,
+		},
+		{
+			name:     "pre-releases included with non-semver tag",
+			selector: tagSelector{Prereleases: prereleasesInclude},
+			tag:      "nightly",
+			expected: true,
+		},
+		{
+			name:     "invalid pre-releases value",
+			selector: tagSelector{Prereleases: "sometimes"},
+			tag:      "v1.2.3",
+			errMsg:   "unrecognized prereleases value",
+		},
+		{
+			name:     "invalid semver constraint",
+			selector: tagSelector{SemverConstraint: ">=one"},
+			tag:      "v1.2.3",
+			errMsg:   "error parsing constraint",
+		},
+	}
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			match, _, err := testCase.selector.matches(testCase.tag)
+			if testCase.errMsg != "" {
+				require.Error(t, err)
+				require.Contains(t, err.Error(), testCase.errMsg)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, testCase.expected, match)
+		})
+	}
+}
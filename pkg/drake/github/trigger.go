@@ -2,91 +2,347 @@ package github
 
 import (
 	"encoding/json"
-	"log"
+	"fmt"
 	"strconv"
+	"strings"
 
 	"github.com/google/go-github/github"
 	"github.com/lovethedrake/brigdrake/pkg/brigade"
 	"github.com/lovethedrake/brigdrake/pkg/drake"
+	"github.com/lovethedrake/brigdrake/pkg/semver"
 	"github.com/pkg/errors"
 )
 
 // nolint: lll
 type trigger struct {
-	PullRequestEventSelector *pullRequestEventSelector `json:"pullRequest,omitempty"`
-	PushEventSelector        *pushEventSelector        `json:"push,omitempty"`
+	PullRequestEventSelector  *pullRequestEventSelector  `json:"pullRequest,omitempty"`
+	PushEventSelector         *pushEventSelector         `json:"push,omitempty"`
+	IssueCommentEventSelector *issueCommentEventSelector `json:"issueComment,omitempty"`
+	ReleaseEventSelector      *releaseEventSelector      `json:"release,omitempty"`
+	CreateEventSelector       *createEventSelector       `json:"create,omitempty"`
+	SummaryCheckName          string                     `json:"summaryCheckName,omitempty"`
+	githubClientFn            githubClientFn
+}
+
+// specURI identifies the spec that this package's Trigger implements.
+const specURI = "github.com/lovethedrake/drakespec-github"
+
+func init() {
+	drake.RegisterTrigger(specURI, ">=1.0.0 <2.0.0", NewTriggerFromJSON)
+	drake.RegisterValidationFailureNotifier(
+		"github",
+		newValidationFailureNotifier,
+	)
 }
 
 // NewTriggerFromJSON takes a slice of bytes containing JSON as an argument and
 // returns a Trigger that implements the
 // github.com/lovethedrake/drakespec-github spec.
 func NewTriggerFromJSON(jsonBytes []byte) (drake.Trigger, error) {
-	t := &trigger{}
-	err := json.Unmarshal(jsonBytes, t)
-	return t, err
+	t := &trigger{
+		githubClientFn: newInstallationClient,
+	}
+	if err := drake.DecodeTriggerConfig(jsonBytes, t); err != nil {
+		return t, err
+	}
+	if t.PushEventSelector != nil {
+		if err := t.PushEventSelector.validate(); err != nil {
+			return t, errors.Wrap(err, "error validating push event selector")
+		}
+	}
+	if t.ReleaseEventSelector != nil {
+		if err := t.ReleaseEventSelector.validate(); err != nil {
+			return t, errors.Wrap(err, "error validating release event selector")
+		}
+	}
+	if t.CreateEventSelector != nil {
+		if err := t.CreateEventSelector.validate(); err != nil {
+			return t, errors.Wrap(err, "error validating create event selector")
+		}
+	}
+	return t, nil
 }
 
-func (t *trigger) Matches(event brigade.Event) (bool, error) {
+func (t *trigger) Matches(
+	project brigade.Project,
+	event brigade.Event,
+) (drake.MatchResult, error) {
 	if event.Provider != "github" {
-		log.Printf(
-			"event from provider %q does not match github trigger",
+		return drake.NoMatch(
+			"",
+			"event provider %q is not github",
 			event.Provider,
-		)
-		return false, nil
+		), nil
 	}
 
 	switch event.Type {
 	case "pull_request:opened",
 		"pull_request:synchronize",
-		"pull_request:reopened":
+		"pull_request:reopened",
+		"pull_request:labeled":
 		if t.PullRequestEventSelector == nil {
-			log.Println(
-				"pull request event does not match trigger with unconfigured pull " +
-					"request event selector",
-			)
-			return false, nil
+			return drake.NoMatch(
+				"pullRequest",
+				"no pull request event selector configured",
+			), nil
 		}
-		matches, err := t.PullRequestEventSelector.matches(event)
+		result, err := t.PullRequestEventSelector.matches(
+			project,
+			event,
+			t.githubClientFn,
+		)
 		if err != nil {
-			return false, errors.Wrap(
+			return drake.MatchResult{}, errors.Wrap(
 				err,
 				"error matching pull request event to pull request event selector",
 			)
 		}
-		if matches {
-			log.Println("pull request event matches trigger")
-		} else {
-			log.Println("pull request event does not match trigger")
-		}
-		return matches, nil
+		return result, nil
 	case "push":
 		if t.PushEventSelector == nil {
-			log.Println(
-				"push event does not match trigger with unconfigured push event " +
-					"selector",
-			)
-			return false, nil
+			return drake.NoMatch("push", "no push event selector configured"), nil
 		}
-		matches, err := t.PushEventSelector.matches(event)
+		result, err := t.PushEventSelector.matches(event)
 		if err != nil {
-			return false, errors.Wrap(
+			return drake.MatchResult{}, errors.Wrap(
 				err,
 				"error matching push event to push event selector ",
 			)
 		}
-		if matches {
-			log.Println("push event matches trigger")
-		} else {
-			log.Println("push event does not match trigger")
+		return result, nil
+	case releasePublishedEventType:
+		if t.ReleaseEventSelector == nil {
+			return drake.NoMatch(
+				"release",
+				"no release event selector configured",
+			), nil
+		}
+		result, err := t.ReleaseEventSelector.matches(event)
+		if err != nil {
+			return drake.MatchResult{}, errors.Wrap(
+				err,
+				"error matching release event to release event selector",
+			)
+		}
+		return result, nil
+	case createEventType:
+		if t.CreateEventSelector == nil {
+			return drake.NoMatch(
+				"create",
+				"no create event selector configured",
+			), nil
+		}
+		result, err := t.CreateEventSelector.matches(event)
+		if err != nil {
+			return drake.MatchResult{}, errors.Wrap(
+				err,
+				"error matching create event to create event selector",
+			)
+		}
+		return result, nil
+	case checkSuiteRerequestedEventType, checkRunRerequestedEventType:
+		return t.matchesCheckRerequest(project, event)
+	case issueCommentCreatedEventType:
+		if t.IssueCommentEventSelector == nil {
+			return drake.NoMatch(
+				"issueComment",
+				"no issue comment event selector configured",
+			), nil
+		}
+		result, err := t.IssueCommentEventSelector.matches(event)
+		if err != nil {
+			return drake.MatchResult{}, errors.Wrap(
+				err,
+				"error matching issue comment event to issue comment event selector",
+			)
 		}
-		return matches, nil
+		return result, nil
 	default:
-		log.Printf(
-			"unsupported event type %q does not match github trigger",
+		return drake.NoMatch("", "event type %q is not supported", event.Type), nil
+	}
+}
+
+// matchesCheckRerequest maps a check_suite:rerequested or check_run:rerequested
+// event back onto the pull request or push that originally caused the checks
+// to run and evaluates it using the corresponding selector in full, as it is
+// now. The event includes neither the pull request's details nor the files
+// that were changed, so GitHub is asked for these.
+func (t *trigger) matchesCheckRerequest(
+	project brigade.Project,
+	event brigade.Event,
+) (drake.MatchResult, error) {
+	cr, err := newCheckRerequest(event)
+	if err != nil {
+		return drake.MatchResult{}, err
+	}
+	if cr.fromOtherRepo() {
+		// GitHub doesn't list pull requests from forks in check suites, so the
+		// pull request can't be evaluated, but this certainly wasn't a push.
+		return drake.NoMatch(
+			"pullRequest",
+			"%s event for branch %s of another repository names no pull request",
 			event.Type,
+			cr.headBranch,
+		), nil
+	}
+	if len(cr.pullRequests) == 0 {
+		return t.matchesPushCheckRerequest(project, event, cr)
+	}
+	if t.PullRequestEventSelector == nil {
+		return drake.NoMatch(
+			"pullRequest",
+			"%s event for pull request with no pull request event selector "+
+				"configured",
+			event.Type,
+		), nil
+	}
+	githubClient, err := t.githubClientFn(project, cr.installationID)
+	if err != nil {
+		return drake.MatchResult{}, errors.Wrap(
+			err,
+			"error creating github client for getting pull request",
 		)
-		return false, nil
 	}
+	var result drake.MatchResult
+	for _, listedPR := range cr.pullRequests {
+		pr, err := getPullRequest(
+			githubClient,
+			cr.repoOwner,
+			cr.repoName,
+			listedPR.GetNumber(),
+		)
+		if err != nil {
+			return drake.MatchResult{}, err
+		}
+		if result, err = t.PullRequestEventSelector.matchesPullRequest(
+			project,
+			cr.installationID,
+			pr,
+			"",
+			t.githubClientFn,
+		); err != nil {
+			return drake.MatchResult{}, errors.Wrap(
+				err,
+				"error matching check rerequest to pull request event selector",
+			)
+		}
+		if result.Matched {
+			break
+		}
+	}
+	return result, nil
+}
+
+// matchesPushCheckRerequest evaluates re-requested checks that originally ran
+// because of a push to the check suite's head branch. If the commit the
+// branch pointed to before the push is unknown, e.g. because the push created
+// the branch, the files that were changed can't be determined and any path
+// selector is disregarded.
+func (t *trigger) matchesPushCheckRerequest(
+	project brigade.Project,
+	event brigade.Event,
+	cr checkRerequest,
+) (drake.MatchResult, error) {
+	if t.PushEventSelector == nil {
+		return drake.NoMatch(
+			"push",
+			"%s event for push with no push event selector configured",
+			event.Type,
+		), nil
+	}
+	if cr.headBranch == "" {
+		return drake.NoMatch(
+			"push",
+			"%s event has no head branch",
+			event.Type,
+		), nil
+	}
+	result, err := t.PushEventSelector.matchesRef(
+		fmt.Sprintf("refs/heads/%s", cr.headBranch),
+	)
+	if err != nil {
+		return drake.MatchResult{}, errors.Wrap(
+			err,
+			"error matching check rerequest to push event selector",
+		)
+	}
+	if !result.Matched || t.PushEventSelector.PathSelector == nil {
+		return result, nil
+	}
+	if strings.Trim(cr.beforeSHA, "0") == "" {
+		return drake.Match(
+			"push.paths",
+			"files changed by the push that created branch %s are unknown",
+			cr.headBranch,
+		), nil
+	}
+	githubClient, err := t.githubClientFn(project, cr.installationID)
+	if err != nil {
+		return drake.MatchResult{}, errors.Wrap(
+			err,
+			"error creating github client for listing changed files",
+		)
+	}
+	files, err := listChangedFiles(
+		githubClient,
+		cr.repoOwner,
+		cr.repoName,
+		cr.beforeSHA,
+		cr.headSHA,
+	)
+	if err != nil {
+		return drake.MatchResult{}, err
+	}
+	return t.PushEventSelector.matchesFiles(files)
+}
+
+// SelectedJobs implements drake.JobSelector. When a single check run is
+// re-requested, only the job of the same name needs to be re-run, unless the
+// check run is a pipeline's summary check run or the check run reporting an
+// invalid Drakefile, in which case the whole pipeline is re-run. When a
+// comment issues a command with an argument, the argument names the pipeline
+// or job to be executed.
+func (t *trigger) SelectedJobs(event brigade.Event) ([]string, error) {
+	if event.Provider != "github" {
+		return nil, nil
+	}
+	var name string
+	switch event.Type {
+	case checkRunRerequestedEventType:
+		cr, err := newCheckRerequest(event)
+		if err != nil {
+			return nil, err
+		}
+		name = t.checkRunSelection(cr.checkRunName)
+	case issueCommentCreatedEventType:
+		if t.IssueCommentEventSelector == nil {
+			return nil, nil
+		}
+		var err error
+		if name, err = t.IssueCommentEventSelector.argument(event); err != nil {
+			return nil, err
+		}
+	}
+	if name == "" {
+		return nil, nil
+	}
+	return []string{name}, nil
+}
+
+// checkRunSelection maps the name of a re-requested check run back to the name
+// of the job or pipeline that it reports on. An empty result selects all of
+// the pipeline's jobs.
+func (t *trigger) checkRunSelection(checkRunName string) string {
+	switch {
+	case checkRunName == validationCheckName:
+		return ""
+	case t.SummaryCheckName != "" && checkRunName == t.SummaryCheckName:
+		return ""
+	case strings.HasPrefix(checkRunName, pipelineCheckNamePrefix):
+		// The default summary check run name selects the named pipeline only
+		return strings.TrimPrefix(checkRunName, pipelineCheckNamePrefix)
+	}
+	return checkRunName
 }
 
 func (t *trigger) JobStatusNotifier(
@@ -96,47 +352,234 @@ func (t *trigger) JobStatusNotifier(
 	if !ok {
 		return nil, nil
 	}
-	appID, err := strconv.ParseInt(appIDStr, 10, 64)
-	if err != nil {
+	if _, err := strconv.ParseInt(appIDStr, 10, 64); err != nil {
 		return nil, nil
 	}
-	githubKey, ok := project.Secrets["BRIGDRAKE_GITHUB_KEY"]
-	if !ok {
+	if _, ok = project.Secrets["BRIGDRAKE_GITHUB_KEY"]; !ok {
 		return nil, nil
 	}
+	var installationID int64
+	var repoOwner, repoName, commit, ref string
+	var pullRequestNumber int
 	switch event.Type {
 	case "pull_request:opened",
 		"pull_request:synchronize",
-		"pull_request:reopened":
+		"pull_request:reopened",
+		"pull_request:labeled":
 		pre := github.PullRequestEvent{}
 		if err := json.Unmarshal(event.Payload, &pre); err != nil {
 			return nil, errors.Wrap(err, "error unmarshaling event payload")
 		}
-		return newJobStatusNotifier(
-			appID,
-			*pre.Installation.ID,
-			githubKey,
-			*pre.PullRequest.Base.Repo.Owner.Login,
-			*pre.PullRequest.Base.Repo.Name,
-			*pre.PullRequest.Head.SHA,
-		)
+		installationID = *pre.Installation.ID
+		repoOwner = *pre.PullRequest.Base.Repo.Owner.Login
+		repoName = *pre.PullRequest.Base.Repo.Name
+		commit = *pre.PullRequest.Head.SHA
 	case "push":
 		pe := github.PushEvent{}
 		if err := json.Unmarshal(event.Payload, &pe); err != nil {
 			return nil, errors.Wrap(err, "error unmarshaling event payload")
 		}
-		// We don't want a notifier if this is for a tag push
-		if tagRefRegex.MatchString(*pe.Ref) {
-			return nil, nil
+		installationID = pe.GetInstallation().GetID()
+		repoOwner = pe.GetRepo().GetOwner().GetLogin()
+		repoName = pe.GetRepo().GetName()
+		// Pushes that delete a ref have no head commit
+		commit = pe.GetHeadCommit().GetID()
+		if commit == "" {
+			commit = pe.GetAfter()
+		}
+	case checkSuiteRerequestedEventType, checkRunRerequestedEventType:
+		cr, err := newCheckRerequest(event)
+		if err != nil {
+			return nil, err
+		}
+		// Post to the same head SHA as the checks that were re-requested
+		installationID = cr.installationID
+		repoOwner = cr.repoOwner
+		repoName = cr.repoName
+		commit = cr.headSHA
+	case releasePublishedEventType:
+		re := github.ReleaseEvent{}
+		if err := json.Unmarshal(event.Payload, &re); err != nil {
+			return nil, errors.Wrap(err, "error unmarshaling event payload")
 		}
-		return newJobStatusNotifier(
-			appID,
-			*pe.Installation.ID,
-			githubKey,
-			*pe.Repo.Owner.Login,
-			*pe.Repo.Name,
-			*pe.HeadCommit.ID,
+		installationID = re.GetInstallation().GetID()
+		repoOwner = re.GetRepo().GetOwner().GetLogin()
+		repoName = re.GetRepo().GetName()
+		ref = re.GetRelease().GetTagName()
+	case createEventType:
+		ce := github.CreateEvent{}
+		if err := json.Unmarshal(event.Payload, &ce); err != nil {
+			return nil, errors.Wrap(err, "error unmarshaling event payload")
+		}
+		installationID = ce.GetInstallation().GetID()
+		repoOwner = ce.GetRepo().GetOwner().GetLogin()
+		repoName = ce.GetRepo().GetName()
+		ref = ce.GetRef()
+	case issueCommentCreatedEventType:
+		ice := github.IssueCommentEvent{}
+		if err := json.Unmarshal(event.Payload, &ice); err != nil {
+			return nil, errors.Wrap(err, "error unmarshaling event payload")
+		}
+		installationID = ice.GetInstallation().GetID()
+		repoOwner = ice.GetRepo().GetOwner().GetLogin()
+		repoName = ice.GetRepo().GetName()
+		pullRequestNumber = ice.GetIssue().GetNumber()
+	default:
+		return nil, nil
+	}
+	githubClient, err := t.githubClientFn(project, installationID)
+	if err != nil {
+		return nil, errors.Wrap(
+			err,
+			"error creating github client for job status notifier",
 		)
 	}
-	return nil, nil
+	// Some events don't indicate what commit they pertain to, so we have to ask
+	// GitHub.
+	switch {
+	case event.Type == issueCommentCreatedEventType:
+		if commit, err = getPullRequestHeadSHA(
+			githubClient,
+			repoOwner,
+			repoName,
+			pullRequestNumber,
+		); err != nil {
+			return nil, err
+		}
+	case commit == "":
+		if commit, err = getCommitSHA(
+			githubClient,
+			repoOwner,
+			repoName,
+			ref,
+		); err != nil {
+			return nil, err
+		}
+	}
+	return newJobStatusNotifier(
+		githubClient,
+		repoOwner,
+		repoName,
+		commit,
+		kashtiBuildURL(project, event),
+		t.SummaryCheckName,
+	), nil
+}
+
+// newValidationFailureNotifier is a drake.ValidationFailureNotifierBuilder. The
+// pipeline's own trigger configuration may be what's invalid, so it uses an
+// unconfigured trigger to obtain a notifier.
+func newValidationFailureNotifier(
+	project brigade.Project,
+	event brigade.Event,
+) (drake.ValidationFailureNotifier, error) {
+	t := &trigger{
+		githubClientFn: newInstallationClient,
+	}
+	jsn, err := t.JobStatusNotifier(project, event)
+	if err != nil {
+		return nil, err
+	}
+	notifier, _ := jsn.(drake.ValidationFailureNotifier)
+	return notifier, nil
+}
+
+// JobEnvironment implements drake.JobEnvironmentProvider. When an event
+// pertains to a pull request, its number is exposed to jobs as
+// DRAKE_PR_NUMBER. When an event pertains to a tag, the tag is exposed to jobs
+// as DRAKE_TAG and, if the tag is a semantic version, the components of that
+// version are also exposed.
+func (t *trigger) JobEnvironment(
+	event brigade.Event,
+) (map[string]string, error) {
+	if event.Provider != "github" {
+		return nil, nil
+	}
+	var pullRequestNumber int
+	var tag string
+	switch event.Type {
+	case "pull_request:opened",
+		"pull_request:synchronize",
+		"pull_request:reopened",
+		"pull_request:labeled":
+		pre := github.PullRequestEvent{}
+		if err := json.Unmarshal(event.Payload, &pre); err != nil {
+			return nil, errors.Wrap(err, "error unmarshaling event payload")
+		}
+		pullRequestNumber = pre.GetPullRequest().GetNumber()
+	case "push":
+		pe := github.PushEvent{}
+		if err := json.Unmarshal(event.Payload, &pe); err != nil {
+			return nil, errors.Wrap(err, "error unmarshaling event payload")
+		}
+		if refSubmatches :=
+			tagRefRegex.FindStringSubmatch(pe.GetRef()); len(refSubmatches) == 2 {
+			tag = refSubmatches[1]
+		}
+	case checkSuiteRerequestedEventType, checkRunRerequestedEventType:
+		cr, err := newCheckRerequest(event)
+		if err != nil {
+			return nil, err
+		}
+		if len(cr.pullRequests) > 0 {
+			pullRequestNumber = cr.pullRequests[0].GetNumber()
+		}
+	case releasePublishedEventType:
+		re := github.ReleaseEvent{}
+		if err := json.Unmarshal(event.Payload, &re); err != nil {
+			return nil, errors.Wrap(err, "error unmarshaling event payload")
+		}
+		tag = re.GetRelease().GetTagName()
+	case createEventType:
+		ce := github.CreateEvent{}
+		if err := json.Unmarshal(event.Payload, &ce); err != nil {
+			return nil, errors.Wrap(err, "error unmarshaling event payload")
+		}
+		if ce.GetRefType() == "tag" {
+			tag = ce.GetRef()
+		}
+	case issueCommentCreatedEventType:
+		ice := github.IssueCommentEvent{}
+		if err := json.Unmarshal(event.Payload, &ice); err != nil {
+			return nil, errors.Wrap(err, "error unmarshaling event payload")
+		}
+		pullRequestNumber = ice.GetIssue().GetNumber()
+	}
+	environment := map[string]string{}
+	if pullRequestNumber != 0 {
+		environment["DRAKE_PR_NUMBER"] = strconv.Itoa(pullRequestNumber)
+	}
+	if tag != "" {
+		environment["DRAKE_TAG"] = tag
+		// Not all tags are semantic versions, and that's ok
+		if version, err := semver.ParseVersion(tag); err == nil {
+			environment["DRAKE_VERSION"] = version.String()
+			environment["DRAKE_VERSION_MAJOR"] =
+				strconv.FormatUint(version.Major, 10)
+			environment["DRAKE_VERSION_MINOR"] =
+				strconv.FormatUint(version.Minor, 10)
+			environment["DRAKE_VERSION_PATCH"] =
+				strconv.FormatUint(version.Patch, 10)
+			environment["DRAKE_VERSION_PRERELEASE"] = version.Prerelease
+			environment["DRAKE_VERSION_BUILD"] = version.Build
+		}
+	}
+	if len(environment) == 0 {
+		return nil, nil
+	}
+	return environment, nil
+}
+
+// kashtiBuildURL returns the URL of the Kashti page for the build, if the
+// project specifies where Kashti can be found. Otherwise, it returns an empty
+// string.
+func kashtiBuildURL(project brigade.Project, event brigade.Event) string {
+	if project.KashtiURL == "" {
+		return ""
+	}
+	return fmt.Sprintf(
+		"%s/builds/%s",
+		strings.TrimSuffix(project.KashtiURL, "/"),
+		event.BuildID,
+	)
 }
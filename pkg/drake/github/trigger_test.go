@@ -1,12 +1,125 @@
 package github
 
 import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strconv"
 	"testing"
 
+	"github.com/google/go-github/github"
 	"github.com/lovethedrake/brigdrake/pkg/brigade"
+	"github.com/lovethedrake/brigdrake/pkg/drake"
 	"github.com/stretchr/testify/require"
 )
 
+// fakePullRequestClient is a fake simpleGithubClient that serves a single pull
+// request and its changed files, two to a page. The same files are also served
+// as the result of any comparison between two commits.
+type fakePullRequestClient struct {
+	// pullRequest is the pull request as JSON. It may be empty.
+	pullRequest string
+	// headSHA, if not empty, overrides the pull request's head SHA.
+	headSHA string
+	files   []string
+}
+
+func (f *fakePullRequestClient) NewRequest(
+	method string,
+	urlStr string,
+	_ interface{},
+) (*http.Request, error) {
+	return http.NewRequest(method, "https://api.github.com/"+urlStr, nil)
+}
+
+func (f *fakePullRequestClient) Do(
+	_ context.Context,
+	req *http.Request,
+	v interface{},
+) (*github.Response, error) {
+	switch v := v.(type) {
+	case *pullRequest:
+		if f.pullRequest != "" {
+			if err := json.Unmarshal([]byte(f.pullRequest), v); err != nil {
+				return nil, err
+			}
+		}
+		if f.headSHA != "" {
+			if v.Head == nil {
+				v.Head = &github.PullRequestBranch{}
+			}
+			v.Head.SHA = &f.headSHA
+		}
+		return &github.Response{}, nil
+	case *commitsComparison:
+		for _, file := range f.files {
+			v.Files = append(v.Files, pullRequestFile{Filename: file})
+		}
+		return &github.Response{}, nil
+	}
+	page, err := strconv.Atoi(req.URL.Query().Get("page"))
+	if err != nil {
+		return nil, err
+	}
+	const pageSize = 2
+	start := (page - 1) * pageSize
+	end := start + pageSize
+	resp := &github.Response{NextPage: page + 1}
+	if end >= len(f.files) {
+		end = len(f.files)
+		resp.NextPage = 0
+	}
+	files := v.(*[]pullRequestFile)
+	for _, file := range f.files[start:end] {
+		*files = append(*files, pullRequestFile{Filename: file})
+	}
+	return resp, nil
+}
+
+func fakeGithubClientFn(files ...string) githubClientFn {
+	return fakePullRequestClientFn("", files...)
+}
+
+func fakePullRequestClientFn(
+	pullRequest string,
+	files ...string,
+) githubClientFn {
+	return func(brigade.Project, int64) (simpleGithubClient, error) {
+		return &fakePullRequestClient{
+			pullRequest: pullRequest,
+			files:       files,
+		}, nil
+	}
+}
+
+func TestNewTriggerFromJSON(t *testing.T) {
+	_, err := NewTriggerFromJSON(
+		[]byte(`{"pullRequest":{"targetBranches":{"only":["master"]}}}`),
+	)
+	require.NoError(t, err)
+	// A misspelled selector must not silently disable the trigger
+	_, err = NewTriggerFromJSON(
+		[]byte(`{"pullRequests":{"targetBranches":{"only":["master"]}}}`),
+	)
+	require.Error(t, err)
+	require.Contains(t, err.Error(), `unknown field "pullRequests"`)
+	// Tag selectors are validated before any event is matched
+	_, err = NewTriggerFromJSON(
+		[]byte(`{"push":{"tags":{"semver":">=1.0.0 <"}}}`),
+	)
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "error validating push event selector")
+	_, err = NewTriggerFromJSON(
+		[]byte(`{"release":{"tags":{"prereleases":"sometimes"}}}`),
+	)
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "unrecognized prereleases value")
+	_, err = NewTriggerFromJSON(
+		[]byte(`{"create":{"tags":{"semver":">=1.0.0","prereleases":"only"}}}`),
+	)
+	require.NoError(t, err)
+}
+
 func TestMatches(t *testing.T) {
 	testCases := []struct {
 		name       string
@@ -205,8 +318,10 @@ func TestMatches(t *testing.T) {
 				"tag selector",
 			trigger: &trigger{
 				PushEventSelector: &pushEventSelector{
-					TagSelector: &refSelector{
-						WhitelistedRefs: []string{"foo"},
+					TagSelector: &tagSelector{
+						refSelector: refSelector{
+							WhitelistedRefs: []string{"foo"},
+						},
 					},
 				},
 			},
@@ -220,13 +335,39 @@ func TestMatches(t *testing.T) {
 				require.False(t, matches)
 			},
 		},
+		{
+			name: "push event deleting tag that matches push event selector's " +
+				"tag selector",
+			trigger: &trigger{
+				PushEventSelector: &pushEventSelector{
+					TagSelector: &tagSelector{
+						refSelector: refSelector{
+							WhitelistedRefs: []string{"/.*/"},
+						},
+					},
+				},
+			},
+			event: brigade.Event{
+				Provider: "github",
+				Type:     "push",
+				Payload: []byte(
+					`{"ref":"refs/tags/v1.0.0","deleted":true,"head_commit":null}`,
+				),
+			},
+			assertions: func(t *testing.T, matches bool, err error) {
+				require.NoError(t, err)
+				require.False(t, matches)
+			},
+		},
 		{
 			name: "push event for tag that matches push event selector's tag " +
 				"selector",
 			trigger: &trigger{
 				PushEventSelector: &pushEventSelector{
-					TagSelector: &refSelector{
-						WhitelistedRefs: []string{"foo"},
+					TagSelector: &tagSelector{
+						refSelector: refSelector{
+							WhitelistedRefs: []string{"foo"},
+						},
 					},
 				},
 			},
@@ -240,12 +381,1409 @@ func TestMatches(t *testing.T) {
 				require.True(t, matches)
 			},
 		},
-	}
-
+		{
+			name: "check suite rerequest for pull request that matches pull " +
+				"request event selector",
+			trigger: &trigger{
+				PullRequestEventSelector: &pullRequestEventSelector{
+					TargetBranchSelector: &refSelector{
+						WhitelistedRefs: []string{"master"},
+					},
+				},
+				githubClientFn: fakePullRequestClientFn(
+					`{"number":7,"base":{"ref":"master"}}`,
+				),
+			},
+			event: brigade.Event{
+				Provider: "github",
+				Type:     "check_suite:rerequested",
+				Payload:  []byte(`{"check_suite":{"head_branch":"feature","pull_requests":[{"number":7,"base":{"ref":"master"}}]}}`), // nolint: lll
+			},
+			assertions: func(t *testing.T, matches bool, err error) {
+				require.NoError(t, err)
+				require.True(t, matches)
+			},
+		},
+		{
+			name: "check suite rerequest for pull request with unconfigured pull " +
+				"request event selector",
+			trigger: &trigger{
+				PushEventSelector: &pushEventSelector{
+					BranchSelector: &refSelector{},
+				},
+			},
+			event: brigade.Event{
+				Provider: "github",
+				Type:     "check_suite:rerequested",
+				Payload:  []byte(`{"check_suite":{"head_branch":"feature","pull_requests":[{"base":{"ref":"master"}}]}}`), // nolint: lll
+			},
+			assertions: func(t *testing.T, matches bool, err error) {
+				require.NoError(t, err)
+				require.False(t, matches)
+			},
+		},
+		{
+			name: "check suite rerequest for push that matches push event " +
+				"selector",
+			trigger: &trigger{
+				PushEventSelector: &pushEventSelector{
+					BranchSelector: &refSelector{
+						WhitelistedRefs: []string{"master"},
+					},
+				},
+			},
+			event: brigade.Event{
+				Provider: "github",
+				Type:     "check_suite:rerequested",
+				Payload:  []byte(`{"check_suite":{"head_branch":"master"}}`),
+			},
+			assertions: func(t *testing.T, matches bool, err error) {
+				require.NoError(t, err)
+				require.True(t, matches)
+			},
+		},
+		{
+			name: "check suite rerequest for branch of fork with no pull requests",
+			trigger: &trigger{
+				PushEventSelector: &pushEventSelector{
+					BranchSelector: &refSelector{
+						WhitelistedRefs: []string{"master"},
+					},
+				},
+			},
+			event: brigade.Event{
+				Provider: "github",
+				Type:     "check_suite:rerequested",
+				Payload:  []byte(`{"check_suite":{"head_branch":"master","head_repository":{"id":2}},"repository":{"id":1}}`), // nolint: lll
+			},
+			assertions: func(t *testing.T, matches bool, err error) {
+				require.NoError(t, err)
+				require.False(t, matches)
+			},
+		},
+		{
+			name: "check run rerequest for push that does not match push event " +
+				"selector",
+			trigger: &trigger{
+				PushEventSelector: &pushEventSelector{
+					BranchSelector: &refSelector{
+						WhitelistedRefs: []string{"master"},
+					},
+				},
+			},
+			event: brigade.Event{
+				Provider: "github",
+				Type:     "check_run:rerequested",
+				Payload:  []byte(`{"check_run":{"name":"test","check_suite":{"head_branch":"foo"}}}`), // nolint: lll
+			},
+			assertions: func(t *testing.T, matches bool, err error) {
+				require.NoError(t, err)
+				require.False(t, matches)
+			},
+		},
+		{
+			name: "check run rerequest for pull request that matches pull " +
+				"request event selector",
+			trigger: &trigger{
+				PullRequestEventSelector: &pullRequestEventSelector{
+					TargetBranchSelector: &refSelector{},
+				},
+				githubClientFn: fakePullRequestClientFn(
+					`{"number":7,"base":{"ref":"master"}}`,
+				),
+			},
+			event: brigade.Event{
+				Provider: "github",
+				Type:     "check_run:rerequested",
+				Payload:  []byte(`{"check_run":{"name":"test","pull_requests":[{"number":7,"base":{"ref":"master"}}]}}`), // nolint: lll
+			},
+			assertions: func(t *testing.T, matches bool, err error) {
+				require.NoError(t, err)
+				require.True(t, matches)
+			},
+		},
+		{
+			name: "check suite rerequest for draft pull request with drafts ignored",
+			trigger: &trigger{
+				PullRequestEventSelector: &pullRequestEventSelector{
+					TargetBranchSelector: &refSelector{},
+					IgnoreDrafts:         true,
+				},
+				githubClientFn: fakePullRequestClientFn(
+					`{"number":7,"draft":true,"base":{"ref":"master"}}`,
+				),
+			},
+			event: brigade.Event{
+				Provider: "github",
+				Type:     "check_suite:rerequested",
+				Payload:  []byte(`{"check_suite":{"pull_requests":[{"number":7,"base":{"ref":"master"}}]}}`), // nolint: lll
+			},
+			assertions: func(t *testing.T, matches bool, err error) {
+				require.NoError(t, err)
+				require.False(t, matches)
+			},
+		},
+		{
+			name: "check suite rerequest for pull request missing required label",
+			trigger: &trigger{
+				PullRequestEventSelector: &pullRequestEventSelector{
+					TargetBranchSelector: &refSelector{},
+					LabelSelector: &labelSelector{
+						RequiredLabels: []string{"ok-to-test"},
+					},
+				},
+				githubClientFn: fakePullRequestClientFn(
+					`{"number":7,"base":{"ref":"master"},"labels":[{"name":"bug"}]}`,
+				),
+			},
+			event: brigade.Event{
+				Provider: "github",
+				Type:     "check_suite:rerequested",
+				Payload:  []byte(`{"check_suite":{"pull_requests":[{"number":7,"base":{"ref":"master"}}]}}`), // nolint: lll
+			},
+			assertions: func(t *testing.T, matches bool, err error) {
+				require.NoError(t, err)
+				require.False(t, matches)
+			},
+		},
+		{
+			name: "check run rerequest for pull request by author with excluded " +
+				"association",
+			trigger: &trigger{
+				PullRequestEventSelector: &pullRequestEventSelector{
+					TargetBranchSelector: &refSelector{},
+					AuthorAssociations:   []string{"OWNER", "MEMBER"},
+				},
+				githubClientFn: fakePullRequestClientFn(
+					`{"number":7,"base":{"ref":"master"},"author_association":"NONE"}`,
+				),
+			},
+			event: brigade.Event{
+				Provider: "github",
+				Type:     "check_run:rerequested",
+				Payload:  []byte(`{"check_run":{"name":"test","pull_requests":[{"number":7,"base":{"ref":"master"}}]}}`), // nolint: lll
+			},
+			assertions: func(t *testing.T, matches bool, err error) {
+				require.NoError(t, err)
+				require.False(t, matches)
+			},
+		},
+		{
+			name: "check suite rerequest for pull request with excluded source " +
+				"branch",
+			trigger: &trigger{
+				PullRequestEventSelector: &pullRequestEventSelector{
+					TargetBranchSelector: &refSelector{},
+					SourceBranchSelector: &refSelector{
+						BlacklistedRefs: []string{"/^dependabot//"},
+					},
+				},
+				githubClientFn: fakePullRequestClientFn(
+					`{"number":7,"head":{"ref":"dependabot/go"},"base":{"ref":"master"}}`, // nolint: lll
+				),
+			},
+			event: brigade.Event{
+				Provider: "github",
+				Type:     "check_suite:rerequested",
+				Payload:  []byte(`{"check_suite":{"pull_requests":[{"number":7,"base":{"ref":"master"}}]}}`), // nolint: lll
+			},
+			assertions: func(t *testing.T, matches bool, err error) {
+				require.NoError(t, err)
+				require.False(t, matches)
+			},
+		},
+		{
+			name: "check suite rerequest for pull request with changed files " +
+				"that do not match path selector",
+			trigger: &trigger{
+				PullRequestEventSelector: &pullRequestEventSelector{
+					TargetBranchSelector: &refSelector{},
+					PathSelector: &refSelector{
+						WhitelistedRefs: []string{"/^docs//"},
+					},
+				},
+				githubClientFn: fakePullRequestClientFn(
+					`{"number":7,"base":{"ref":"master"}}`,
+					"main.go",
+				),
+			},
+			event: brigade.Event{
+				Provider: "github",
+				Type:     "check_suite:rerequested",
+				Payload:  []byte(`{"check_suite":{"pull_requests":[{"number":7,"base":{"ref":"master"}}]}}`), // nolint: lll
+			},
+			assertions: func(t *testing.T, matches bool, err error) {
+				require.NoError(t, err)
+				require.False(t, matches)
+			},
+		},
+		{
+			name: "check suite rerequest for push with changed files that match " +
+				"path selector",
+			trigger: &trigger{
+				PushEventSelector: &pushEventSelector{
+					BranchSelector: &refSelector{},
+					PathSelector: &refSelector{
+						WhitelistedRefs: []string{"/^docs//"},
+					},
+				},
+				githubClientFn: fakeGithubClientFn("main.go", "docs/guide.md"),
+			},
+			event: brigade.Event{
+				Provider: "github",
+				Type:     "check_suite:rerequested",
+				Payload:  []byte(`{"check_suite":{"head_branch":"master","head_sha":"def","before":"abc"}}`), // nolint: lll
+			},
+			assertions: func(t *testing.T, matches bool, err error) {
+				require.NoError(t, err)
+				require.True(t, matches)
+			},
+		},
+		{
+			name: "check suite rerequest for push with changed files that do not " +
+				"match path selector",
+			trigger: &trigger{
+				PushEventSelector: &pushEventSelector{
+					BranchSelector: &refSelector{},
+					PathSelector: &refSelector{
+						WhitelistedRefs: []string{"/^docs//"},
+					},
+				},
+				githubClientFn: fakeGithubClientFn("main.go"),
+			},
+			event: brigade.Event{
+				Provider: "github",
+				Type:     "check_suite:rerequested",
+				Payload:  []byte(`{"check_suite":{"head_branch":"master","head_sha":"def","before":"abc"}}`), // nolint: lll
+			},
+			assertions: func(t *testing.T, matches bool, err error) {
+				require.NoError(t, err)
+				require.False(t, matches)
+			},
+		},
+		{
+			name: "check suite rerequest for push that created branch, with path " +
+				"selector",
+			trigger: &trigger{
+				PushEventSelector: &pushEventSelector{
+					BranchSelector: &refSelector{},
+					PathSelector: &refSelector{
+						WhitelistedRefs: []string{"/^docs//"},
+					},
+				},
+			},
+			event: brigade.Event{
+				Provider: "github",
+				Type:     "check_suite:rerequested",
+				Payload:  []byte(`{"check_suite":{"head_branch":"master","head_sha":"def","before":"0000000000000000000000000000000000000000"}}`), // nolint: lll
+			},
+			assertions: func(t *testing.T, matches bool, err error) {
+				require.NoError(t, err)
+				require.True(t, matches)
+			},
+		},
+		{
+			name: "pull request event with changed files that match path selector",
+			trigger: &trigger{
+				PullRequestEventSelector: &pullRequestEventSelector{
+					TargetBranchSelector: &refSelector{},
+					PathSelector: &refSelector{
+						WhitelistedRefs: []string{"/^docs//"},
+						BlacklistedRefs: []string{"docs/README.md"},
+					},
+				},
+				githubClientFn: fakeGithubClientFn(
+					"main.go",
+					"docs/README.md",
+					"pkg/foo.go",
+					"docs/guide.md",
+				),
+			},
+			event: brigade.Event{
+				Provider: "github",
+				Type:     "pull_request:synchronize",
+				Payload:  []byte(`{"pull_request":{"number":42,"base":{"ref":"master"}}}`), // nolint: lll
+			},
+			assertions: func(t *testing.T, matches bool, err error) {
+				require.NoError(t, err)
+				require.True(t, matches)
+			},
+		},
+		{
+			name: "pull request event with changed files that do not match path " +
+				"selector",
+			trigger: &trigger{
+				PullRequestEventSelector: &pullRequestEventSelector{
+					TargetBranchSelector: &refSelector{},
+					PathSelector: &refSelector{
+						WhitelistedRefs: []string{"/^docs//"},
+						BlacklistedRefs: []string{"docs/README.md"},
+					},
+				},
+				githubClientFn: fakeGithubClientFn(
+					"main.go",
+					"docs/README.md",
+					"pkg/foo.go",
+				),
+			},
+			event: brigade.Event{
+				Provider: "github",
+				Type:     "pull_request:synchronize",
+				Payload:  []byte(`{"pull_request":{"number":42,"base":{"ref":"master"}}}`), // nolint: lll
+			},
+			assertions: func(t *testing.T, matches bool, err error) {
+				require.NoError(t, err)
+				require.False(t, matches)
+			},
+		},
+		{
+			name: "push event with changed files that match path selector",
+			trigger: &trigger{
+				PushEventSelector: &pushEventSelector{
+					BranchSelector: &refSelector{},
+					PathSelector: &refSelector{
+						WhitelistedRefs: []string{"/^services/api//"},
+					},
+				},
+			},
+			event: brigade.Event{
+				Provider: "github",
+				Type:     "push",
+				Payload:  []byte(`{"ref":"refs/heads/master","commits":[{"modified":["README.md"]},{"removed":["services/api/main.go"]}]}`), // nolint: lll
+			},
+			assertions: func(t *testing.T, matches bool, err error) {
+				require.NoError(t, err)
+				require.True(t, matches)
+			},
+		},
+		{
+			name: "push event with changed files that do not match path selector",
+			trigger: &trigger{
+				PushEventSelector: &pushEventSelector{
+					BranchSelector: &refSelector{},
+					PathSelector: &refSelector{
+						WhitelistedRefs: []string{"/^services/api//"},
+					},
+				},
+			},
+			event: brigade.Event{
+				Provider: "github",
+				Type:     "push",
+				Payload:  []byte(`{"ref":"refs/heads/master","commits":[{"added":["services/web/main.go"],"modified":["README.md"]}]}`), // nolint: lll
+			},
+			assertions: func(t *testing.T, matches bool, err error) {
+				require.NoError(t, err)
+				require.False(t, matches)
+			},
+		},
+		{
+			name: "push event without changed files and path selector with " +
+				"only-list",
+			trigger: &trigger{
+				PushEventSelector: &pushEventSelector{
+					TagSelector: &tagSelector{},
+					PathSelector: &refSelector{
+						WhitelistedRefs: []string{"/^services/api//"},
+					},
+				},
+			},
+			event: brigade.Event{
+				Provider: "github",
+				Type:     "push",
+				Payload:  []byte(`{"ref":"refs/tags/v1.0.0","commits":[]}`),
+			},
+			assertions: func(t *testing.T, matches bool, err error) {
+				require.NoError(t, err)
+				require.False(t, matches)
+			},
+		},
+		{
+			name: "push event without changed files and path selector with " +
+				"ignore-list only",
+			trigger: &trigger{
+				PushEventSelector: &pushEventSelector{
+					BranchSelector: &refSelector{},
+					PathSelector: &refSelector{
+						BlacklistedRefs: []string{"/^docs//"},
+					},
+				},
+			},
+			event: brigade.Event{
+				Provider: "github",
+				Type:     "push",
+				Payload:  []byte(`{"ref":"refs/heads/master","commits":[]}`),
+			},
+			assertions: func(t *testing.T, matches bool, err error) {
+				require.NoError(t, err)
+				require.True(t, matches)
+			},
+		},
+		{
+			name: "draft pull request with selector ignoring drafts",
+			trigger: &trigger{
+				PullRequestEventSelector: &pullRequestEventSelector{
+					TargetBranchSelector: &refSelector{},
+					IgnoreDrafts:         true,
+				},
+			},
+			event: brigade.Event{
+				Provider: "github",
+				Type:     "pull_request:opened",
+				Payload:  []byte(`{"pull_request":{"draft":true,"base":{"ref":"master"}}}`), // nolint: lll
+			},
+			assertions: func(t *testing.T, matches bool, err error) {
+				require.NoError(t, err)
+				require.False(t, matches)
+			},
+		},
+		{
+			name: "non-draft pull request with selector ignoring drafts",
+			trigger: &trigger{
+				PullRequestEventSelector: &pullRequestEventSelector{
+					TargetBranchSelector: &refSelector{},
+					IgnoreDrafts:         true,
+				},
+			},
+			event: brigade.Event{
+				Provider: "github",
+				Type:     "pull_request:opened",
+				Payload:  []byte(`{"pull_request":{"draft":false,"base":{"ref":"master"}}}`), // nolint: lll
+			},
+			assertions: func(t *testing.T, matches bool, err error) {
+				require.NoError(t, err)
+				require.True(t, matches)
+			},
+		},
+		{
+			name: "pull request with required labels",
+			trigger: &trigger{
+				PullRequestEventSelector: &pullRequestEventSelector{
+					TargetBranchSelector: &refSelector{},
+					LabelSelector: &labelSelector{
+						RequiredLabels:  []string{"run-e2e"},
+						ForbiddenLabels: []string{"/^do-not-/"},
+					},
+				},
+			},
+			event: brigade.Event{
+				Provider: "github",
+				Type:     "pull_request:synchronize",
+				Payload:  []byte(`{"pull_request":{"labels":[{"name":"bug"},{"name":"run-e2e"}],"base":{"ref":"master"}}}`), // nolint: lll
+			},
+			assertions: func(t *testing.T, matches bool, err error) {
+				require.NoError(t, err)
+				require.True(t, matches)
+			},
+		},
+		{
+			name: "pull request missing required label",
+			trigger: &trigger{
+				PullRequestEventSelector: &pullRequestEventSelector{
+					TargetBranchSelector: &refSelector{},
+					LabelSelector: &labelSelector{
+						RequiredLabels:  []string{"run-e2e"},
+						ForbiddenLabels: []string{"/^do-not-/"},
+					},
+				},
+			},
+			event: brigade.Event{
+				Provider: "github",
+				Type:     "pull_request:synchronize",
+				Payload:  []byte(`{"pull_request":{"labels":[{"name":"bug"}],"base":{"ref":"master"}}}`), // nolint: lll
+			},
+			assertions: func(t *testing.T, matches bool, err error) {
+				require.NoError(t, err)
+				require.False(t, matches)
+			},
+		},
+		{
+			name: "pull request with forbidden label",
+			trigger: &trigger{
+				PullRequestEventSelector: &pullRequestEventSelector{
+					TargetBranchSelector: &refSelector{},
+					LabelSelector: &labelSelector{
+						RequiredLabels:  []string{"run-e2e"},
+						ForbiddenLabels: []string{"/^do-not-/"},
+					},
+				},
+			},
+			event: brigade.Event{
+				Provider: "github",
+				Type:     "pull_request:synchronize",
+				Payload:  []byte(`{"pull_request":{"labels":[{"name":"run-e2e"},{"name":"do-not-merge"}],"base":{"ref":"master"}}}`), // nolint: lll
+			},
+			assertions: func(t *testing.T, matches bool, err error) {
+				require.NoError(t, err)
+				require.False(t, matches)
+			},
+		},
+		{
+			name: "pull request newly labeled with required label",
+			trigger: &trigger{
+				PullRequestEventSelector: &pullRequestEventSelector{
+					TargetBranchSelector: &refSelector{},
+					LabelSelector: &labelSelector{
+						RequiredLabels:  []string{"run-e2e"},
+						ForbiddenLabels: []string{"/^do-not-/"},
+					},
+				},
+			},
+			event: brigade.Event{
+				Provider: "github",
+				Type:     "pull_request:labeled",
+				Payload:  []byte(`{"label":{"name":"run-e2e"},"pull_request":{"labels":[{"name":"run-e2e"}],"base":{"ref":"master"}}}`), // nolint: lll
+			},
+			assertions: func(t *testing.T, matches bool, err error) {
+				require.NoError(t, err)
+				require.True(t, matches)
+			},
+		},
+		{
+			name: "pull request newly labeled with label that is not required",
+			trigger: &trigger{
+				PullRequestEventSelector: &pullRequestEventSelector{
+					TargetBranchSelector: &refSelector{},
+					LabelSelector: &labelSelector{
+						RequiredLabels:  []string{"run-e2e"},
+						ForbiddenLabels: []string{"/^do-not-/"},
+					},
+				},
+			},
+			event: brigade.Event{
+				Provider: "github",
+				Type:     "pull_request:labeled",
+				Payload:  []byte(`{"label":{"name":"bug"},"pull_request":{"labels":[{"name":"bug"},{"name":"run-e2e"}],"base":{"ref":"master"}}}`), // nolint: lll
+			},
+			assertions: func(t *testing.T, matches bool, err error) {
+				require.NoError(t, err)
+				require.False(t, matches)
+			},
+		},
+		{
+			name: "pull request newly labeled with unconfigured label selector",
+			trigger: &trigger{
+				PullRequestEventSelector: &pullRequestEventSelector{
+					TargetBranchSelector: &refSelector{},
+				},
+			},
+			event: brigade.Event{
+				Provider: "github",
+				Type:     "pull_request:labeled",
+				Payload:  []byte(`{"label":{"name":"bug"},"pull_request":{"labels":[{"name":"bug"}],"base":{"ref":"master"}}}`), // nolint: lll
+			},
+			assertions: func(t *testing.T, matches bool, err error) {
+				require.NoError(t, err)
+				require.False(t, matches)
+			},
+		},
+		{
+			name: "pull request from author with allowed association",
+			trigger: &trigger{
+				PullRequestEventSelector: &pullRequestEventSelector{
+					TargetBranchSelector: &refSelector{},
+					AuthorAssociations:   []string{"OWNER", "MEMBER"},
+				},
+			},
+			event: brigade.Event{
+				Provider: "github",
+				Type:     "pull_request:opened",
+				Payload:  []byte(`{"pull_request":{"author_association":"MEMBER","base":{"ref":"master"}}}`), // nolint: lll
+			},
+			assertions: func(t *testing.T, matches bool, err error) {
+				require.NoError(t, err)
+				require.True(t, matches)
+			},
+		},
+		{
+			name: "pull request from author with disallowed association",
+			trigger: &trigger{
+				PullRequestEventSelector: &pullRequestEventSelector{
+					TargetBranchSelector: &refSelector{},
+					AuthorAssociations:   []string{"OWNER", "MEMBER"},
+				},
+			},
+			event: brigade.Event{
+				Provider: "github",
+				Type:     "pull_request:opened",
+				Payload:  []byte(`{"pull_request":{"author_association":"CONTRIBUTOR","base":{"ref":"master"}}}`), // nolint: lll
+			},
+			assertions: func(t *testing.T, matches bool, err error) {
+				require.NoError(t, err)
+				require.False(t, matches)
+			},
+		},
+		{
+			name: "pull request from branch that matches source branch selector",
+			trigger: &trigger{
+				PullRequestEventSelector: &pullRequestEventSelector{
+					TargetBranchSelector: &refSelector{},
+					SourceBranchSelector: &refSelector{
+						WhitelistedRefs: []string{"/^release-/"},
+					},
+				},
+			},
+			event: brigade.Event{
+				Provider: "github",
+				Type:     "pull_request:opened",
+				Payload:  []byte(`{"pull_request":{"head":{"ref":"release-1.0"},"base":{"ref":"master"}}}`), // nolint: lll
+			},
+			assertions: func(t *testing.T, matches bool, err error) {
+				require.NoError(t, err)
+				require.True(t, matches)
+			},
+		},
+		{
+			name: "pull request from branch that does not match source branch " +
+				"selector",
+			trigger: &trigger{
+				PullRequestEventSelector: &pullRequestEventSelector{
+					TargetBranchSelector: &refSelector{},
+					SourceBranchSelector: &refSelector{
+						WhitelistedRefs: []string{"/^release-/"},
+					},
+				},
+			},
+			event: brigade.Event{
+				Provider: "github",
+				Type:     "pull_request:opened",
+				Payload:  []byte(`{"pull_request":{"head":{"ref":"feature"},"base":{"ref":"master"}}}`), // nolint: lll
+			},
+			assertions: func(t *testing.T, matches bool, err error) {
+				require.NoError(t, err)
+				require.False(t, matches)
+			},
+		},
+		{
+			name: "issue comment event with unconfigured issue comment event " +
+				"selector",
+			trigger: &trigger{},
+			event: brigade.Event{
+				Provider: "github",
+				Type:     "issue_comment:created",
+				Payload:  []byte(`{"issue":{"pull_request":{}},"comment":{"body":"/drake run","author_association":"OWNER"}}`), // nolint: lll
+			},
+			assertions: func(t *testing.T, matches bool, err error) {
+				require.NoError(t, err)
+				require.False(t, matches)
+			},
+		},
+		{
+			name: "comment issuing command on pull request",
+			trigger: &trigger{
+				IssueCommentEventSelector: &issueCommentEventSelector{},
+			},
+			event: brigade.Event{
+				Provider: "github",
+				Type:     "issue_comment:created",
+				Payload:  []byte(`{"issue":{"pull_request":{}},"comment":{"body":"/drake run e2e\nplease","author_association":"MEMBER"}}`), // nolint: lll
+			},
+			assertions: func(t *testing.T, matches bool, err error) {
+				require.NoError(t, err)
+				require.True(t, matches)
+			},
+		},
+		{
+			name: "comment issuing command on issue",
+			trigger: &trigger{
+				IssueCommentEventSelector: &issueCommentEventSelector{},
+			},
+			event: brigade.Event{
+				Provider: "github",
+				Type:     "issue_comment:created",
+				Payload:  []byte(`{"issue":{},"comment":{"body":"/drake run","author_association":"OWNER"}}`), // nolint: lll
+			},
+			assertions: func(t *testing.T, matches bool, err error) {
+				require.NoError(t, err)
+				require.False(t, matches)
+			},
+		},
+		{
+			name: "comment issuing command by author with default disallowed " +
+				"association",
+			trigger: &trigger{
+				IssueCommentEventSelector: &issueCommentEventSelector{},
+			},
+			event: brigade.Event{
+				Provider: "github",
+				Type:     "issue_comment:created",
+				Payload:  []byte(`{"issue":{"pull_request":{}},"comment":{"body":"/drake run","author_association":"CONTRIBUTOR"}}`), // nolint: lll
+			},
+			assertions: func(t *testing.T, matches bool, err error) {
+				require.NoError(t, err)
+				require.False(t, matches)
+			},
+		},
+		{
+			name: "comment issuing command by author with configured allowed " +
+				"association",
+			trigger: &trigger{
+				IssueCommentEventSelector: &issueCommentEventSelector{
+					AuthorAssociations: []string{"CONTRIBUTOR"},
+				},
+			},
+			event: brigade.Event{
+				Provider: "github",
+				Type:     "issue_comment:created",
+				Payload:  []byte(`{"issue":{"pull_request":{}},"comment":{"body":"/drake run","author_association":"CONTRIBUTOR"}}`), // nolint: lll
+			},
+			assertions: func(t *testing.T, matches bool, err error) {
+				require.NoError(t, err)
+				require.True(t, matches)
+			},
+		},
+		{
+			name: "comment not issuing command",
+			trigger: &trigger{
+				IssueCommentEventSelector: &issueCommentEventSelector{},
+			},
+			event: brigade.Event{
+				Provider: "github",
+				Type:     "issue_comment:created",
+				Payload:  []byte(`{"issue":{"pull_request":{}},"comment":{"body":"LGTM! /drake run","author_association":"OWNER"}}`), // nolint: lll
+			},
+			assertions: func(t *testing.T, matches bool, err error) {
+				require.NoError(t, err)
+				require.False(t, matches)
+			},
+		},
+		{
+			name: "comment issuing command that matches configured pattern",
+			trigger: &trigger{
+				IssueCommentEventSelector: &issueCommentEventSelector{
+					Command: `/^/(test|retest)/`,
+				},
+			},
+			event: brigade.Event{
+				Provider: "github",
+				Type:     "issue_comment:created",
+				Payload:  []byte(`{"issue":{"pull_request":{}},"comment":{"body":"/retest","author_association":"OWNER"}}`), // nolint: lll
+			},
+			assertions: func(t *testing.T, matches bool, err error) {
+				require.NoError(t, err)
+				require.True(t, matches)
+			},
+		},
+		{
+			name: "push event for tag that satisfies push event selector's tag " +
+				"selector's semver constraint",
+			trigger: &trigger{
+				PushEventSelector: &pushEventSelector{
+					TagSelector: &tagSelector{
+						SemverConstraint: ">=1.0.0 <2.0.0",
+					},
+				},
+			},
+			event: brigade.Event{
+				Provider: "github",
+				Type:     "push",
+				Payload:  []byte(`{"ref":"refs/tags/v1.4.0"}`),
+			},
+			assertions: func(t *testing.T, matches bool, err error) {
+				require.NoError(t, err)
+				require.True(t, matches)
+			},
+		},
+		{
+			name:    "release event with unconfigured release event selector",
+			trigger: &trigger{},
+			event: brigade.Event{
+				Provider: "github",
+				Type:     "release:published",
+				Payload:  []byte(`{"release":{"tag_name":"v1.0.0"}}`),
+			},
+			assertions: func(t *testing.T, matches bool, err error) {
+				require.NoError(t, err)
+				require.False(t, matches)
+			},
+		},
+		{
+			name: "release event that matches release event selector",
+			trigger: &trigger{
+				ReleaseEventSelector: &releaseEventSelector{
+					TagSelector: &tagSelector{
+						Prereleases: prereleasesExclude,
+					},
+				},
+			},
+			event: brigade.Event{
+				Provider: "github",
+				Type:     "release:published",
+				Payload:  []byte(`{"release":{"tag_name":"v1.0.0"}}`),
+			},
+			assertions: func(t *testing.T, matches bool, err error) {
+				require.NoError(t, err)
+				require.True(t, matches)
+			},
+		},
+		{
+			name: "release event that does not match release event selector",
+			trigger: &trigger{
+				ReleaseEventSelector: &releaseEventSelector{
+					TagSelector: &tagSelector{
+						Prereleases: prereleasesExclude,
+					},
+				},
+			},
+			event: brigade.Event{
+				Provider: "github",
+				Type:     "release:published",
+				Payload:  []byte(`{"release":{"tag_name":"v1.0.0-beta.1"}}`),
+			},
+			assertions: func(t *testing.T, matches bool, err error) {
+				require.NoError(t, err)
+				require.False(t, matches)
+			},
+		},
+		{
+			name: "create event for tag that matches create event selector",
+			trigger: &trigger{
+				CreateEventSelector: &createEventSelector{
+					TagSelector: &tagSelector{
+						Prereleases: prereleasesOnly,
+					},
+				},
+			},
+			event: brigade.Event{
+				Provider: "github",
+				Type:     "create",
+				Payload:  []byte(`{"ref":"v2.0.0-rc.1","ref_type":"tag"}`),
+			},
+			assertions: func(t *testing.T, matches bool, err error) {
+				require.NoError(t, err)
+				require.True(t, matches)
+			},
+		},
+		{
+			name: "create event for branch with unconfigured branch selector",
+			trigger: &trigger{
+				CreateEventSelector: &createEventSelector{
+					TagSelector: &tagSelector{},
+				},
+			},
+			event: brigade.Event{
+				Provider: "github",
+				Type:     "create",
+				Payload:  []byte(`{"ref":"feature","ref_type":"branch"}`),
+			},
+			assertions: func(t *testing.T, matches bool, err error) {
+				require.NoError(t, err)
+				require.False(t, matches)
+			},
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			result, err := testCase.trigger.Matches(
+				brigade.Project{},
+				testCase.event,
+			)
+			testCase.assertions(t, result.Matched, err)
+		})
+	}
+}
+
+func TestMatchesExplainsResult(t *testing.T) {
+	testCases := []struct {
+		name     string
+		trigger  *trigger
+		event    brigade.Event
+		expected drake.MatchResult
+	}{
+		{
+			name:    "event from another provider",
+			trigger: &trigger{},
+			event: brigade.Event{
+				Provider: "brigade-cli",
+				Type:     "push",
+			},
+			expected: drake.MatchResult{
+				Reason: `event provider "brigade-cli" is not github`,
+			},
+		},
+		{
+			name:    "push event with no push event selector",
+			trigger: &trigger{},
+			event: brigade.Event{
+				Provider: "github",
+				Type:     "push",
+				Payload:  []byte(`{"ref":"refs/heads/master"}`),
+			},
+			expected: drake.MatchResult{
+				Selector: "push",
+				Reason:   "no push event selector configured",
+			},
+		},
+		{
+			name: "push event for branch not in only-list",
+			trigger: &trigger{
+				PushEventSelector: &pushEventSelector{
+					BranchSelector: &refSelector{
+						WhitelistedRefs: []string{"master"},
+					},
+				},
+			},
+			event: brigade.Event{
+				Provider: "github",
+				Type:     "push",
+				Payload:  []byte(`{"ref":"refs/heads/foo"}`),
+			},
+			expected: drake.MatchResult{
+				Selector: "push.branches",
+				Reason:   "branch foo not in only-list [master]",
+			},
+		},
+		{
+			name: "push event for branch in ignore-list",
+			trigger: &trigger{
+				PushEventSelector: &pushEventSelector{
+					BranchSelector: &refSelector{
+						BlacklistedRefs: []string{"/^dependabot/"},
+					},
+				},
+			},
+			event: brigade.Event{
+				Provider: "github",
+				Type:     "push",
+				Payload:  []byte(`{"ref":"refs/heads/dependabot/foo"}`),
+			},
+			expected: drake.MatchResult{
+				Selector: "push.branches",
+				Reason: "branch dependabot/foo matches ignore-list entry " +
+					"/^dependabot/",
+			},
+		},
+		{
+			name: "push event for tag in only-list",
+			trigger: &trigger{
+				PushEventSelector: &pushEventSelector{
+					TagSelector: &tagSelector{
+						refSelector: refSelector{
+							WhitelistedRefs: []string{"foo"},
+						},
+					},
+				},
+			},
+			event: brigade.Event{
+				Provider: "github",
+				Type:     "push",
+				Payload:  []byte(`{"ref":"refs/tags/foo"}`),
+			},
+			expected: drake.MatchResult{
+				Matched:  true,
+				Selector: "push.tags",
+				Reason:   "tag foo matches only-list entry foo",
+			},
+		},
+		{
+			name: "pull request event from author with disallowed association",
+			trigger: &trigger{
+				PullRequestEventSelector: &pullRequestEventSelector{
+					TargetBranchSelector: &refSelector{},
+					AuthorAssociations:   []string{"MEMBER"},
+				},
+			},
+			event: brigade.Event{
+				Provider: "github",
+				Type:     "pull_request:opened",
+				Payload: []byte(
+					`{"pull_request":{"author_association":"NONE",` +
+						`"base":{"ref":"master"}}}`,
+				),
+			},
+			expected: drake.MatchResult{
+				Selector: "pullRequest.authorAssociations",
+				Reason:   `author association "NONE" not in [MEMBER]`,
+			},
+		},
+	}
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			result, err := testCase.trigger.Matches(
+				brigade.Project{},
+				testCase.event,
+			)
+			require.NoError(t, err)
+			require.Equal(t, testCase.expected, result)
+		})
+	}
+}
+
+func TestSelectedJobs(t *testing.T) {
+	testCases := []struct {
+		name       string
+		event      brigade.Event
+		assertions func(*testing.T, []string, error)
+	}{
+		{
+			name: "pull request event",
+			event: brigade.Event{
+				Provider: "github",
+				Type:     "pull_request:opened",
+			},
+			assertions: func(t *testing.T, jobNames []string, err error) {
+				require.NoError(t, err)
+				require.Empty(t, jobNames)
+			},
+		},
+		{
+			name: "check suite rerequest",
+			event: brigade.Event{
+				Provider: "github",
+				Type:     "check_suite:rerequested",
+				Payload:  []byte(`{"check_suite":{"head_branch":"master"}}`),
+			},
+			assertions: func(t *testing.T, jobNames []string, err error) {
+				require.NoError(t, err)
+				require.Empty(t, jobNames)
+			},
+		},
+		{
+			name: "check run rerequest",
+			event: brigade.Event{
+				Provider: "github",
+				Type:     "check_run:rerequested",
+				Payload:  []byte(`{"check_run":{"name":"test"}}`),
+			},
+			assertions: func(t *testing.T, jobNames []string, err error) {
+				require.NoError(t, err)
+				require.Equal(t, []string{"test"}, jobNames)
+			},
+		},
+		{
+			name: "comment issuing command without argument",
+			event: brigade.Event{
+				Provider: "github",
+				Type:     "issue_comment:created",
+				Payload:  []byte(`{"comment":{"body":"/drake run"}}`),
+			},
+			assertions: func(t *testing.T, jobNames []string, err error) {
+				require.NoError(t, err)
+				require.Empty(t, jobNames)
+			},
+		},
+		{
+			name: "comment issuing command with argument",
+			event: brigade.Event{
+				Provider: "github",
+				Type:     "issue_comment:created",
+				Payload:  []byte(`{"comment":{"body":"/drake run  e2e  now"}}`),
+			},
+			assertions: func(t *testing.T, jobNames []string, err error) {
+				require.NoError(t, err)
+				require.Equal(t, []string{"e2e"}, jobNames)
+			},
+		},
+		{
+			name: "check run rerequest with invalid payload",
+			event: brigade.Event{
+				Provider: "github",
+				Type:     "check_run:rerequested",
+				Payload:  []byte(`{`),
+			},
+			assertions: func(t *testing.T, _ []string, err error) {
+				require.Error(t, err)
+			},
+		},
+	}
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			trigger := &trigger{
+				IssueCommentEventSelector: &issueCommentEventSelector{},
+			}
+			jobNames, err := trigger.SelectedJobs(testCase.event)
+			testCase.assertions(t, jobNames, err)
+		})
+	}
+}
+
+func TestSelectedJobsForSummaryCheckRerequest(t *testing.T) {
+	testCases := []struct {
+		name             string
+		summaryCheckName string
+		checkRunName     string
+		expected         []string
+	}{
+		{
+			name:         "job check run",
+			checkRunName: "test",
+			expected:     []string{"test"},
+		},
+		{
+			name:         "default summary check run",
+			checkRunName: "drake/ci",
+			expected:     []string{"ci"},
+		},
+		{
+			name:             "configured summary check run",
+			summaryCheckName: "required",
+			checkRunName:     "required",
+		},
+		{
+			name:             "another pipeline's configured summary check run",
+			summaryCheckName: "required",
+			checkRunName:     "other",
+			expected:         []string{"other"},
+		},
+		{
+			name:         "drakefile validation check run",
+			checkRunName: "drake/Drakefile.yaml",
+		},
+		{
+			name:         "summary check run of pipeline named drakefile",
+			checkRunName: "drake/drakefile",
+			expected:     []string{"drakefile"},
+		},
+	}
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			trigger := &trigger{SummaryCheckName: testCase.summaryCheckName}
+			jobNames, err := trigger.SelectedJobs(
+				brigade.Event{
+					Provider: "github",
+					Type:     "check_run:rerequested",
+					Payload: []byte(
+						`{"check_run":{"name":"` + testCase.checkRunName + `"}}`,
+					),
+				},
+			)
+			require.NoError(t, err)
+			require.Equal(t, testCase.expected, jobNames)
+		})
+	}
+}
+
+func TestListPullRequestFiles(t *testing.T) {
+	githubClient := &fakePullRequestClient{
+		files: []string{"a.go", "b.go", "c.go", "d.go", "e.go"},
+	}
+	files, err := listPullRequestFiles(githubClient, "owner", "repo", 42)
+	require.NoError(t, err)
+	require.Equal(t, githubClient.files, files)
+}
+
+func TestParseCommand(t *testing.T) {
+	testCases := []struct {
+		command     string
+		body        string
+		expectMatch bool
+		expectedArg string
+	}{
+		{body: "/drake run", expectMatch: true},
+		{body: "  /drake run\n", expectMatch: true},
+		{body: "/drake run e2e", expectMatch: true, expectedArg: "e2e"},
+		{
+			body:        "/drake run\te2e extra words",
+			expectMatch: true,
+			expectedArg: "e2e",
+		},
+		{body: "/drake runaway"},
+		{body: "please /drake run"},
+		{body: "LGTM\n/drake run"},
+		{command: "/ci", body: "/ci lint", expectMatch: true, expectedArg: "lint"},
+		{command: "/^/(re)?test/", body: "/retest unit", expectMatch: true, expectedArg: "unit"}, // nolint: lll
+		{command: "/^/(re)?test/", body: "/testing"},
+	}
+	for _, testCase := range testCases {
+		t.Run(testCase.body, func(t *testing.T) {
+			selector := &issueCommentEventSelector{Command: testCase.command}
+			match, arg, err := selector.parseCommand(testCase.body)
+			require.NoError(t, err)
+			require.Equal(t, testCase.expectMatch, match)
+			require.Equal(t, testCase.expectedArg, arg)
+		})
+	}
+}
+
+func TestJobStatusNotifierForRefDeletion(t *testing.T) {
+	const after = "0000000000000000000000000000000000000000"
+	trigger := &trigger{
+		githubClientFn: func(brigade.Project, int64) (simpleGithubClient, error) {
+			return &fakePullRequestClient{}, nil
+		},
+	}
+	jsn, err := trigger.JobStatusNotifier(
+		brigade.Project{
+			Secrets: map[string]string{
+				"BRIGDRAKE_GITHUB_APP_ID": "42",
+				"BRIGDRAKE_GITHUB_KEY":    "key",
+			},
+		},
+		brigade.Event{
+			Provider: "github",
+			Type:     "push",
+			Payload:  []byte(`{"ref":"refs/tags/v1.0.0","deleted":true,"after":"` + after + `","head_commit":null,"repository":{"name":"repo","owner":{"login":"owner"}}}`), // nolint: lll
+		},
+	)
+	require.NoError(t, err)
+	require.IsType(t, &jobStatusNotifier{}, jsn)
+	require.Equal(t, after, jsn.(*jobStatusNotifier).commit)
+}
+
+func TestJobStatusNotifierForIssueComment(t *testing.T) {
+	const headSHA = "1234567890abcdef"
+	trigger := &trigger{
+		githubClientFn: func(brigade.Project, int64) (simpleGithubClient, error) {
+			return &fakePullRequestClient{headSHA: headSHA}, nil
+		},
+	}
+	jsn, err := trigger.JobStatusNotifier(
+		brigade.Project{
+			Secrets: map[string]string{
+				"BRIGDRAKE_GITHUB_APP_ID": "42",
+				"BRIGDRAKE_GITHUB_KEY":    "key",
+			},
+		},
+		brigade.Event{
+			Provider: "github",
+			Type:     "issue_comment:created",
+			Payload:  []byte(`{"issue":{"number":7,"pull_request":{}},"repository":{"name":"repo","owner":{"login":"owner"}}}`), // nolint: lll
+		},
+	)
+	require.NoError(t, err)
+	require.IsType(t, &jobStatusNotifier{}, jsn)
+	require.Equal(t, headSHA, jsn.(*jobStatusNotifier).commit)
+	require.Equal(
+		t,
+		"repos/owner/repo/check-runs",
+		jsn.(*jobStatusNotifier).checkRunsURL,
+	)
+}
+
+func TestJobEnvironment(t *testing.T) {
+	testCases := []struct {
+		name       string
+		event      brigade.Event
+		assertions func(*testing.T, map[string]string, error)
+	}{
+		{
+			name: "push event for branch",
+			event: brigade.Event{
+				Provider: "github",
+				Type:     "push",
+				Payload:  []byte(`{"ref":"refs/heads/master"}`),
+			},
+			assertions: func(t *testing.T, env map[string]string, err error) {
+				require.NoError(t, err)
+				require.Empty(t, env)
+			},
+		},
+		{
+			name: "push event for tag that is not a semantic version",
+			event: brigade.Event{
+				Provider: "github",
+				Type:     "push",
+				Payload:  []byte(`{"ref":"refs/tags/nightly"}`),
+			},
+			assertions: func(t *testing.T, env map[string]string, err error) {
+				require.NoError(t, err)
+				require.Equal(t, map[string]string{"DRAKE_TAG": "nightly"}, env)
+			},
+		},
+		{
+			name: "push event for tag that is a semantic version",
+			event: brigade.Event{
+				Provider: "github",
+				Type:     "push",
+				Payload:  []byte(`{"ref":"refs/tags/v1.2.3-rc.1+42"}`),
+			},
+			assertions: func(t *testing.T, env map[string]string, err error) {
+				require.NoError(t, err)
+				require.Equal(
+					t,
+					map[string]string{
+						"DRAKE_TAG":                "v1.2.3-rc.1+42",
+						"DRAKE_VERSION":            "1.2.3-rc.1+42",
+						"DRAKE_VERSION_MAJOR":      "1",
+						"DRAKE_VERSION_MINOR":      "2",
+						"DRAKE_VERSION_PATCH":      "3",
+						"DRAKE_VERSION_PRERELEASE": "rc.1",
+						"DRAKE_VERSION_BUILD":      "42",
+					},
+					env,
+				)
+			},
+		},
+		{
+			name: "release event",
+			event: brigade.Event{
+				Provider: "github",
+				Type:     "release:published",
+				Payload:  []byte(`{"release":{"tag_name":"v2.0.0"}}`),
+			},
+			assertions: func(t *testing.T, env map[string]string, err error) {
+				require.NoError(t, err)
+				require.Equal(t, "2.0.0", env["DRAKE_VERSION"])
+				require.Equal(t, "2", env["DRAKE_VERSION_MAJOR"])
+			},
+		},
+		{
+			name: "create event for tag",
+			event: brigade.Event{
+				Provider: "github",
+				Type:     "create",
+				Payload:  []byte(`{"ref":"0.1.0","ref_type":"tag"}`),
+			},
+			assertions: func(t *testing.T, env map[string]string, err error) {
+				require.NoError(t, err)
+				require.Equal(t, "0.1.0", env["DRAKE_VERSION"])
+			},
+		},
+		{
+			name: "create event for branch",
+			event: brigade.Event{
+				Provider: "github",
+				Type:     "create",
+				Payload:  []byte(`{"ref":"feature","ref_type":"branch"}`),
+			},
+			assertions: func(t *testing.T, env map[string]string, err error) {
+				require.NoError(t, err)
+				require.Empty(t, env)
+			},
+		},
+		{
+			name: "pull request event",
+			event: brigade.Event{
+				Provider: "github",
+				Type:     "pull_request:opened",
+				Payload:  []byte(`{"pull_request":{"number":42}}`),
+			},
+			assertions: func(t *testing.T, env map[string]string, err error) {
+				require.NoError(t, err)
+				require.Equal(t, map[string]string{"DRAKE_PR_NUMBER": "42"}, env)
+			},
+		},
+		{
+			name: "check run rerequested for pull request",
+			event: brigade.Event{
+				Provider: "github",
+				Type:     "check_run:rerequested",
+				Payload: []byte(
+					`{"check_run":{"pull_requests":[{"number":7}]}}`,
+				),
+			},
+			assertions: func(t *testing.T, env map[string]string, err error) {
+				require.NoError(t, err)
+				require.Equal(t, map[string]string{"DRAKE_PR_NUMBER": "7"}, env)
+			},
+		},
+		{
+			name: "issue comment event",
+			event: brigade.Event{
+				Provider: "github",
+				Type:     "issue_comment:created",
+				Payload:  []byte(`{"issue":{"number":3}}`),
+			},
+			assertions: func(t *testing.T, env map[string]string, err error) {
+				require.NoError(t, err)
+				require.Equal(t, map[string]string{"DRAKE_PR_NUMBER": "3"}, env)
+			},
+		},
+	}
 	for _, testCase := range testCases {
 		t.Run(testCase.name, func(t *testing.T) {
-			matches, err := testCase.trigger.Matches(testCase.event)
-			testCase.assertions(t, matches, err)
+			env, err := (&trigger{}).JobEnvironment(testCase.event)
+			testCase.assertions(t, env, err)
 		})
 	}
 }
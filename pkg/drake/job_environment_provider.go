@@ -0,0 +1,11 @@
+package drake
+
+import "github.com/lovethedrake/brigdrake/pkg/brigade"
+
+// JobEnvironmentProvider is an optional interface that a Trigger may also
+// implement if it can derive details from the events it matches that should
+// be exposed to all of a pipeline's job containers as environment variables.
+type JobEnvironmentProvider interface {
+	// JobEnvironment returns environment variables, indexed by name.
+	JobEnvironment(brigade.Event) (map[string]string, error)
+}
@@ -0,0 +1,28 @@
+package drake
+
+import "fmt"
+
+// JobFailure describes the cause of a job's failure in as much detail as is
+// known.
+type JobFailure struct {
+	// Reason is a brief, machine-readable explanation of the failure, e.g.
+	// "OOMKilled" or "ImagePullBackOff".
+	Reason string
+	// ExitCode is the exit code of the job's primary container, if it exited.
+	ExitCode int32
+	// Message is a human-readable explanation of the failure.
+	Message string
+}
+
+// String returns a brief description of the failure, e.g.
+// "OOMKilled (exit 137)". This does not include the failure's message.
+func (j JobFailure) String() string {
+	str := j.Reason
+	if str == "" {
+		str = "Failed"
+	}
+	if j.ExitCode != 0 {
+		str = fmt.Sprintf("%s (exit %d)", str, j.ExitCode)
+	}
+	return str
+}
@@ -0,0 +1,42 @@
+package drake
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestJobFailureString(t *testing.T) {
+	testCases := []struct {
+		name     string
+		failure  JobFailure
+		expected string
+	}{
+		{
+			name:     "no details",
+			failure:  JobFailure{},
+			expected: "Failed",
+		},
+		{
+			name: "reason only",
+			failure: JobFailure{
+				Reason:  "ImagePullBackOff",
+				Message: "Back-off pulling image",
+			},
+			expected: "ImagePullBackOff",
+		},
+		{
+			name: "reason and exit code",
+			failure: JobFailure{
+				Reason:   "OOMKilled",
+				ExitCode: 137,
+			},
+			expected: "OOMKilled (exit 137)",
+		},
+	}
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			require.Equal(t, testCase.expected, testCase.failure.String())
+		})
+	}
+}
@@ -0,0 +1,11 @@
+package drake
+
+// JobOutput captures output from a job that can be reported via a
+// JobStatusNotifier when the job completes.
+type JobOutput struct {
+	// Logs is the tail of the logs from the job's primary container.
+	Logs string
+	// ArtifactsURL is the URL at which artifacts collected from the job can be
+	// found, if any.
+	ArtifactsURL string
+}
@@ -0,0 +1,33 @@
+package drake
+
+import "time"
+
+// JobConclusion is the outcome of a job.
+type JobConclusion string
+
+const (
+	// JobConclusionSuccess indicates a job succeeded.
+	JobConclusionSuccess JobConclusion = "success"
+	// JobConclusionFailure indicates a job failed.
+	JobConclusionFailure JobConclusion = "failure"
+	// JobConclusionCancelled indicates a job was canceled while in progress.
+	JobConclusionCancelled JobConclusion = "cancelled"
+	// JobConclusionTimedOut indicates a job did not complete in the time
+	// permitted.
+	JobConclusionTimedOut JobConclusion = "timed_out"
+	// JobConclusionSkipped indicates a job never started, e.g. because a job it
+	// depends on failed.
+	JobConclusionSkipped JobConclusion = "skipped"
+)
+
+// JobResult summarizes the outcome of one of a pipeline's jobs for reporting
+// via a JobStatusNotifier when the pipeline completes.
+type JobResult struct {
+	// Job is the name of the job.
+	Job string
+	// Conclusion is the outcome of the job.
+	Conclusion JobConclusion
+	// Duration is how long the job ran, including any retries. It is zero for
+	// jobs that never started.
+	Duration time.Duration
+}
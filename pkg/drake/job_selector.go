@@ -0,0 +1,15 @@
+package drake
+
+import "github.com/lovethedrake/brigdrake/pkg/brigade"
+
+// JobSelector is an optional interface that a Trigger may also implement if
+// the events it matches can request that only some of a pipeline's jobs be
+// executed.
+type JobSelector interface {
+	// SelectedJobs returns the names of the jobs that the event requested be
+	// executed. Any jobs that those jobs depend upon are implicitly selected as
+	// well. The name of a pipeline may also be returned to select all of that
+	// pipeline's jobs. An empty result means all of the pipeline's jobs are
+	// selected.
+	SelectedJobs(brigade.Event) ([]string, error)
+}
@@ -5,11 +5,31 @@ import (
 )
 
 // JobStatusNotifier is an interface to be implemented by components that can
-// report job status back to the event provider.
+// report job status back to the event provider. In addition to the status of
+// individual jobs, it reports the status of each pipeline as a whole. All of a
+// pipeline's jobs are reported as queued when the pipeline starts. Jobs that
+// never start because a job they depend on failed are reported as skipped.
+// Pipeline notifications include the result of the trigger match that caused
+// the pipeline to be executed, so that it can be explained to users. A job
+// that is retried is reported as in progress once per attempt, with attempts
+// numbered from 1.
 type JobStatusNotifier interface {
-	SendInProgressNotification(config.Job) error
-	SendSuccessNotification(config.Job) error
-	SendCancelledNotification(config.Job) error
-	SendTimedOutNotification(config.Job) error
-	SendFailureNotification(config.Job) error
+	SendQueuedNotification(config.Job) error
+	SendInProgressNotification(job config.Job, attempt int) error
+	SendSuccessNotification(config.Job, JobOutput) error
+	SendCancelledNotification(config.Job, JobOutput) error
+	SendTimedOutNotification(config.Job, JobOutput) error
+	SendFailureNotification(config.Job, JobFailure, JobOutput) error
+	SendSkippedNotification(config.Job) error
+	SendPipelineInProgressNotification(config.Pipeline, MatchResult) error
+	SendPipelineSuccessNotification(
+		config.Pipeline,
+		MatchResult,
+		[]JobResult,
+	) error
+	SendPipelineFailureNotification(
+		config.Pipeline,
+		MatchResult,
+		[]JobResult,
+	) error
 }
@@ -0,0 +1,54 @@
+package drake
+
+import "fmt"
+
+// MatchResult describes whether a trigger matched an event and why.
+type MatchResult struct {
+	// Matched indicates whether the trigger matched the event.
+	Matched bool
+	// Selector identifies the part of the trigger's configuration that decided
+	// the result, e.g. "push.branches". It is empty if the result was decided
+	// without consulting the trigger's configuration, e.g. because the event
+	// came from a provider the trigger doesn't handle.
+	Selector string
+	// Reason is a human-readable explanation of the result, e.g.
+	// "branch foo not in only-list [master]".
+	Reason string
+}
+
+// Match returns a MatchResult indicating that the trigger matched the event
+// on account of the given selector, with a reason formatted according to the
+// given format specifier.
+func Match(selector string, format string, a ...interface{}) MatchResult {
+	return MatchResult{
+		Matched:  true,
+		Selector: selector,
+		Reason:   fmt.Sprintf(format, a...),
+	}
+}
+
+// NoMatch returns a MatchResult indicating that the trigger did not match the
+// event on account of the given selector, with a reason formatted according
+// to the given format specifier.
+func NoMatch(selector string, format string, a ...interface{}) MatchResult {
+	return MatchResult{
+		Selector: selector,
+		Reason:   fmt.Sprintf(format, a...),
+	}
+}
+
+// String returns a brief description of the result, e.g.
+// "no match (push.branches): branch foo not in only-list [master]".
+func (m MatchResult) String() string {
+	str := "no match"
+	if m.Matched {
+		str = "match"
+	}
+	if m.Selector != "" {
+		str = fmt.Sprintf("%s (%s)", str, m.Selector)
+	}
+	if m.Reason != "" {
+		str = fmt.Sprintf("%s: %s", str, m.Reason)
+	}
+	return str
+}
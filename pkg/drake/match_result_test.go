@@ -0,0 +1,42 @@
+package drake
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestMatchResultString(t *testing.T) {
+	testCases := []struct {
+		result   MatchResult
+		expected string
+	}{
+		{
+			result:   Match("push.branches", "branch %s in only-list", "master"),
+			expected: "match (push.branches): branch master in only-list",
+		},
+		{
+			result: NoMatch(
+				"push.branches",
+				"branch %s not in only-list %v",
+				"foo",
+				[]string{"master"},
+			),
+			expected: "no match (push.branches): branch foo not in only-list " +
+				"[master]",
+		},
+		{
+			result:   NoMatch("", "event from provider %q is not handled", "foo"),
+			expected: `no match: event from provider "foo" is not handled`,
+		},
+		{
+			result:   MatchResult{},
+			expected: "no match",
+		},
+	}
+	for _, testCase := range testCases {
+		t.Run(testCase.expected, func(t *testing.T) {
+			require.Equal(t, testCase.expected, testCase.result.String())
+		})
+	}
+}
@@ -2,8 +2,10 @@ package drake
 
 import "github.com/lovethedrake/brigdrake/pkg/brigade"
 
-// Trigger is the public interface for all triggers.
+// Trigger is the public interface for all triggers. Matches explains its
+// decision by way of the MatchResult it returns; errors are reserved for
+// failures to reach a decision at all.
 type Trigger interface {
-	Matches(brigade.Event) (bool, error)
+	Matches(brigade.Project, brigade.Event) (MatchResult, error)
 	JobStatusNotifier(brigade.Project, brigade.Event) (JobStatusNotifier, error)
 }
@@ -0,0 +1,22 @@
+package drake
+
+import (
+	"bytes"
+	"encoding/json"
+
+	"github.com/pkg/errors"
+)
+
+// DecodeTriggerConfig decodes a trigger's JSON configuration into v. Unlike
+// json.Unmarshal, it rejects any field that v does not define, so that a
+// misspelled field is reported as an error instead of silently leaving part
+// of the trigger unconfigured. TriggerBuilders should decode configuration
+// using this.
+func DecodeTriggerConfig(config []byte, v interface{}) error {
+	if len(bytes.TrimSpace(config)) == 0 {
+		return errors.New("trigger configuration is missing")
+	}
+	decoder := json.NewDecoder(bytes.NewReader(config))
+	decoder.DisallowUnknownFields()
+	return decoder.Decode(v)
+}
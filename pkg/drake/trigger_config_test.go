@@ -0,0 +1,61 @@
+package drake
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestDecodeTriggerConfig(t *testing.T) {
+	type nestedConfig struct {
+		Only []string `json:"only"`
+	}
+	type config struct {
+		Branches *nestedConfig `json:"branches"`
+	}
+	testCases := []struct {
+		name       string
+		config     string
+		assertions func(*testing.T, config, error)
+	}{
+		{
+			name:   "valid config",
+			config: `{"branches":{"only":["master"]}}`,
+			assertions: func(t *testing.T, cfg config, err error) {
+				require.NoError(t, err)
+				require.Equal(t, []string{"master"}, cfg.Branches.Only)
+			},
+		},
+		{
+			name:   "missing config",
+			config: "",
+			assertions: func(t *testing.T, _ config, err error) {
+				require.Error(t, err)
+				require.Contains(t, err.Error(), "missing")
+			},
+		},
+		{
+			name:   "unknown field",
+			config: `{"branch":{"only":["master"]}}`,
+			assertions: func(t *testing.T, _ config, err error) {
+				require.Error(t, err)
+				require.Contains(t, err.Error(), `unknown field "branch"`)
+			},
+		},
+		{
+			name:   "unknown nested field",
+			config: `{"branches":{"ignore":["master"]}}`,
+			assertions: func(t *testing.T, _ config, err error) {
+				require.Error(t, err)
+				require.Contains(t, err.Error(), `unknown field "ignore"`)
+			},
+		},
+	}
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			cfg := config{}
+			err := DecodeTriggerConfig([]byte(testCase.config), &cfg)
+			testCase.assertions(t, cfg, err)
+		})
+	}
+}
@@ -0,0 +1,92 @@
+package drake
+
+import (
+	"fmt"
+	"sync"
+
+	"github.com/lovethedrake/brigdrake/pkg/semver"
+)
+
+// TriggerBuilder builds a Trigger from the trigger-specific configuration
+// found in a Drakefile, which is passed to it as JSON.
+type TriggerBuilder func(config []byte) (Trigger, error)
+
+type triggerRegistration struct {
+	constraint *semver.Constraint
+	builder    TriggerBuilder
+}
+
+var (
+	triggerRegistrations   = map[string][]triggerRegistration{}
+	triggerRegistrationsMu sync.RWMutex
+)
+
+// RegisterTrigger makes a trigger implementation available for use by
+// pipelines whose triggers reference the given spec URI and a spec version
+// that satisfies the given version constraint, e.g. ">=1.0.0 <2.0.0". Several
+// versions of one spec may be supported side by side by registering a builder
+// for each with non-overlapping constraints. If constraints do overlap, the
+// builder registered first is used. Trigger implementations typically
+// register themselves from an init function, so that linking the package into
+// a worker is all it takes to make them available. RegisterTrigger panics if
+// the constraint is invalid or the builder is nil, since either is a
+// programming error.
+func RegisterTrigger(
+	specURI string,
+	versionConstraint string,
+	builder TriggerBuilder,
+) {
+	if builder == nil {
+		panic(fmt.Sprintf("nil builder registered for trigger %q", specURI))
+	}
+	constraint, err := semver.ParseConstraint(versionConstraint)
+	if err != nil {
+		panic(
+			fmt.Sprintf(
+				"invalid version constraint registered for trigger %q: %s",
+				specURI,
+				err,
+			),
+		)
+	}
+	triggerRegistrationsMu.Lock()
+	defer triggerRegistrationsMu.Unlock()
+	triggerRegistrations[specURI] = append(
+		triggerRegistrations[specURI],
+		triggerRegistration{
+			constraint: constraint,
+			builder:    builder,
+		},
+	)
+}
+
+// LookupTrigger returns the builder registered for the given spec URI whose
+// version constraint is satisfied by the given spec version. If there is no
+// such builder, it returns false.
+func LookupTrigger(specURI, specVersion string) (TriggerBuilder, bool) {
+	version, err := semver.ParsePartialVersion(specVersion)
+	if err != nil {
+		return nil, false
+	}
+	triggerRegistrationsMu.RLock()
+	defer triggerRegistrationsMu.RUnlock()
+	for _, registration := range triggerRegistrations[specURI] {
+		if registration.constraint.Check(version) {
+			return registration.builder, true
+		}
+	}
+	return nil, false
+}
+
+// TriggerVersionConstraints returns the version constraints of all the
+// builders registered for the given spec URI, in the order they were
+// registered. If none are registered, the spec is not supported at all.
+func TriggerVersionConstraints(specURI string) []string {
+	triggerRegistrationsMu.RLock()
+	defer triggerRegistrationsMu.RUnlock()
+	constraints := []string{}
+	for _, registration := range triggerRegistrations[specURI] {
+		constraints = append(constraints, registration.constraint.String())
+	}
+	return constraints
+}
@@ -0,0 +1,92 @@
+package drake
+
+import (
+	"testing"
+
+	"github.com/lovethedrake/brigdrake/pkg/brigade"
+	"github.com/stretchr/testify/require"
+)
+
+type fakeTrigger struct {
+	version string
+}
+
+func (f *fakeTrigger) Matches(
+	brigade.Project,
+	brigade.Event,
+) (MatchResult, error) {
+	return Match("", "fake trigger %s always matches", f.version), nil
+}
+
+func (f *fakeTrigger) JobStatusNotifier(
+	brigade.Project,
+	brigade.Event,
+) (JobStatusNotifier, error) {
+	return nil, nil
+}
+
+func fakeTriggerBuilder(version string) TriggerBuilder {
+	return func([]byte) (Trigger, error) {
+		return &fakeTrigger{version: version}, nil
+	}
+}
+
+func TestLookupTrigger(t *testing.T) {
+	const specURI = "example.com/drakespec-fake"
+	RegisterTrigger(specURI, ">=1.0.0 <2.0.0", fakeTriggerBuilder("v1"))
+	RegisterTrigger(specURI, ">=2.0.0 <3.0.0", fakeTriggerBuilder("v2"))
+	testCases := []struct {
+		specURI         string
+		specVersion     string
+		expectedVersion string
+	}{
+		{specURI: specURI, specVersion: "v1.0.0", expectedVersion: "v1"},
+		{specURI: specURI, specVersion: "v1.4", expectedVersion: "v1"},
+		{specURI: specURI, specVersion: "v2", expectedVersion: "v2"},
+		{specURI: specURI, specVersion: "v3.0.0"},
+		{specURI: specURI, specVersion: "latest"},
+		{specURI: "example.com/drakespec-unknown", specVersion: "v1.0.0"},
+	}
+	for _, testCase := range testCases {
+		t.Run(testCase.specURI+" "+testCase.specVersion, func(t *testing.T) {
+			builder, ok := LookupTrigger(testCase.specURI, testCase.specVersion)
+			if testCase.expectedVersion == "" {
+				require.False(t, ok)
+				return
+			}
+			require.True(t, ok)
+			trigger, err := builder(nil)
+			require.NoError(t, err)
+			require.Equal(
+				t,
+				testCase.expectedVersion,
+				trigger.(*fakeTrigger).version,
+			)
+		})
+	}
+}
+
+func TestTriggerVersionConstraints(t *testing.T) {
+	const specURI = "example.com/drakespec-constrained"
+	RegisterTrigger(specURI, ">=1.0.0 <2.0.0", fakeTriggerBuilder("v1"))
+	RegisterTrigger(specURI, ">=2.0.0 <3.0.0", fakeTriggerBuilder("v2"))
+	require.Equal(
+		t,
+		[]string{">=1.0.0 <2.0.0", ">=2.0.0 <3.0.0"},
+		TriggerVersionConstraints(specURI),
+	)
+	require.Empty(t, TriggerVersionConstraints("example.com/drakespec-unknown"))
+}
+
+func TestRegisterTriggerPanics(t *testing.T) {
+	require.Panics(t, func() {
+		RegisterTrigger(
+			"example.com/drakespec-fake",
+			"not a constraint",
+			fakeTriggerBuilder("v1"),
+		)
+	})
+	require.Panics(t, func() {
+		RegisterTrigger("example.com/drakespec-fake", ">=1.0.0", nil)
+	})
+}
@@ -0,0 +1,65 @@
+package drake
+
+import (
+	"fmt"
+	"sync"
+
+	"github.com/lovethedrake/brigdrake/pkg/brigade"
+)
+
+// ValidationFailureNotifier is an optional interface that a JobStatusNotifier
+// may also implement if it can report that a build was not executed because
+// its Drakefile is invalid.
+type ValidationFailureNotifier interface {
+	// SendValidationFailureNotification reports all of the errors found while
+	// validating the Drakefile together.
+	SendValidationFailureNotification([]error) error
+}
+
+// ValidationFailureNotifierBuilder returns a ValidationFailureNotifier for the
+// given project and event. Since the Drakefile is invalid, no trigger
+// configuration is available to build it from. It may return nil if the event
+// is not one for which a report can be made.
+type ValidationFailureNotifierBuilder func(
+	brigade.Project,
+	brigade.Event,
+) (ValidationFailureNotifier, error)
+
+var (
+	validationNotifierBuilders   = map[string]ValidationFailureNotifierBuilder{}
+	validationNotifierBuildersMu sync.RWMutex
+)
+
+// RegisterValidationFailureNotifier makes a ValidationFailureNotifier
+// available for reporting invalid Drakefiles for builds caused by events from
+// the given provider, e.g. "github". Like triggers, implementations typically
+// register themselves from an init function. Registering a second builder for
+// the same provider replaces the first. RegisterValidationFailureNotifier
+// panics if the builder is nil, since that is a programming error.
+func RegisterValidationFailureNotifier(
+	eventProvider string,
+	builder ValidationFailureNotifierBuilder,
+) {
+	if builder == nil {
+		panic(
+			fmt.Sprintf(
+				"nil builder registered for validation failure notifier %q",
+				eventProvider,
+			),
+		)
+	}
+	validationNotifierBuildersMu.Lock()
+	defer validationNotifierBuildersMu.Unlock()
+	validationNotifierBuilders[eventProvider] = builder
+}
+
+// LookupValidationFailureNotifier returns the builder registered for the given
+// event provider. If there is no such builder, it returns false.
+func LookupValidationFailureNotifier(
+	eventProvider string,
+) (ValidationFailureNotifierBuilder, bool) {
+	validationNotifierBuildersMu.RLock()
+	defer validationNotifierBuildersMu.RUnlock()
+	builder, ok := validationNotifierBuilders[eventProvider]
+	return builder, ok
+}
@@ -0,0 +1,36 @@
+package drake
+
+import (
+	"testing"
+
+	"github.com/lovethedrake/brigdrake/pkg/brigade"
+	"github.com/stretchr/testify/require"
+)
+
+type fakeValidationFailureNotifier struct{}
+
+func (f *fakeValidationFailureNotifier) SendValidationFailureNotification(
+	[]error,
+) error {
+	return nil
+}
+
+func TestLookupValidationFailureNotifier(t *testing.T) {
+	const eventProvider = "fake"
+	_, ok := LookupValidationFailureNotifier(eventProvider)
+	require.False(t, ok)
+	RegisterValidationFailureNotifier(
+		eventProvider,
+		func(brigade.Project, brigade.Event) (ValidationFailureNotifier, error) {
+			return &fakeValidationFailureNotifier{}, nil
+		},
+	)
+	builder, ok := LookupValidationFailureNotifier(eventProvider)
+	require.True(t, ok)
+	notifier, err := builder(brigade.Project{}, brigade.Event{})
+	require.NoError(t, err)
+	require.IsType(t, &fakeValidationFailureNotifier{}, notifier)
+	require.Panics(t, func() {
+		RegisterValidationFailureNotifier(eventProvider, nil)
+	})
+}
@@ -0,0 +1,106 @@
+package semver
+
+import (
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// Constraint represents a constraint on semantic versions, such as
+// ">=1.0.0 <2.0.0". Space-separated comparisons must all be satisfied.
+// Alternatives may be separated by "||", in which case any one of them must
+// be satisfied. Supported operators are =, !=, >, >=, <, and <=. A comparison
+// without an operator is an equality comparison. Versions within a constraint
+// may omit their minor and patch components, in which case those are zero.
+type Constraint struct {
+	raw          string
+	alternatives [][]comparison
+}
+
+type comparison struct {
+	operator string
+	version  *Version
+}
+
+// operators is ordered such that no operator is preceded by another operator
+// that is its prefix.
+var operators = []string{">=", "<=", "!=", ">", "<", "="}
+
+// ParseConstraint parses a constraint on semantic versions.
+func ParseConstraint(str string) (*Constraint, error) {
+	c := &Constraint{raw: str}
+	for _, alternative := range strings.Split(str, "||") {
+		comparisons := []comparison{}
+		fields := strings.Fields(alternative)
+		for i := 0; i < len(fields); i++ {
+			field := fields[i]
+			operator := "="
+			for _, op := range operators {
+				if strings.HasPrefix(field, op) {
+					operator = op
+					field = field[len(op):]
+					break
+				}
+			}
+			// Permit whitespace between an operator and its version
+			if field == "" && i+1 < len(fields) {
+				i++
+				field = fields[i]
+			}
+			version, err := parseVersion(field, true)
+			if err != nil {
+				return nil, errors.Wrapf(err, "error parsing constraint %q", str)
+			}
+			comparisons = append(
+				comparisons,
+				comparison{operator: operator, version: version},
+			)
+		}
+		if len(comparisons) == 0 {
+			return nil, errors.Errorf(
+				"constraint %q contains an empty alternative",
+				str,
+			)
+		}
+		c.alternatives = append(c.alternatives, comparisons)
+	}
+	return c, nil
+}
+
+// Check returns true if the version satisfies the constraint.
+func (c *Constraint) Check(version *Version) bool {
+	for _, comparisons := range c.alternatives {
+		satisfied := true
+		for _, comparison := range comparisons {
+			if !comparison.check(version) {
+				satisfied = false
+				break
+			}
+		}
+		if satisfied {
+			return true
+		}
+	}
+	return false
+}
+
+func (c comparison) check(version *Version) bool {
+	result := version.Compare(c.version)
+	switch c.operator {
+	case "!=":
+		return result != 0
+	case ">":
+		return result > 0
+	case ">=":
+		return result >= 0
+	case "<":
+		return result < 0
+	case "<=":
+		return result <= 0
+	}
+	return result == 0
+}
+
+func (c *Constraint) String() string {
+	return c.raw
+}
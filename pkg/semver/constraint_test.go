@@ -0,0 +1,56 @@
+package semver
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestConstraint(t *testing.T) {
+	testCases := []struct {
+		constraint string
+		version    string
+		expected   bool
+	}{
+		{constraint: ">=1.0.0 <2.0.0", version: "1.5.0", expected: true},
+		{constraint: ">=1.0.0 <2.0.0", version: "2.0.0", expected: false},
+		{constraint: ">=1.0.0 <2.0.0", version: "0.9.9", expected: false},
+		{constraint: ">= 1.0 < 2", version: "1.0.0", expected: true},
+		{constraint: "1.2.3", version: "v1.2.3", expected: true},
+		{constraint: "=1.2.3", version: "1.2.4", expected: false},
+		{constraint: "!=1.2.3", version: "1.2.4", expected: true},
+		{constraint: ">1.0.0", version: "1.0.0", expected: false},
+		{constraint: "<=1.0.0", version: "1.0.0", expected: true},
+		{constraint: ">=1.0.0", version: "1.0.0-rc.1", expected: false},
+		{constraint: "<1.0.0 || >=2.0.0", version: "2.1.0", expected: true},
+		{constraint: "<1.0.0 || >=2.0.0", version: "1.1.0", expected: false},
+	}
+	for _, testCase := range testCases {
+		t.Run(testCase.constraint+" "+testCase.version, func(t *testing.T) {
+			constraint, err := ParseConstraint(testCase.constraint)
+			require.NoError(t, err)
+			version, err := ParseVersion(testCase.version)
+			require.NoError(t, err)
+			require.Equal(t, testCase.expected, constraint.Check(version))
+		})
+	}
+}
+
+func TestParseConstraintErrors(t *testing.T) {
+	testCases := []struct {
+		constraint string
+		errMsg     string
+	}{
+		{constraint: "", errMsg: "empty alternative"},
+		{constraint: ">=1.0.0 ||", errMsg: "empty alternative"},
+		{constraint: ">=foo", errMsg: "invalid numeric component"},
+		{constraint: "~1.0.0", errMsg: "invalid numeric component"},
+	}
+	for _, testCase := range testCases {
+		t.Run(testCase.constraint, func(t *testing.T) {
+			_, err := ParseConstraint(testCase.constraint)
+			require.Error(t, err)
+			require.Contains(t, err.Error(), testCase.errMsg)
+		})
+	}
+}
@@ -0,0 +1,193 @@
+package semver
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// Version represents a semantic version as defined by https://semver.org.
+type Version struct {
+	Major      uint64
+	Minor      uint64
+	Patch      uint64
+	Prerelease string
+	Build      string
+}
+
+// ParseVersion parses a semantic version such as 1.2.3, v1.2.3-rc.1, or
+// 1.2.3+20190601. A leading "v" is permitted, as is conventional for git tags.
+func ParseVersion(str string) (*Version, error) {
+	return parseVersion(str, false)
+}
+
+// ParsePartialVersion parses a semantic version that may omit its minor and
+// patch components, such as v1 or v1.2, in which case those are zero. This is
+// the form in which DrakeSpec versions are often written.
+func ParsePartialVersion(str string) (*Version, error) {
+	return parseVersion(str, true)
+}
+
+// parseVersion parses a semantic version. If partial is true, the minor and
+// patch components may be omitted, in which case they are zero.
+func parseVersion(str string, partial bool) (*Version, error) {
+	s := strings.TrimPrefix(str, "v")
+	v := &Version{}
+	if i := strings.Index(s, "+"); i >= 0 {
+		v.Build = s[i+1:]
+		s = s[:i]
+		if err := validateIdentifiers(v.Build, false); err != nil {
+			return nil, errors.Wrapf(err, "invalid build metadata in %q", str)
+		}
+	}
+	if i := strings.Index(s, "-"); i >= 0 {
+		v.Prerelease = s[i+1:]
+		s = s[:i]
+		if err := validateIdentifiers(v.Prerelease, true); err != nil {
+			return nil, errors.Wrapf(err, "invalid pre-release in %q", str)
+		}
+	}
+	components := strings.Split(s, ".")
+	if len(components) > 3 || (!partial && len(components) != 3) {
+		return nil, errors.Errorf(
+			"%q is not a semantic version of the form major.minor.patch",
+			str,
+		)
+	}
+	numbers := []*uint64{&v.Major, &v.Minor, &v.Patch}
+	for i, component := range components {
+		if !isNumeric(component) ||
+			(len(component) > 1 && component[0] == '0') {
+			return nil, errors.Errorf(
+				"invalid numeric component %q in %q",
+				component,
+				str,
+			)
+		}
+		var err error
+		if *numbers[i], err = strconv.ParseUint(component, 10, 64); err != nil {
+			return nil, errors.Wrapf(err, "invalid numeric component in %q", str)
+		}
+	}
+	return v, nil
+}
+
+// validateIdentifiers validates the dot-separated identifiers of a
+// pre-release or build metadata. Numeric pre-release identifiers must not have
+// leading zeroes.
+func validateIdentifiers(identifiers string, prerelease bool) error {
+	for _, identifier := range strings.Split(identifiers, ".") {
+		if identifier == "" {
+			return errors.New("identifiers must not be empty")
+		}
+		for _, c := range identifier {
+			if !(c == '-' ||
+				(c >= '0' && c <= '9') ||
+				(c >= 'a' && c <= 'z') ||
+				(c >= 'A' && c <= 'Z')) {
+				return errors.Errorf("invalid character %q in %q", c, identifier)
+			}
+		}
+		if prerelease && isNumeric(identifier) && len(identifier) > 1 &&
+			identifier[0] == '0' {
+			return errors.Errorf(
+				"numeric identifier %q must not have leading zeroes",
+				identifier,
+			)
+		}
+	}
+	return nil
+}
+
+func isNumeric(s string) bool {
+	if s == "" {
+		return false
+	}
+	for _, c := range s {
+		if c < '0' || c > '9' {
+			return false
+		}
+	}
+	return true
+}
+
+// IsPrerelease returns true if the version has a pre-release component.
+func (v *Version) IsPrerelease() bool {
+	return v.Prerelease != ""
+}
+
+// Compare returns -1, 0, or 1 if the version has lower, equal, or higher
+// precedence, respectively, than the other version. Build metadata is ignored,
+// as specified by https://semver.org.
+func (v *Version) Compare(other *Version) int {
+	if c := compareUint(v.Major, other.Major); c != 0 {
+		return c
+	}
+	if c := compareUint(v.Minor, other.Minor); c != 0 {
+		return c
+	}
+	if c := compareUint(v.Patch, other.Patch); c != 0 {
+		return c
+	}
+	// A version without a pre-release has higher precedence than one with
+	if v.Prerelease == "" || other.Prerelease == "" {
+		switch {
+		case v.Prerelease == other.Prerelease:
+			return 0
+		case v.Prerelease == "":
+			return 1
+		default:
+			return -1
+		}
+	}
+	identifiers := strings.Split(v.Prerelease, ".")
+	otherIdentifiers := strings.Split(other.Prerelease, ".")
+	for i := 0; i < len(identifiers) && i < len(otherIdentifiers); i++ {
+		if c := compareIdentifiers(identifiers[i], otherIdentifiers[i]); c != 0 {
+			return c
+		}
+	}
+	return compareUint(uint64(len(identifiers)), uint64(len(otherIdentifiers)))
+}
+
+// compareIdentifiers compares pre-release identifiers. Numeric identifiers are
+// compared numerically and have lower precedence than alphanumeric
+// identifiers, which are compared lexically.
+func compareIdentifiers(a, b string) int {
+	aIsNumeric, bIsNumeric := isNumeric(a), isNumeric(b)
+	switch {
+	case aIsNumeric && bIsNumeric:
+		aNum, _ := strconv.ParseUint(a, 10, 64)
+		bNum, _ := strconv.ParseUint(b, 10, 64)
+		return compareUint(aNum, bNum)
+	case aIsNumeric:
+		return -1
+	case bIsNumeric:
+		return 1
+	}
+	return strings.Compare(a, b)
+}
+
+func compareUint(a, b uint64) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	}
+	return 0
+}
+
+// String returns the canonical form of the version, without any leading "v".
+func (v *Version) String() string {
+	str := fmt.Sprintf("%d.%d.%d", v.Major, v.Minor, v.Patch)
+	if v.Prerelease != "" {
+		str = fmt.Sprintf("%s-%s", str, v.Prerelease)
+	}
+	if v.Build != "" {
+		str = fmt.Sprintf("%s+%s", str, v.Build)
+	}
+	return str
+}
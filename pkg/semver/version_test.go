@@ -0,0 +1,117 @@
+package semver
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseVersion(t *testing.T) {
+	testCases := []struct {
+		version  string
+		expected *Version
+		errMsg   string
+	}{
+		{
+			version:  "1.2.3",
+			expected: &Version{Major: 1, Minor: 2, Patch: 3},
+		},
+		{
+			version: "v1.2.3-rc.1+build.42",
+			expected: &Version{
+				Major:      1,
+				Minor:      2,
+				Patch:      3,
+				Prerelease: "rc.1",
+				Build:      "build.42",
+			},
+		},
+		{
+			version:  "0.0.0-alpha-1",
+			expected: &Version{Prerelease: "alpha-1"},
+		},
+		{version: "1.2", errMsg: "major.minor.patch"},
+		{version: "1.2.3.4", errMsg: "major.minor.patch"},
+		{version: "01.2.3", errMsg: "invalid numeric component"},
+		{version: "1.x.3", errMsg: "invalid numeric component"},
+		{version: "1.2.3-rc..1", errMsg: "must not be empty"},
+		{version: "1.2.3-01", errMsg: "leading zeroes"},
+		{version: "1.2.3-rc_1", errMsg: "invalid character"},
+		{version: "release-1", errMsg: "major.minor.patch"},
+	}
+	for _, testCase := range testCases {
+		t.Run(testCase.version, func(t *testing.T) {
+			version, err := ParseVersion(testCase.version)
+			if testCase.errMsg != "" {
+				require.Error(t, err)
+				require.Contains(t, err.Error(), testCase.errMsg)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, testCase.expected, version)
+		})
+	}
+}
+
+func TestParsePartialVersion(t *testing.T) {
+	testCases := []struct {
+		version  string
+		expected *Version
+		errMsg   string
+	}{
+		{version: "v1", expected: &Version{Major: 1}},
+		{version: "v1.2", expected: &Version{Major: 1, Minor: 2}},
+		{version: "v1.2.3", expected: &Version{Major: 1, Minor: 2, Patch: 3}},
+		{version: "1.2.3.4", errMsg: "major.minor.patch"},
+		{version: "v1.x", errMsg: "invalid numeric component"},
+	}
+	for _, testCase := range testCases {
+		t.Run(testCase.version, func(t *testing.T) {
+			version, err := ParsePartialVersion(testCase.version)
+			if testCase.errMsg != "" {
+				require.Error(t, err)
+				require.Contains(t, err.Error(), testCase.errMsg)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, testCase.expected, version)
+		})
+	}
+}
+
+func TestCompare(t *testing.T) {
+	// Each version in this list has lower precedence than the next
+	orderedVersions := []string{
+		"1.0.0-alpha",
+		"1.0.0-alpha.1",
+		"1.0.0-alpha.beta",
+		"1.0.0-beta",
+		"1.0.0-beta.2",
+		"1.0.0-beta.11",
+		"1.0.0-rc.1",
+		"1.0.0",
+		"1.0.1",
+		"1.1.0",
+		"2.0.0",
+	}
+	for i := 0; i < len(orderedVersions)-1; i++ {
+		lower, err := ParseVersion(orderedVersions[i])
+		require.NoError(t, err)
+		higher, err := ParseVersion(orderedVersions[i+1])
+		require.NoError(t, err)
+		require.Equal(t, -1, lower.Compare(higher))
+		require.Equal(t, 1, higher.Compare(lower))
+		require.Equal(t, 0, lower.Compare(lower))
+	}
+	withBuild, err := ParseVersion("1.0.0+build.1")
+	require.NoError(t, err)
+	withoutBuild, err := ParseVersion("1.0.0")
+	require.NoError(t, err)
+	require.Equal(t, 0, withBuild.Compare(withoutBuild))
+}
+
+func TestVersionString(t *testing.T) {
+	version, err := ParseVersion("v1.2.3-rc.1+build.42")
+	require.NoError(t, err)
+	require.Equal(t, "1.2.3-rc.1+build.42", version.String())
+}